@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/pkg/utils"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// exportFormat is bumped whenever documentExport changes incompatibly.
+const exportFormat = 1
+
+// documentExport is the file written by "documents export" and read by
+// "documents import". People are identified by email, so a document can be
+// moved between environments.
+type documentExport struct {
+	Format     int             `json:"format"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Title      string          `json:"title"`
+	Content    *datatypes.JSON `json:"content"`
+	IsPublic   bool            `json:"isPublic"`
+	Owner      string          `json:"owner"`
+	Version    int             `json:"version"`
+	Metadata   *model.Metadata `json:"metadata,omitempty"`
+	Access     []accessExport  `json:"access"`
+}
+
+type accessExport struct {
+	Email string     `json:"email"`
+	Role  model.Role `json:"role"`
+}
+
+func parseID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid id %q", s)
+	}
+	return id, nil
+}
+
+func (a *app) document(id string) (*model.Document, error) {
+	documentId, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	var document model.Document
+	if err := a.documents.GetOne(documentId, &document); err != nil {
+		return nil, fmt.Errorf("error finding document %s: %w", id, err)
+	}
+	return &document, nil
+}
+
+func listDocuments(a *app, args []string) error {
+	fs := flag.NewFlagSet("documents list", flag.ContinueOnError)
+	owner := fs.String("owner", "", "only documents owned by this email")
+	limit := fs.Int("limit", 50, "maximum number of documents")
+	if err := a.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	var ownerId *uuid.UUID
+	if *owner != "" {
+		user, err := a.userByEmail(*owner)
+		if err != nil {
+			return err
+		}
+		ownerId = &user.ID
+	}
+
+	documents, err := a.documents.List(ownerId, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tOWNER ID\tPUBLIC\tUPDATED")
+	for _, document := range documents {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n",
+			document.ID, document.Title, document.UserID, document.PublicVisibility,
+			document.UpdatedAt.Format(time.DateTime),
+		)
+	}
+	return w.Flush()
+}
+
+// transferOwnership makes the new owner the document's creator, adding them
+// as a collaborator if needed, and leaves the previous owner with edit
+// access.
+func transferOwnership(a *app, args []string) error {
+	fs := flag.NewFlagSet("documents transfer", flag.ContinueOnError)
+	if err := a.parse(fs, args, 2); err != nil {
+		return err
+	}
+
+	document, err := a.document(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	recipient, err := a.userByEmail(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if document.UserID == recipient.ID {
+		return fmt.Errorf("%s already owns this document", recipient.Email)
+	}
+
+	previousOwnerDoc := *document
+	err = a.documents.ExecuteInTransaction(func(tx *gorm.DB) error {
+		document.UserID = recipient.ID
+		if err := a.documents.UpdateWithTransaction(tx, document, document.ID); err != nil {
+			return fmt.Errorf("failed to update document details: %w", err)
+		}
+
+		var previousAccess model.DocumentAccess
+		err := tx.Where("document_id = ? AND collaborator_id = ?", document.ID, previousOwnerDoc.UserID).First(&previousAccess).Error
+		if err == nil {
+			previousAccess.Role = model.Edit
+			if err := a.documentAccess.UpdateWithTransaction(tx, &previousAccess, previousAccess.ID); err != nil {
+				return fmt.Errorf("failed to update previous owner access: %w", err)
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var recipientAccess model.DocumentAccess
+		err = tx.Where("document_id = ? AND collaborator_id = ?", document.ID, recipient.ID).First(&recipientAccess).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return a.documentAccess.CreateWithTransaction(tx, &model.DocumentAccess{
+				CollaboratorId: recipient.ID,
+				DocumentId:     document.ID,
+				Role:           model.Creator,
+			})
+		} else if err != nil {
+			return err
+		}
+		recipientAccess.Role = model.Creator
+		if err := a.documentAccess.UpdateWithTransaction(tx, &recipientAccess, recipientAccess.ID); err != nil {
+			return fmt.Errorf("failed to update new owner access: %w", err)
+		}
+		return nil
+	}, 3)
+	if err != nil {
+		return err
+	}
+
+	a.audit(model.AuditLog{
+		Action:     model.AuditOwnershipTransferred,
+		TargetType: "user",
+		TargetID:   recipient.ID.String(),
+		DocumentID: &document.ID,
+	}, gin.H{"ownerId": previousOwnerDoc.UserID}, gin.H{"ownerId": recipient.ID})
+
+	a.webhooks.Publish(a.ctx, model.WebhookOwnershipTransferred, &previousOwnerDoc, nil, gin.H{
+		"previousOwnerId": previousOwnerDoc.UserID,
+		"newOwnerId":      recipient.ID,
+	})
+
+	fmt.Printf("Transferred %q to %s\n", document.Title, recipient.Email)
+	return nil
+}
+
+// renderExport generates the document's PDF again and stores it like the
+// generate-pdf endpoint does; the media cleanup job removes it later.
+func renderExport(a *app, args []string) error {
+	fs := flag.NewFlagSet("documents render", flag.ContinueOnError)
+	if err := a.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	document, err := a.document(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var metadata model.DocumentMetadata
+	if err := a.documentMeta.GetOneByDocId(document.ID, &metadata); err != nil {
+		return fmt.Errorf("error finding document metadata: %w", err)
+	}
+
+	uploaded, err := utils.DocumentHandler(a.ctx, document, &metadata)
+	if err != nil {
+		return err
+	}
+	if err := a.documentMedia.Create(&model.DocumentMedia{
+		DocumentID: document.ID,
+		PublicID:   uploaded.PublicID,
+		SecureURL:  uploaded.SecureURL,
+		Format:     "pdf",
+	}); err != nil {
+		return fmt.Errorf("error storing export: %w", err)
+	}
+
+	fmt.Println(uploaded.SecureURL)
+	return nil
+}
+
+func exportDocument(a *app, args []string) error {
+	fs := flag.NewFlagSet("documents export", flag.ContinueOnError)
+	output := fs.String("o", "", "file to write instead of stdout")
+	if err := a.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	document, err := a.document(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var owner model.User
+	if err := a.users.GetById(&owner, document.UserID); err != nil {
+		return fmt.Errorf("error finding document owner: %w", err)
+	}
+
+	export := documentExport{
+		Format:     exportFormat,
+		ExportedAt: time.Now().UTC(),
+		Title:      document.Title,
+		Content:    document.Content,
+		IsPublic:   document.PublicVisibility,
+		Owner:      owner.Email,
+		Access:     []accessExport{},
+	}
+
+	var metadata model.DocumentMetadata
+	if err := a.documentMeta.GetOneByDocId(document.ID, &metadata); err == nil {
+		export.Version = metadata.Version
+		export.Metadata = metadata.Metadata
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error finding document metadata: %w", err)
+	}
+
+	accesses, err := a.documentAccess.GetDocumentAccesses(document.ID)
+	if err != nil {
+		return err
+	}
+	for _, access := range accesses {
+		if access.CollaboratorId == document.UserID {
+			continue
+		}
+		export.Access = append(export.Access, accessExport{Email: access.User.Email, Role: access.Role})
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("error creating %s: %w", *output, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// importDocument creates a new document from an export. Collaborators
+// without an account in this environment are skipped.
+func importDocument(a *app, args []string) error {
+	fs := flag.NewFlagSet("documents import", flag.ContinueOnError)
+	ownerEmail := fs.String("owner", "", "owner of the new document instead of the exported owner")
+	if err := a.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", fs.Arg(0), err)
+	}
+	var export documentExport
+	if err := json.Unmarshal(b, &export); err != nil {
+		return fmt.Errorf("error decoding %s: %w", fs.Arg(0), err)
+	}
+	if export.Format != exportFormat {
+		return fmt.Errorf("unsupported export format %d", export.Format)
+	}
+
+	if *ownerEmail == "" {
+		*ownerEmail = export.Owner
+	}
+	owner, err := a.userByEmail(*ownerEmail)
+	if err != nil {
+		return err
+	}
+
+	var collaborators []model.DocumentAccess
+	for _, access := range export.Access {
+		if access.Role == model.Creator {
+			access.Role = model.Edit
+		}
+		if access.Role != model.Edit && access.Role != model.Read {
+			return fmt.Errorf("invalid role %q for %s", access.Role, access.Email)
+		}
+		user, err := a.userByEmail(access.Email)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Skipping %s: no account", access.Email)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if user.ID == owner.ID {
+			continue
+		}
+		collaborators = append(collaborators, model.DocumentAccess{CollaboratorId: user.ID, Role: access.Role})
+	}
+
+	version := export.Version
+	if version < 1 {
+		version = 1
+	}
+	document := model.Document{
+		Title:            export.Title,
+		Content:          export.Content,
+		UserID:           owner.ID,
+		PublicVisibility: export.IsPublic,
+	}
+	err = a.documents.ExecuteInTransaction(func(tx *gorm.DB) error {
+		if err := a.documents.CreateWithTransaction(tx, &document); err != nil {
+			return fmt.Errorf("failed to create document: %w", err)
+		}
+		creatorAccess := model.DocumentAccess{CollaboratorId: owner.ID, DocumentId: document.ID, Role: model.Creator}
+		if err := a.documentAccess.CreateWithTransaction(tx, &creatorAccess); err != nil {
+			return fmt.Errorf("failed to create owner access: %w", err)
+		}
+		for _, access := range collaborators {
+			access.DocumentId = document.ID
+			if err := a.documentAccess.CreateWithTransaction(tx, &access); err != nil {
+				return fmt.Errorf("failed to create collaborator access: %w", err)
+			}
+		}
+		return a.documentMeta.CreateWithTransaction(tx, &model.DocumentMetadata{
+			DocumentID: document.ID,
+			Version:    version,
+			Metadata:   export.Metadata,
+		})
+	}, 1)
+	if err != nil {
+		return err
+	}
+
+	a.webhooks.Publish(a.ctx, model.WebhookDocumentCreated, &document, nil, gin.H{"title": document.Title})
+
+	fmt.Printf("Imported %q as %s, owned by %s with %d collaborators\n", document.Title, document.ID, owner.Email, len(collaborators))
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/jobs"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/pkg/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resendInvite issues a pending or expired invite a new link and expiry and
+// emails it; the previous link stops working.
+func resendInvite(a *app, args []string) error {
+	fs := flag.NewFlagSet("invites resend", flag.ContinueOnError)
+	if err := a.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	inviteId, err := parseID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var invite model.Invite
+	if err := a.invites.GetOne(inviteId, &invite); err != nil {
+		return fmt.Errorf("error finding invite: %w", err)
+	}
+	if invite.Status != model.InviteStatus(model.Pending) && invite.Status != model.InviteStatus(model.Expired) {
+		return fmt.Errorf("invite is %s and cannot be resent", invite.Status)
+	}
+	document, err := a.document(invite.DocumentId.String())
+	if err != nil {
+		return err
+	}
+
+	// Recipients who have an account get the email in their own language.
+	locale := ""
+	var recipient model.User
+	if err := a.users.GetByEmail(&recipient, *invite.Email); err == nil {
+		locale = recipient.Locale
+	}
+
+	token, err := utils.NewCodeGenerator().GenerateSecureToken(16)
+	if err != nil {
+		return fmt.Errorf("error generating invite token: %w", err)
+	}
+	expiresAt := time.Now().UTC().Add(controllers.InviteTTL)
+	if err := a.invites.Reissue(invite.ID, token, expiresAt); err != nil {
+		return err
+	}
+
+	if err := a.mailer.Send(a.ctx, *invite.Email, locale, "invite", handlers.Invite{
+		InviteLink:    fmt.Sprintf("%s/invite/%s", a.cfg.Frontend.RootURL, token),
+		DocumentTitle: document.Title,
+		Role:          invite.Role,
+		FullName:      *invite.Email,
+		Year:          time.Now().Year(),
+	}); err != nil {
+		return fmt.Errorf("invite reissued but not emailed: %w", err)
+	}
+
+	a.audit(model.AuditLog{
+		Action:     model.AuditInviteResent,
+		TargetType: "invite",
+		TargetID:   invite.ID.String(),
+		DocumentID: &invite.DocumentId,
+	}, gin.H{"status": invite.Status}, gin.H{"status": model.Pending, "expiresAt": expiresAt})
+
+	fmt.Printf("Resent invite to %s, valid until %s\n", *invite.Email, expiresAt.Format(time.DateTime))
+	return nil
+}
+
+// cleanupMedia runs one batch of the scheduled media cleanup job now.
+func cleanupMedia(a *app, args []string) error {
+	fs := flag.NewFlagSet("media cleanup", flag.ContinueOnError)
+	if err := a.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	jobs.NewReceiptCleanup(*a.documentMedia, a.cfg.Jobs).CleanupBatch(a.ctx)
+	return nil
+}
@@ -0,0 +1,186 @@
+// Command admin inspects and repairs data using the same configuration,
+// repositories and services as the server. Configuration flags go before the
+// command:
+//
+//	admin [flags] <command> [arguments]
+//
+// Run it without a command to list the commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"realTimeEditor/config"
+	"realTimeEditor/db"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/migrate"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/jwt"
+	"realTimeEditor/pkg/utils"
+	"sort"
+	"strings"
+)
+
+// actor is recorded as the actor of every audited change made here.
+const actor = "admin-cli"
+
+type command struct {
+	usage string
+	run   func(a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"users list":            {"users list [-search TEXT] [-limit N]", listUsers},
+	"users reset-password":  {"users reset-password EMAIL", resetPassword},
+	"users revoke-sessions": {"users revoke-sessions EMAIL", revokeSessions},
+	"users grant-admin":     {"users grant-admin EMAIL", grantAdmin},
+	"users revoke-admin":    {"users revoke-admin EMAIL", revokeAdmin},
+	"documents list":        {"documents list [-owner EMAIL] [-limit N]", listDocuments},
+	"documents transfer":    {"documents transfer DOCUMENT_ID NEW_OWNER_EMAIL", transferOwnership},
+	"documents render":      {"documents render DOCUMENT_ID", renderExport},
+	"documents export":      {"documents export [-o FILE] DOCUMENT_ID", exportDocument},
+	"documents import":      {"documents import [-owner EMAIL] FILE", importDocument},
+	"invites resend":        {"invites resend INVITE_ID", resendInvite},
+	"media cleanup":         {"media cleanup", cleanupMedia},
+}
+
+// app holds what the commands need, built the same way as in the server.
+type app struct {
+	ctx            context.Context
+	cfg            *config.Config
+	users          *repositories.UserRepository
+	documents      *repositories.DocumentRepository
+	documentAccess *repositories.DocumentAccessRepository
+	documentMeta   *repositories.DocumentMetaDataRepository
+	documentMedia  *repositories.DocumentMediaRepository
+	invites        *repositories.InviteRepository
+	auditor        *handlers.Auditor
+	sessions       *handlers.SessionManager
+	loginThrottler *handlers.LoginThrottler
+	mailer         *handlers.Mailer
+	passwordHasher *utils.PasswordHasher
+	webhooks       *handlers.WebhookPublisher
+	// usage is the usage line of the command being run.
+	usage string
+}
+
+func usage() string {
+	lines := make([]string, 0, len(commands))
+	for _, c := range commands {
+		lines = append(lines, "  admin [flags] "+c.usage)
+	}
+	sort.Strings(lines)
+	return "usage:\n" + strings.Join(lines, "\n")
+}
+
+func main() {
+	log.SetFlags(0)
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+	if len(cfg.Args) < 2 {
+		log.Fatal(usage())
+	}
+	cmd, ok := commands[cfg.Args[0]+" "+cfg.Args[1]]
+	if !ok {
+		log.Fatalf("unknown command %q\n%s", strings.Join(cfg.Args[:2], " "), usage())
+	}
+
+	config.ConnectToDB(cfg.Database)
+	defer config.CloseDB()
+
+	// Commands assume the current schema, so never run against an older one.
+	ctx := context.Background()
+	migrator, err := migrate.New(config.DB, db.Migrations)
+	if err != nil {
+		log.Fatalf("Error: %s", err)
+	}
+	if pending, err := migrator.Pending(ctx); err != nil {
+		log.Fatalf("Error checking migrations: %s", err)
+	} else if len(pending) > 0 {
+		log.Fatalf("Error: %d pending migrations; run \"migrate up\" first", len(pending))
+	}
+
+	if err := config.ConnectToCloudinary(cfg.Cloudinary); err != nil {
+		log.Fatalf("Error initializing media storage: %s", err)
+	}
+
+	a, err := newApp(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Error: %s", err)
+	}
+	a.usage = cmd.usage
+	if err := cmd.run(a, cfg.Args[2:]); err != nil {
+		log.Fatalf("Error: %s", err)
+	}
+}
+
+func newApp(ctx context.Context, cfg *config.Config) (*app, error) {
+	userRepo := repositories.NewUserRepository(config.DB)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(config.DB)
+	auditor := handlers.NewAuditor(repositories.NewAuditLogRepository(config.DB))
+	mailer := handlers.NewMailer(cfg.SMTP)
+
+	sessionService, err := jwt.NewSession(cfg.Auth.JWTSecret.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	return &app{
+		ctx:            ctx,
+		cfg:            cfg,
+		users:          userRepo,
+		documents:      repositories.NewDocumentRepository(config.DB),
+		documentAccess: repositories.NewDocumentAccessRepository(config.DB),
+		documentMeta:   repositories.NewDocumentMetaDataRepository(config.DB),
+		documentMedia:  repositories.NewDocumentMediaRepository(config.DB),
+		invites:        repositories.NewInviteRepository(config.DB),
+		auditor:        auditor,
+		sessions:       handlers.NewSessionManager(sessionService, refreshTokenRepo, userRepo, repositories.NewPersonalAccessTokenRepository(config.DB)),
+		loginThrottler: handlers.NewLoginThrottler(repositories.NewLoginThrottleRepository(config.DB), userRepo, auditor, mailer, cfg),
+		mailer:         mailer,
+		passwordHasher: utils.NewPasswordHasher(cfg.Auth.LegacySalt.Value()),
+		webhooks: handlers.NewWebhookPublisher(
+			repositories.NewWebhookRepository(config.DB),
+			repositories.NewWebhookDeliveryRepository(config.DB),
+		),
+	}, nil
+}
+
+// audit records a change made from the command line.
+func (a *app) audit(entry model.AuditLog, before, after handlers.AuditState) {
+	entry.ActorEmail = actor
+	entry.UserAgent = actor
+	a.auditor.Record(nil, entry, before, after)
+}
+
+func (a *app) userByEmail(email string) (*model.User, error) {
+	var user model.User
+	if err := a.users.GetByEmail(&user, strings.TrimSpace(email)); err != nil {
+		return nil, fmt.Errorf("error finding user %s: %w", email, err)
+	}
+	return &user, nil
+}
+
+// parse parses the command's own flags and checks it got exactly nargs
+// arguments.
+func (a *app) parse(fs *flag.FlagSet, args []string, nargs int) error {
+	fs.Usage = func() { fmt.Fprintf(fs.Output(), "usage: admin [flags] %s\n", a.usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != nargs {
+		return fmt.Errorf("usage: admin [flags] %s", a.usage)
+	}
+	return nil
+}
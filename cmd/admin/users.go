@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/pkg/utils"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func listUsers(a *app, args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	search := fs.String("search", "", "only users whose email or name contains this")
+	limit := fs.Int("limit", 50, "maximum number of users")
+	if err := a.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	users, err := a.users.List(*search, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tNAME\tVERIFIED\tADMIN\tCREATED")
+	for _, user := range users {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%t\t%s\n",
+			user.ID, user.Email, displayName(user), user.EmailVerified(), user.IsAdmin,
+			user.CreatedAt.Format(time.DateTime),
+		)
+	}
+	return w.Flush()
+}
+
+// resetPassword sets a random temporary password, prints it once and signs
+// the user out everywhere.
+func resetPassword(a *app, args []string) error {
+	fs := flag.NewFlagSet("users reset-password", flag.ContinueOnError)
+	if err := a.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	user, err := a.userByEmail(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	password, err := utils.NewCodeGenerator().GenerateSecureToken(12)
+	if err != nil {
+		return fmt.Errorf("error generating password: %w", err)
+	}
+	hashedPassword, err := a.passwordHasher.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+	if err := a.users.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("error updating password: %w", err)
+	}
+	if err := a.sessions.LogoutAll(user.ID); err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+	a.loginThrottler.Reset(model.ThrottleAccount, user.Email)
+
+	a.audit(model.AuditLog{
+		Action:     model.AuditPasswordReset,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	}, nil, nil)
+
+	fmt.Printf("Temporary password for %s: %s\n", user.Email, password)
+	return nil
+}
+
+func revokeSessions(a *app, args []string) error {
+	fs := flag.NewFlagSet("users revoke-sessions", flag.ContinueOnError)
+	if err := a.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	user, err := a.userByEmail(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := a.sessions.LogoutAll(user.ID); err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+
+	a.audit(model.AuditLog{
+		Action:     model.AuditLogoutAll,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	}, nil, nil)
+
+	fmt.Printf("Revoked every session of %s\n", user.Email)
+	return nil
+}
+
+func grantAdmin(a *app, args []string) error {
+	return setAdmin(a, "users grant-admin", args, true)
+}
+
+func revokeAdmin(a *app, args []string) error {
+	return setAdmin(a, "users revoke-admin", args, false)
+}
+
+// setAdmin gives or takes away the user's access to the admin routes, which
+// only this command can change.
+func setAdmin(a *app, name string, args []string, admin bool) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := a.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	user, err := a.userByEmail(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin == admin {
+		fmt.Printf("%s already has admin set to %t\n", user.Email, admin)
+		return nil
+	}
+	if err := a.users.SetAdmin(user.ID, admin); err != nil {
+		return fmt.Errorf("error updating user: %w", err)
+	}
+
+	action := model.AuditAdminGranted
+	if !admin {
+		action = model.AuditAdminRevoked
+	}
+	a.audit(model.AuditLog{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	}, gin.H{"isAdmin": user.IsAdmin}, gin.H{"isAdmin": admin})
+
+	fmt.Printf("Set admin to %t for %s\n", admin, user.Email)
+	return nil
+}
+
+func displayName(user model.User) string {
+	if user.FirstName == nil || user.LastName == nil {
+		return "-"
+	}
+	return *user.FirstName + " " + *user.LastName
+}
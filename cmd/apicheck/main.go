@@ -0,0 +1,45 @@
+// Command apicheck fails when the routes and the OpenAPI spec they are
+// generated from disagree: an operation without a handler, a handler the
+// spec does not list, an invalid middleware extension or paths that cannot
+// be served side by side. It needs no database, so CI can run it on every
+// change:
+//
+//	apicheck [spec]
+//
+// The spec defaults to ./api/swagger.yaml.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"realTimeEditor/internal/apispec"
+	"realTimeEditor/internal/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+	gin.SetMode(gin.ReleaseMode)
+
+	path := apispec.Path
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	spec, err := apispec.Load(path)
+	if err != nil {
+		log.Fatalf("Error: %s", err)
+	}
+	if err := router.CheckSpec(spec); err != nil {
+		log.Fatalf("API spec and routes disagree:\n%s", err)
+	}
+
+	operations := 0
+	for _, pathItem := range spec.Paths.Map() {
+		operations += len(pathItem.Operations())
+	}
+	fmt.Printf("%s: %d operations match their routes\n", path, operations)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"realTimeEditor/config"
+	"realTimeEditor/db"
+	"realTimeEditor/internal/health"
+	"realTimeEditor/internal/migrate"
+	"time"
+)
+
+// healthCheckTimeout bounds a whole probe; checks run concurrently.
+const healthCheckTimeout = 3 * time.Second
+
+// cloudinaryCheckInterval keeps readiness probes well below the Cloudinary
+// Admin API's hourly rate limit.
+const cloudinaryCheckInterval = time.Minute
+
+// registerReadinessChecks adds the checks of the dependencies shared by
+// every request: the database, its schema and, when configured, media
+// storage.
+func registerReadinessChecks(checker *health.Checker) error {
+	sqlDB, err := config.DB.DB()
+	if err != nil {
+		return err
+	}
+	checker.AddReadiness("database", sqlDB.PingContext)
+
+	migrator, err := migrate.New(config.DB, db.Migrations)
+	if err != nil {
+		return err
+	}
+	checker.AddReadiness("migrations", func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations", len(pending))
+		}
+		return nil
+	})
+
+	if config.Cloudinary != nil {
+		checker.AddReadiness("media", health.Cached(func(ctx context.Context) error {
+			cld, err := config.CloudinaryCredentials()
+			if err != nil {
+				return err
+			}
+			result, err := cld.Admin.Ping(ctx)
+			if err != nil {
+				return err
+			}
+			if result.Error.Message != "" {
+				return errors.New(result.Error.Message)
+			}
+			return nil
+		}, cloudinaryCheckInterval))
+	}
+	return nil
+}
@@ -2,26 +2,43 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"realTimeEditor/config"
+	"realTimeEditor/internal/apispec"
 	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/health"
 	"realTimeEditor/internal/jobs"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/metrics"
 	"realTimeEditor/internal/middlewares"
 	"realTimeEditor/internal/repositories"
 	"realTimeEditor/internal/router"
+	"realTimeEditor/internal/tracing"
 	"realTimeEditor/internal/ws"
+	"realTimeEditor/pkg/constants"
 	"realTimeEditor/pkg/jwt"
+	"realTimeEditor/pkg/utils"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
 	socketio "github.com/googollee/go-socket.io"
 	"github.com/googollee/go-socket.io/engineio"
 	"github.com/googollee/go-socket.io/engineio/transport"
 	"github.com/googollee/go-socket.io/engineio/transport/websocket"
+	"github.com/ulule/limiter/v3"
+	memstore "github.com/ulule/limiter/v3/drivers/store/memory"
 )
 
 func allowCORS(h http.Handler) http.Handler {
@@ -31,18 +48,35 @@ func allowCORS(h http.Handler) http.Handler {
 			if origin != "" {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+logging.RequestIDHeader)
 			}
 		}
 		h.ServeHTTP(w, r)
 	})
 }
 
-func CreateRouter(container *router.RouterContainer) *gin.Engine {
-	r := gin.Default()
+// fatal logs msg with err and exits; deferred calls do not run.
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
+}
+
+func CreateRouter(container *router.RouterContainer, spec *openapi3.T) (*gin.Engine, error) {
+	r := gin.New()
 
+	r.Use(tracing.GinMiddleware())
+	r.Use(logging.Middleware())
+	r.Use(metrics.GinMiddleware())
+	// Inside the logging and metrics middlewares so they see the status
+	// it writes, and outside Recovery so panics get a problem response.
+	r.Use(middlewares.ErrorHandler())
+	r.Use(logging.Recovery())
 	r.Use(middlewares.CORSMiddleware())
-	r.Use(middlewares.RateLimiterMiddleware())
+	r.Use(container.RateLimiter.Limit(constants.RateLimitGlobal))
 	r.Use(middlewares.SecureHeadersMiddleware())
 	r.Use(middlewares.CSPMiddleware())
 	r.Use(func(c *gin.Context) {
@@ -52,25 +86,68 @@ func CreateRouter(container *router.RouterContainer) *gin.Engine {
 
 	// Serve Swagger
 	r.Static("/swagger-ui", "./api/swagger-ui/dist")
-	r.StaticFile("/swagger.yaml", "./api/swagger.yaml")
+	r.StaticFile("/swagger.yaml", apispec.Path)
 	r.GET("/swagger", func(c *gin.Context) {
 		c.Redirect(http.StatusMovedPermanently, "/swagger-ui/index.html")
 	})
 
+	if err := container.RegisterV1(r, spec); err != nil {
+		return nil, err
+	}
 	container.Register(r)
-	return r
+	return r, nil
 }
 
 func main() {
-	// Step 1: Initialize DB
-	config.ConnectToDB()
+	// Step 1: Load configuration
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("Error loading configuration", err)
+	}
+	if err := logging.Setup(cfg.Log.Format, cfg.Log.Level); err != nil {
+		fatal("Error setting up logging", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("Error setting up tracing", err)
+	}
+	cfg.LogSummary()
+
+	// Step 2: Initialize DB, schema and media storage
+	config.ConnectToDB(cfg.Database)
 	if config.DB == nil {
-		log.Fatal("Database connection is not initialized")
+		fatal("Database connection is not initialized", nil)
+	}
+	slog.Info("Database connected")
+
+	if sqlDB, err := config.DB.DB(); err == nil {
+		metrics.RegisterDB(sqlDB)
+	}
+	if err := tracing.InstrumentDB(config.DB); err != nil {
+		fatal("Error instrumenting database", err)
 	}
-	defer config.CloseDB()
-	log.Println("Database connected")
 
-	// Step 2: Set up repositories
+	if len(cfg.Args) > 0 {
+		err := runCommand(context.Background(), cfg.Args)
+		config.CloseDB()
+		if err != nil {
+			fatal("Command failed", err)
+		}
+		return
+	}
+
+	if err := migrateOnStart(context.Background(), cfg.Database.MigrationMode); err != nil {
+		fatal("Error checking migrations", err)
+	}
+
+	if err := config.ConnectToCloudinary(cfg.Cloudinary); err != nil {
+		fatal("Error initializing media storage", err)
+	}
+
+	// Step 3: Set up repositories
 	userRepo := repositories.NewUserRepository(config.DB)
 	docRepo := repositories.NewDocumentRepository(config.DB)
 	docAccessRepo := repositories.NewDocumentAccessRepository(config.DB)
@@ -79,29 +156,21 @@ func main() {
 	docMetaRepo := repositories.NewDocumentMetaDataRepository(config.DB)
 	docMediaRepo := repositories.NewDocumentMediaRepository(config.DB)
 
-	// Step 3: Initialize controllers
-	userCtrl := controllers.NewUserHandler(userRepo, forgotPwdRepo)
-	docCtrl := controllers.NewDocumentController(docRepo, docAccessRepo, inviteRepo, userRepo, docMetaRepo, docMediaRepo)
-	docMetaCtrl := controllers.NewDocumentMetaDataController(docRepo, docMetaRepo)
-
-	// Step 4: Auth middleware & session service
-	authMiddleware := &middlewares.AuthMiddleware{UserRepository: userRepo}
-	sessionService, err := jwt.NewSession()
-	if err != nil {
-		log.Fatalf("Error initializing session: %s", err)
-	}
-
-	// Step 5: Set up router
-	container := router.RouterContainer{
-		UserController:             userCtrl,
-		DocumentController:         docCtrl,
-		DocumentMetadataController: docMetaCtrl,
-		AuthMiddleware:             authMiddleware,
-		Session:                    sessionService,
-	}
-	apiRouter := CreateRouter(&container)
+	notificationRepo := repositories.NewNotificationRepository(config.DB)
+	notificationPrefRepo := repositories.NewNotificationPreferenceRepository(config.DB)
+	webhookRepo := repositories.NewWebhookRepository(config.DB)
+	webhookDeliveryRepo := repositories.NewWebhookDeliveryRepository(config.DB)
+	auditLogRepo := repositories.NewAuditLogRepository(config.DB)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(config.DB)
+	twoFactorRepo := repositories.NewTwoFactorRepository(config.DB)
+	userIdentityRepo := repositories.NewUserIdentityRepository(config.DB)
+	oidcAuthRequestRepo := repositories.NewOIDCAuthRequestRepository(config.DB)
+	personalTokenRepo := repositories.NewPersonalAccessTokenRepository(config.DB)
+	emailVerificationRepo := repositories.NewEmailVerificationRepository(config.DB)
+	loginThrottleRepo := repositories.NewLoginThrottleRepository(config.DB)
+	rateLimitRepo := repositories.NewRateLimitRepository(config.DB)
 
-	// Step 6: WebSocket server setup
+	// Step 4: WebSocket server setup
 	socketServer := socketio.NewServer(&engineio.Options{
 		Transports: []transport.Transport{
 			&websocket.Transport{
@@ -113,63 +182,190 @@ func main() {
 		PingTimeout:  60 * time.Second,
 		PingInterval: 25 * time.Second,
 	})
+	mailer := handlers.NewMailer(cfg.SMTP)
+	passwordHasher := utils.NewPasswordHasher(cfg.Auth.LegacySalt.Value())
+	notifier := handlers.NewNotifier(notificationRepo, notificationPrefRepo, socketServer, mailer, cfg)
+	webhookPublisher := handlers.NewWebhookPublisher(webhookRepo, webhookDeliveryRepo)
+	auditor := handlers.NewAuditor(auditLogRepo)
 
-	// Step 7: Register socket events
-	socketHandler := ws.NewSocketHandler(docRepo, docAccessRepo, sessionService, userRepo)
+	sessionService, err := jwt.NewSession(cfg.Auth.JWTSecret.Value())
+	if err != nil {
+		fatal("Error initializing session", err)
+	}
+	sessions := handlers.NewSessionManager(sessionService, refreshTokenRepo, userRepo, personalTokenRepo)
+	twoFactor := handlers.NewTwoFactorService(twoFactorRepo)
+	emailVerifier := handlers.NewEmailVerifier(emailVerificationRepo, userRepo, refreshTokenRepo, personalTokenRepo, mailer, cfg)
+	loginThrottler := handlers.NewLoginThrottler(loginThrottleRepo, userRepo, auditor, mailer, cfg)
+
+	oidcService := handlers.NewOIDCService(cfg.OIDC, oidcAuthRequestRepo, userIdentityRepo, userRepo, inviteRepo, emailVerifier)
+
+	// Step 5: Initialize controllers
+	userCtrl := controllers.NewUserHandler(userRepo, forgotPwdRepo, inviteRepo, auditor, sessions, twoFactor, emailVerifier, loginThrottler, mailer, passwordHasher)
+	docCtrl := controllers.NewDocumentController(docRepo, docAccessRepo, inviteRepo, userRepo, docMetaRepo, docMediaRepo, notifier, webhookPublisher, auditor, sessions, emailVerifier, mailer, cfg)
+	docMetaCtrl := controllers.NewDocumentMetaDataController(docRepo, docMetaRepo)
+	adminCtrl := controllers.NewAdminController(auditLogRepo)
+	notificationCtrl := controllers.NewNotificationController(notificationRepo, notificationPrefRepo)
+	webhookCtrl := controllers.NewWebhookController(webhookRepo, webhookDeliveryRepo, docRepo, webhookPublisher)
+	twoFactorCtrl := controllers.NewTwoFactorController(twoFactor, auditor, passwordHasher)
+	oidcCtrl := controllers.NewOIDCController(oidcService, sessions, twoFactor, auditor, cfg)
+	personalTokenCtrl := controllers.NewPersonalAccessTokenController(personalTokenRepo, auditor)
+
+	// Step 6: Auth middleware
+	authMiddleware := &middlewares.AuthMiddleware{
+		UserRepository:                userRepo,
+		PersonalAccessTokenRepository: personalTokenRepo,
+	}
+
+	rateLimitStore := memstore.NewStore()
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimitStore = middlewares.NewPostgresStore(rateLimitRepo)
+	}
+	rateLimiter, err := middlewares.NewRateLimiter(rateLimitStore, cfg.RateLimit.Policies)
+	if err != nil {
+		fatal("Error loading rate limits", err)
+	}
+
+	// Step 7: Set up router
+	container := router.RouterContainer{
+		UserController:                userCtrl,
+		DocumentController:            docCtrl,
+		DocumentMetadataController:    docMetaCtrl,
+		AdminController:               adminCtrl,
+		NotificationController:        notificationCtrl,
+		WebhookController:             webhookCtrl,
+		TwoFactorController:           twoFactorCtrl,
+		OIDCController:                oidcCtrl,
+		PersonalAccessTokenController: personalTokenCtrl,
+		AuthMiddleware:                authMiddleware,
+		RateLimiter:                   rateLimiter,
+		Session:                       sessionService,
+	}
+	spec, err := apispec.Load(apispec.Path)
+	if err != nil {
+		fatal("Error loading API spec", err)
+	}
+	apiRouter, err := CreateRouter(&container, spec)
+	if err != nil {
+		fatal("Error registering API routes", err)
+	}
+
+	// Step 8: Register socket events
+	socketEditRate, err := limiter.NewRateFromFormatted(cfg.RateLimit.Policies[constants.RateLimitSocket])
+	if err != nil {
+		fatal("Error loading rate limits", err)
+	}
+	socketEditLimiter := limiter.New(memstore.NewStore(), socketEditRate)
+	socketHandler := ws.NewSocketHandler(docRepo, docAccessRepo, sessionService, userRepo, webhookPublisher, socketEditLimiter)
 	socketHandler.RegisterEvents(socketServer)
+	metrics.RegisterSocketServer(socketServer, handlers.SocketNamespace)
 
 	// Error handler for socket server
 	socketServer.OnError("/", func(conn socketio.Conn, err error) {
 		if conn != nil {
-			log.Printf("Socket error [%s]: %v", conn.ID(), err)
+			logging.Socket(conn).Error("Socket error", "error", err)
 			conn.Emit("error", err.Error())
 		} else {
-			log.Printf("Socket error [nil conn]: %v", err)
+			slog.Error("Socket error", "error", err)
 		}
 	})
 
-	// Serve socket server
+	// Serve socket server. A server that stops on its own shuts the
+	// process down like a signal would.
+	serverErr := make(chan error, 2)
+	var socketServing atomic.Bool
+	socketServing.Store(true)
 	go func() {
-		if err := socketServer.Serve(); err != nil {
-			log.Fatalf("SocketIO server error: %v", err)
+		err := socketServer.Serve()
+		socketServing.Store(false)
+		if err != nil {
+			serverErr <- fmt.Errorf("socket server: %w", err)
 		}
 	}()
-	defer socketServer.Close()
 
-	// Step 8: Setup background cleanup job
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	cleanUpJob := jobs.NewReceiptCleanup(*docMediaRepo)
-	go cleanUpJob.Start(ctx)
+	// Step 9: Setup background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	cleanUpJob := jobs.NewReceiptCleanup(*docMediaRepo, cfg.Jobs)
+	webhookDispatcher := jobs.NewWebhookDispatcher(webhookDeliveryRepo, webhookPublisher.Pending(), cfg.Jobs)
+	tokenCleanupJob := jobs.NewTokenCleanup(refreshTokenRepo, oidcAuthRequestRepo, forgotPwdRepo, emailVerificationRepo, loginThrottleRepo, rateLimitRepo, cfg.Jobs)
+	inviteExpiryJob := jobs.NewInviteExpiry(inviteRepo, cfg.Jobs)
+	var workers sync.WaitGroup
+	for _, job := range []interface{ Start(context.Context) }{cleanUpJob, webhookDispatcher, tokenCleanupJob, inviteExpiryJob} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			job.Start(jobsCtx)
+		}()
+	}
 
-	// Step 9: Compose final HTTP server with both API and WS
+	// Step 10: Compose final HTTP server with both API and WS
 	mux := http.NewServeMux()
 	mux.Handle("/socket.io/", allowCORS(socketServer))
-	mux.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	}))
+	checker := health.NewChecker(healthCheckTimeout)
+	checker.AddLiveness("socket", func(context.Context) error {
+		if !socketServing.Load() {
+			return errors.New("socket server is not running")
+		}
+		return nil
+	})
+	checker.AddLiveness("webhook_dispatcher", webhookDispatcher.HealthCheck())
+	if err := registerReadinessChecks(checker); err != nil {
+		fatal("Error setting up readiness checks", err)
+	}
+	mux.Handle("/livez", checker.Livez())
+	mux.Handle("/readyz", checker.Readyz())
+	// Kept for existing probes; same as /livez.
+	mux.Handle("/health", checker.Livez())
+	mux.Handle("/metrics", metrics.Handler(cfg.Metrics.Token.Value()))
 	mux.Handle("/", apiRouter)
 
 	server := &http.Server{
-		Addr:    ":9091",
-		Handler: mux,
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
 	}
 
-	// Step 10: Start server in goroutine
+	// Step 11: Start server in goroutine
 	go func() {
-		log.Println("Starting server on port 9091...")
+		slog.Info("Starting server", "port", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			serverErr <- fmt.Errorf("http server: %w", err)
 		}
 	}()
 
-	// Step 11: Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
-	log.Println("Shutting down server...")
+	// Step 12: Graceful shutdown on SIGINT or SIGTERM
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case <-signals.Done():
+		slog.Info("Shutdown signal received")
+	case err := <-serverErr:
+		slog.Error("Server stopped unexpectedly", "error", err)
+		exitCode = 1
+	}
+	// A second signal kills the process right away.
+	stopSignals()
 
-	if err := server.Shutdown(context.Background()); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	slog.Info("Draining before shutdown", "delay", cfg.Server.DrainDelay)
+	checker.Drain()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	slog.Info("Shutting down", "timeout", cfg.Server.ShutdownTimeout)
+	err = shutdown(cfg.Server.ShutdownTimeout, []shutdownStep{
+		{"sockets", func(ctx context.Context) error {
+			err := socketHandler.Shutdown(ctx, socketServer)
+			return errors.Join(err, socketServer.Close())
+		}},
+		{"http", server.Shutdown},
+		{"jobs", func(ctx context.Context) error {
+			stopJobs()
+			return utils.WaitContext(ctx, &workers)
+		}},
+		{"emails", mailer.Wait},
+		{"database", func(context.Context) error { return config.CloseDB() }},
+		{"traces", shutdownTracing},
+	})
+	if err != nil {
+		exitCode = 1
 	}
+	os.Exit(exitCode)
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"realTimeEditor/config"
+	"realTimeEditor/db"
+	"realTimeEditor/internal/migrate"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = "usage: migrate up [N] | down [N] | status | baseline VERSION"
+
+// runCommand runs a command given after the flags instead of starting the
+// server.
+func runCommand(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// migrateOnStart applies pending migrations in "apply" mode; in "check" mode
+// it fails if any are pending, so a release never runs against an older
+// schema.
+func migrateOnStart(ctx context.Context, mode string) error {
+	migrator, err := migrate.New(config.DB, db.Migrations)
+	if err != nil {
+		return err
+	}
+
+	if mode == "apply" {
+		done, err := migrator.Up(ctx, 0)
+		for _, m := range done {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		return err
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf(
+			"%d pending migrations, starting with %d_%s; run \"migrate up\" or set MIGRATION_MODE=apply",
+			len(pending), pending[0].Version, pending[0].Name,
+		)
+	}
+	return nil
+}
+
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	migrator, err := migrate.New(config.DB, db.Migrations)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		limit, err := countArg(args, 0)
+		if err != nil {
+			return err
+		}
+		done, err := migrator.Up(ctx, limit)
+		for _, m := range done {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			slog.Info("No pending migrations")
+		}
+		return err
+
+	case "down":
+		steps, err := countArg(args, 1)
+		if err != nil {
+			return err
+		}
+		done, err := migrator.Down(ctx, steps)
+		for _, m := range done {
+			slog.Info("Reverted migration", "version", m.Version, "name", m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Missing:
+				state = "applied, no file"
+			case s.Modified:
+				state = "applied, modified since"
+			case s.AppliedAt != nil:
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, state)
+		}
+		return w.Flush()
+
+	case "baseline":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		done, err := migrator.Baseline(ctx, version)
+		for _, m := range done {
+			slog.Info("Recorded migration as applied", "version", m.Version, "name", m.Name)
+		}
+		return err
+
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// countArg reads the optional count after a migrate subcommand.
+func countArg(args []string, fallback int) (int, error) {
+	if len(args) < 2 {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid count %q", args[1])
+	}
+	return n, nil
+}
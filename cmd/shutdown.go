@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// shutdownStep is one stage of graceful shutdown.
+type shutdownStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// shutdown runs steps in order under a shared deadline. A failed or timed
+// out step is logged and the following steps still run, so the database is
+// closed and traces are flushed even when requests did not finish in time.
+func shutdown(timeout time.Duration, steps []shutdownStep) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for _, step := range steps {
+		start := time.Now()
+		if err := step.run(ctx); err != nil {
+			slog.Error("Shutdown step failed", "step", step.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		slog.Info("Shutdown step done", "step", step.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
@@ -1,30 +1,36 @@
 package config
 
 import (
-	"context"
+	"errors"
 	"fmt"
-	"realTimeEditor/pkg/constants"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 )
 
-func CloudinaryCredentials() (*cloudinary.Cloudinary, context.Context, error) {
-	envVars, err := constants.LoadEnv()
-	if err != nil {
-		return nil, nil, fmt.Errorf("error loading environment variables: %w", err)
+var ErrCloudinaryDisabled = errors.New("media storage is not configured")
+
+var Cloudinary *cloudinary.Cloudinary
+
+// ConnectToCloudinary sets up the Cloudinary client. A nil cfg leaves media
+// storage disabled.
+func ConnectToCloudinary(cfg *CloudinaryConfig) error {
+	if cfg == nil {
+		return nil
 	}
 
-	cld, err := cloudinary.NewFromParams(
-		envVars.CLOUD_NAME,
-		envVars.API_KEY,
-		envVars.API_SECRET,
-	)
+	cld, err := cloudinary.NewFromParams(cfg.CloudName, cfg.APIKey, cfg.APISecret.Value())
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to initialize Cloudinary: %w", err)
+		return fmt.Errorf("failed to initialize Cloudinary: %w", err)
 	}
-
 	cld.Config.URL.Secure = true
-	ctx := context.Background()
 
-	return cld, ctx, nil
+	Cloudinary = cld
+	return nil
+}
+
+func CloudinaryCredentials() (*cloudinary.Cloudinary, error) {
+	if Cloudinary == nil {
+		return nil, ErrCloudinaryDisabled
+	}
+	return Cloudinary, nil
 }
@@ -0,0 +1,412 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the application configuration. It is loaded once at startup by
+// Load and handed to the services that need it.
+type Config struct {
+	Server   ServerConfig
+	Database DatabaseConfig
+	Auth     AuthConfig
+	Frontend FrontendConfig
+	// SMTP is nil when email is not configured; mail is then skipped.
+	SMTP *SMTPConfig
+	// Cloudinary is nil when media storage is not configured; uploads and
+	// PDF exports then fail with ErrCloudinaryDisabled.
+	Cloudinary *CloudinaryConfig
+	OIDC       []OIDCProvider
+	RateLimit  RateLimitConfig
+	Jobs       JobsConfig
+	Metrics    MetricsConfig
+	Log        LogConfig
+	Tracing    TracingConfig
+	// Args are the command line arguments left after the flags, such as
+	// "migrate up".
+	Args []string
+}
+
+type ServerConfig struct {
+	Port              int
+	ReadHeaderTimeout time.Duration
+	// DrainDelay is how long /readyz fails before the server stops taking
+	// requests on shutdown, so load balancers can take it out of rotation.
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds the rest of shutdown: finishing requests,
+	// socket edits, jobs and background emails.
+	ShutdownTimeout time.Duration
+}
+
+type DatabaseConfig struct {
+	// URI usually carries the database password.
+	URI         Secret
+	SSLCertPath string
+	// MigrationMode is "check", which refuses to start while migrations are
+	// pending, or "apply", which applies them first.
+	MigrationMode string
+}
+
+type AuthConfig struct {
+	JWTSecret Secret
+	// LegacySalt verifies password hashes created before per-user salts.
+	LegacySalt Secret
+}
+
+type FrontendConfig struct {
+	RootURL string
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password Secret
+	Timeout  time.Duration
+}
+
+type CloudinaryConfig struct {
+	CloudName string
+	APIKey    string
+	APISecret Secret
+}
+
+type JobsConfig struct {
+	DocumentCleanupSchedule string
+	// ReceiptTTL is how long a generated PDF stays in storage.
+	ReceiptTTL           time.Duration
+	TokenCleanupSchedule string
+	InviteExpirySchedule string
+	WebhookPollInterval  time.Duration
+}
+
+type MetricsConfig struct {
+	// Token, when set, must be sent as a bearer token to read /metrics.
+	Token Secret
+}
+
+type LogConfig struct {
+	// Format is "text" or "json".
+	Format string
+	// Level is "debug", "info", "warn" or "error".
+	Level string
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "otlp" or "stdout".
+	Exporter string
+	// OTLPEndpoint is the collector's host:port; when empty the standard
+	// OTEL_EXPORTER_OTLP_* variables apply, defaulting to localhost:4318.
+	OTLPEndpoint string
+	// SampleRatio is the share of new traces recorded; requests that arrive
+	// with a sampled trace context are always recorded.
+	SampleRatio float64
+	ServiceName string
+}
+
+// Secret is a configuration value that must never be logged. It prints as
+// "[redacted]"; Value returns the real thing.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// setting is a configuration value that can also be given as a command line
+// flag. Flags are named after the variable: SMTP_HOST is -smtp-host.
+type setting struct {
+	name     string
+	fallback string
+	usage    string
+}
+
+var settings = []setting{
+	{"PORT", "9091", "HTTP port to listen on"},
+	{"READ_HEADER_TIMEOUT", "10s", "time allowed to read request headers"},
+	{"SHUTDOWN_DRAIN_DELAY", "5s", "how long readiness fails before shutdown stops accepting requests"},
+	{"SHUTDOWN_TIMEOUT", "30s", "deadline for finishing in-flight work once shutdown stops accepting requests"},
+	{"DB_URI", "", "Postgres connection URI (required)"},
+	{"SSL_CERT_PATH", "", "CA certificate for the database connection"},
+	{"MIGRATION_MODE", "check", "on startup, check for or apply pending migrations: check or apply"},
+	{"JWT_SECRET", "", "secret used to sign session tokens (required)"},
+	{"SALT", "", "global salt of password hashes created before per-user salts"},
+	{"FE_ROOT_URL", "", "frontend base URL used in emails and redirects (required)"},
+	{"SMTP_HOST", "", "SMTP server; email is disabled when empty"},
+	{"SMTP_PORT", "465", "SMTP port (implicit TLS)"},
+	{"SMTP_USER", "", "SMTP user, also the sender address"},
+	{"SMTP_PASS", "", "SMTP password"},
+	{"SMTP_TIMEOUT", "10s", "timeout for connecting to the SMTP server, and again for the rest of sending each email"},
+	{"CLOUD_NAME", "", "Cloudinary cloud; media storage is disabled when empty"},
+	{"API_KEY", "", "Cloudinary API key"},
+	{"API_SECRET", "", "Cloudinary API secret"},
+	{"OIDC_PROVIDERS", "", "comma separated OpenID Connect providers"},
+	{"OIDC_REDIRECT_BASE_URL", "", "public base URL for OIDC callbacks"},
+	{"RATE_LIMIT_STORE", "postgres", "rate limit counter store: postgres or memory"},
+	{"DOCUMENT_CLEANUP_SCHEDULE", "0 */20 * * * *", "cron schedule (with seconds) of the PDF cleanup job"},
+	{"RECEIPT_TTL", "20m", "how long generated PDFs are kept"},
+	{"TOKEN_CLEANUP_SCHEDULE", "0 0 * * * *", "cron schedule (with seconds) of the token cleanup job"},
+	{"INVITE_EXPIRY_SCHEDULE", "0 30 * * * *", "cron schedule (with seconds) of the invite expiry job"},
+	{"WEBHOOK_POLL_INTERVAL", "10s", "how often the webhook dispatcher looks for due deliveries"},
+	{"METRICS_TOKEN", "", "bearer token required to read /metrics; open when empty"},
+	{"LOG_FORMAT", "text", "log output format: text or json"},
+	{"LOG_LEVEL", "info", "minimum log level: debug, info, warn or error"},
+	{"TRACE_EXPORTER", "none", "where to send traces: none, otlp or stdout"},
+	{"TRACE_OTLP_ENDPOINT", "", "OTLP/HTTP collector host:port, e.g. localhost:4318"},
+	{"TRACE_SAMPLE_RATIO", "1", "share of new traces to record, from 0 to 1"},
+	{"TRACE_SERVICE_NAME", "realtime-editor", "service name reported with traces"},
+}
+
+// source resolves a variable from, in order: a command line flag, the
+// environment, a file named by <NAME>_FILE (for mounted secrets), the
+// dotenv file and the setting's default.
+type source struct {
+	args     []string
+	flags    map[string]string
+	defaults map[string]string
+	errs     []error
+}
+
+func newSource(args []string) (*source, error) {
+	fs := flag.NewFlagSet("realTimeEditor", flag.ContinueOnError)
+	envFile := fs.String("env-file", ".env", "dotenv file to read")
+	defaults := make(map[string]string, len(settings))
+	for _, s := range settings {
+		fs.String(flagName(s.name), s.fallback, s.usage)
+		defaults[s.name] = s.fallback
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	flags := make(map[string]string)
+	envFileSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "env-file" {
+			envFileSet = true
+			return
+		}
+		flags[strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))] = f.Value.String()
+	})
+
+	// Variables already in the environment win over the dotenv file.
+	if path, err := filepath.Abs(*envFile); err != nil {
+		return nil, fmt.Errorf("error resolving env file: %w", err)
+	} else if err := godotenv.Load(path); err != nil {
+		if envFileSet || !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error loading env file %s: %w", path, err)
+		}
+		slog.Info("No .env file; using the environment only", "path", path)
+	}
+
+	return &source{args: fs.Args(), flags: flags, defaults: defaults}, nil
+}
+
+func flagName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+func (s *source) get(name string) string {
+	if v, ok := s.flags[name]; ok {
+		return strings.TrimSpace(v)
+	}
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			s.errs = append(s.errs, fmt.Errorf("error reading %s_FILE: %w", name, err))
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
+	return s.defaults[name]
+}
+
+func (s *source) required(name string) string {
+	v := s.get(name)
+	if v == "" {
+		s.errs = append(s.errs, fmt.Errorf("%s is required", name))
+	}
+	return v
+}
+
+func (s *source) port(name string) int {
+	v := s.get(name)
+	port, err := strconv.Atoi(v)
+	if err != nil || port < 1 || port > 65535 {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a port number, got %q", name, v))
+	}
+	return port
+}
+
+func (s *source) ratio(name string) float64 {
+	v := s.get(name)
+	r, err := strconv.ParseFloat(v, 64)
+	if err != nil || r < 0 || r > 1 {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a number from 0 to 1, got %q", name, v))
+	}
+	return r
+}
+
+func (s *source) duration(name string) time.Duration {
+	v := s.get(name)
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a positive duration such as 30s, got %q", name, v))
+	}
+	return d
+}
+
+// Load reads the configuration from flags in args, the environment, secret
+// files and the dotenv file, and reports every invalid or missing value at
+// once.
+func Load(args []string) (*Config, error) {
+	src, err := newSource(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:              src.port("PORT"),
+			ReadHeaderTimeout: src.duration("READ_HEADER_TIMEOUT"),
+			DrainDelay:        src.duration("SHUTDOWN_DRAIN_DELAY"),
+			ShutdownTimeout:   src.duration("SHUTDOWN_TIMEOUT"),
+		},
+		Database: DatabaseConfig{
+			URI:           Secret(src.required("DB_URI")),
+			SSLCertPath:   src.get("SSL_CERT_PATH"),
+			MigrationMode: strings.ToLower(src.get("MIGRATION_MODE")),
+		},
+		Auth: AuthConfig{
+			JWTSecret:  Secret(src.required("JWT_SECRET")),
+			LegacySalt: Secret(src.get("SALT")),
+		},
+		Frontend: FrontendConfig{
+			RootURL: strings.TrimRight(src.required("FE_ROOT_URL"), "/"),
+		},
+		Jobs: JobsConfig{
+			DocumentCleanupSchedule: src.get("DOCUMENT_CLEANUP_SCHEDULE"),
+			ReceiptTTL:              src.duration("RECEIPT_TTL"),
+			TokenCleanupSchedule:    src.get("TOKEN_CLEANUP_SCHEDULE"),
+			InviteExpirySchedule:    src.get("INVITE_EXPIRY_SCHEDULE"),
+			WebhookPollInterval:     src.duration("WEBHOOK_POLL_INTERVAL"),
+		},
+		Metrics: MetricsConfig{
+			Token: Secret(src.get("METRICS_TOKEN")),
+		},
+		Log: LogConfig{
+			Format: strings.ToLower(src.get("LOG_FORMAT")),
+			Level:  strings.ToLower(src.get("LOG_LEVEL")),
+		},
+		Tracing: TracingConfig{
+			Exporter:     strings.ToLower(src.get("TRACE_EXPORTER")),
+			OTLPEndpoint: src.get("TRACE_OTLP_ENDPOINT"),
+			SampleRatio:  src.ratio("TRACE_SAMPLE_RATIO"),
+			ServiceName:  src.get("TRACE_SERVICE_NAME"),
+		},
+		Args: src.args,
+	}
+
+	if mode := cfg.Database.MigrationMode; mode != "check" && mode != "apply" {
+		src.errs = append(src.errs, fmt.Errorf("MIGRATION_MODE must be check or apply, got %q", mode))
+	}
+
+	if format := cfg.Log.Format; format != "text" && format != "json" {
+		src.errs = append(src.errs, fmt.Errorf("LOG_FORMAT must be text or json, got %q", format))
+	}
+	switch cfg.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		src.errs = append(src.errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", cfg.Log.Level))
+	}
+
+	switch cfg.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		src.errs = append(src.errs, fmt.Errorf("TRACE_EXPORTER must be none, otlp or stdout, got %q", cfg.Tracing.Exporter))
+	}
+
+	if cfg.Database.SSLCertPath != "" {
+		path, err := filepath.Abs(cfg.Database.SSLCertPath)
+		if err != nil {
+			src.errs = append(src.errs, fmt.Errorf("error resolving SSL_CERT_PATH: %w", err))
+		}
+		cfg.Database.SSLCertPath = path
+	}
+
+	if src.get("SMTP_HOST") != "" {
+		cfg.SMTP = &SMTPConfig{
+			Host:     src.get("SMTP_HOST"),
+			Port:     src.port("SMTP_PORT"),
+			User:     src.required("SMTP_USER"),
+			Password: Secret(src.required("SMTP_PASS")),
+			Timeout:  src.duration("SMTP_TIMEOUT"),
+		}
+	}
+
+	if src.get("CLOUD_NAME") != "" {
+		cfg.Cloudinary = &CloudinaryConfig{
+			CloudName: src.get("CLOUD_NAME"),
+			APIKey:    src.required("API_KEY"),
+			APISecret: Secret(src.required("API_SECRET")),
+		}
+	}
+
+	cfg.OIDC = src.oidcProviders()
+	cfg.RateLimit = src.rateLimits()
+
+	if err := errors.Join(src.errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// LogSummary logs which optional features are enabled. Secrets are never
+// included.
+func (c *Config) LogSummary() {
+	enabled := func(on bool) string {
+		if on {
+			return "enabled"
+		}
+		return "disabled"
+	}
+	providers := make([]string, 0, len(c.OIDC))
+	for _, p := range c.OIDC {
+		providers = append(providers, p.Name)
+	}
+	slog.Info("Configuration loaded",
+		"port", c.Server.Port,
+		"email", enabled(c.SMTP != nil),
+		"media", enabled(c.Cloudinary != nil),
+		"oidc", providers,
+		"rateLimitStore", c.RateLimit.Store,
+	)
+}
@@ -2,11 +2,6 @@ package config
 
 import (
 	"fmt"
-	"log"
-	"path/filepath"
-
-	"realTimeEditor/internal/model"
-	"realTimeEditor/pkg/constants"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,23 +9,14 @@ import (
 
 var DB *gorm.DB
 
-func ConnectToDB() {
-	envVars, err := constants.LoadEnv()
-	if err != nil {
-		log.Printf("Error loading environment variables: %v\n", err)
-		return
-	}
-
-	dbUri := envVars.DB_URI
+// ConnectToDB opens the database. The schema is managed by the migrations in
+// db/migrations, not created here.
+func ConnectToDB(cfg DatabaseConfig) {
+	dbUri := cfg.URI.Value()
 
 	// Append SSL cert only if provided (for Aiven or other managed DBs)
-	if envVars.SSL_CERT_PATH != "" {
-		sslCertPath, err := filepath.Abs(envVars.SSL_CERT_PATH)
-		if err != nil {
-			log.Printf("Error resolving SSL_CERT_PATH: %v\n", err)
-			return
-		}
-		dbUri = fmt.Sprintf("%s&sslrootcert=%s", dbUri, sslCertPath)
+	if cfg.SSLCertPath != "" {
+		dbUri = fmt.Sprintf("%s&sslrootcert=%s", dbUri, cfg.SSLCertPath)
 	}
 
 	db, err := gorm.Open(postgres.Open(dbUri), &gorm.Config{})
@@ -38,13 +24,6 @@ func ConnectToDB() {
 		panic(fmt.Sprintf("Error connecting to database: %v", err))
 	}
 
-	if err := db.AutoMigrate(
-		&model.User{}, &model.Document{}, &model.DocumentAccess{}, &model.Invite{},
-		&model.ForgotPassword{}, &model.DocumentMetadata{}, &model.DocumentMedia{},
-	); err != nil {
-		panic(fmt.Sprintf("Error during migration: %v", err))
-	}
-
 	DB = db
 	fmt.Println("Database connection initialized successfully")
 }
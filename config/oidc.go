@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OIDCProvider is one OpenID Connect provider users can sign in with.
+type OIDCProvider struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret Secret
+	Scopes       []string
+	RedirectURL  string
+}
+
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// oidcProviders reads the providers listed in OIDC_PROVIDERS, e.g.
+// "google,okta". Each provider NAME is configured with OIDC_NAME_ISSUER_URL,
+// OIDC_NAME_CLIENT_ID, OIDC_NAME_CLIENT_SECRET (optional for public clients)
+// and OIDC_NAME_SCOPES (optional, space separated). Callbacks are served at
+// OIDC_REDIRECT_BASE_URL/auth/oidc/<name>/callback. No providers is valid.
+func (s *source) oidcProviders() []OIDCProvider {
+	list := s.get("OIDC_PROVIDERS")
+	if list == "" {
+		return nil
+	}
+
+	baseURL := strings.TrimRight(s.required("OIDC_REDIRECT_BASE_URL"), "/")
+
+	var providers []OIDCProvider
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !oidcProviderName.MatchString(name) {
+			s.errs = append(s.errs, fmt.Errorf("invalid OIDC provider name %q", name))
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			IssuerURL:    s.required(prefix + "ISSUER_URL"),
+			ClientID:     s.required(prefix + "CLIENT_ID"),
+			ClientSecret: Secret(s.get(prefix + "CLIENT_SECRET")),
+			Scopes:       strings.Fields(s.get(prefix + "SCOPES")),
+			RedirectURL:  baseURL + "/auth/oidc/" + name + "/callback",
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
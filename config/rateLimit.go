@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"realTimeEditor/pkg/constants"
+	"strings"
+)
+
+var defaultRateLimits = map[string]string{
+	constants.RateLimitGlobal:    "600-M",
+	constants.RateLimitAuth:      "20-M",
+	constants.RateLimitPublic:    "60-M",
+	constants.RateLimitAPI:       "300-M",
+	constants.RateLimitExpensive: "10-M",
+	constants.RateLimitAdmin:     "60-M",
+	constants.RateLimitSocket:    "20-S",
+}
+
+// RateLimitConfig selects where counters are kept and the rate of each
+// policy. Store is "postgres" (shared by every replica) or "memory" (per
+// process, for local development).
+type RateLimitConfig struct {
+	Store    string
+	Policies map[string]string
+}
+
+func (s *source) rateLimits() RateLimitConfig {
+	store := strings.ToLower(s.get("RATE_LIMIT_STORE"))
+	if store != "postgres" && store != "memory" {
+		s.errs = append(s.errs, fmt.Errorf("RATE_LIMIT_STORE must be postgres or memory, got %q", store))
+	}
+
+	policies := make(map[string]string, len(defaultRateLimits))
+	for name, rate := range defaultRateLimits {
+		if override := s.get("RATE_LIMIT_" + strings.ToUpper(name)); override != "" {
+			rate = override
+		}
+		policies[name] = rate
+	}
+
+	return RateLimitConfig{
+		Store:    store,
+		Policies: policies,
+	}
+}
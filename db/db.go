@@ -0,0 +1,13 @@
+// Package db embeds the SQL migrations so the binary can apply them without
+// the db directory being present at runtime.
+//
+// Each migration is a pair of files in migrations/ named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, where version is a
+// UTC timestamp (YYYYMMDDhhmmss). Applied migrations must not be edited; add
+// a new one instead.
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,8 @@
+-- down.sql
+DROP TABLE IF EXISTS "document_media";
+DROP TABLE IF EXISTS "document_metadata";
+DROP TABLE IF EXISTS "forgot_passwords";
+DROP TABLE IF EXISTS "invites";
+DROP TABLE IF EXISTS "document_accesses";
+DROP TABLE IF EXISTS "documents";
+DROP TABLE IF EXISTS "users";
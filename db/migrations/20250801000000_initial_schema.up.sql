@@ -0,0 +1,89 @@
+-- up.sql
+-- The schema as gorm AutoMigrate created it before migrations were
+-- versioned. Databases created back then already have it and should record
+-- it as applied with "migrate baseline 20250808040322", then run "migrate up".
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" uuid,
+    "first_name" varchar(255),
+    "last_name" varchar(255),
+    "email" varchar(255),
+    "password" varchar(255),
+    "profile_photo" jsonb,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "documents" (
+    "id" uuid,
+    "title" varchar(255),
+    "content" jsonb,
+    "user_id" uuid,
+    "public_visibility" boolean,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "document_accesses" (
+    "id" uuid,
+    "collaborator_id" uuid,
+    "document_id" uuid,
+    "role" varchar,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_document_accesses_document" FOREIGN KEY ("document_id") REFERENCES "documents"("id"),
+    CONSTRAINT "fk_document_accesses_user" FOREIGN KEY ("collaborator_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "invites" (
+    "id" uuid,
+    "collaborator_id" uuid DEFAULT null,
+    "email" varchar(255) NOT NULL,
+    "document_id" uuid NOT NULL,
+    "role" varchar(20) NOT NULL,
+    "token" varchar(64) NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "inviter_id" uuid DEFAULT null,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_invites_collaborator_id" ON "invites" ("collaborator_id");
+CREATE INDEX IF NOT EXISTS "idx_invites_document_id" ON "invites" ("document_id");
+CREATE INDEX IF NOT EXISTS "idx_invites_email" ON "invites" ("email");
+CREATE INDEX IF NOT EXISTS "idx_invites_inviter_id" ON "invites" ("inviter_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invites_token" ON "invites" ("token");
+
+CREATE TABLE IF NOT EXISTS "forgot_passwords" (
+    "id" uuid,
+    "email" varchar(255),
+    "reset_code" varchar(255),
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "document_metadata" (
+    "id" uuid,
+    "document_id" uuid,
+    "version" int,
+    "metadata" jsonb,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_document_metadata_document" FOREIGN KEY ("document_id") REFERENCES "documents"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "document_media" (
+    "id" uuid,
+    "document_id" uuid NOT NULL,
+    "public_id" text,
+    "secure_url" text,
+    "format" varchar(20),
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
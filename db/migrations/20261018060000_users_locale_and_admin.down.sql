@@ -0,0 +1,3 @@
+-- down.sql
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
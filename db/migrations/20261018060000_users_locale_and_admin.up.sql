@@ -0,0 +1,3 @@
+-- up.sql
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale varchar(10) DEFAULT 'en';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin boolean DEFAULT false;
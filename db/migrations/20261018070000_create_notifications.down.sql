@@ -0,0 +1,3 @@
+-- down.sql
+DROP TABLE IF EXISTS "notification_preferences";
+DROP TABLE IF EXISTS "notifications";
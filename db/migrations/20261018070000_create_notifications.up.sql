@@ -0,0 +1,32 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "actor_id" uuid DEFAULT null,
+    "document_id" uuid DEFAULT null,
+    "event" varchar(50) NOT NULL,
+    "title" varchar(255),
+    "body" text,
+    "link" text,
+    "data" jsonb,
+    "read_at" timestamp,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_notifications_created_at" ON "notifications" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_notifications_document_id" ON "notifications" ("document_id");
+CREATE INDEX IF NOT EXISTS "idx_notifications_read_at" ON "notifications" ("read_at");
+CREATE INDEX IF NOT EXISTS "idx_notifications_user_id" ON "notifications" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "notification_preferences" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "event" varchar(50) NOT NULL,
+    "in_app" boolean NOT NULL DEFAULT true,
+    "email" boolean NOT NULL DEFAULT false,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_event" ON "notification_preferences" ("user_id","event");
@@ -0,0 +1,3 @@
+-- down.sql
+DROP TABLE IF EXISTS "webhook_deliveries";
+DROP TABLE IF EXISTS "webhooks";
@@ -0,0 +1,35 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "document_id" uuid DEFAULT null,
+    "url" text NOT NULL,
+    "secret" varchar(128) NOT NULL,
+    "events" jsonb,
+    "active" boolean NOT NULL DEFAULT true,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhooks_document_id" ON "webhooks" ("document_id");
+CREATE INDEX IF NOT EXISTS "idx_webhooks_user_id" ON "webhooks" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" uuid,
+    "webhook_id" uuid NOT NULL,
+    "event" varchar(50) NOT NULL,
+    "payload" jsonb,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamp,
+    "last_status_code" bigint,
+    "last_error" text,
+    "delivered_at" timestamp,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhook_deliveries_webhook" FOREIGN KEY ("webhook_id") REFERENCES "webhooks"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_next_attempt_at" ON "webhook_deliveries" ("next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_status" ON "webhook_deliveries" ("status");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_webhook_id" ON "webhook_deliveries" ("webhook_id");
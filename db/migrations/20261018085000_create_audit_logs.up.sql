@@ -0,0 +1,21 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" uuid,
+    "actor_id" uuid DEFAULT null,
+    "actor_email" varchar(255),
+    "action" varchar(64) NOT NULL,
+    "target_type" varchar(64),
+    "target_id" varchar(255),
+    "document_id" uuid DEFAULT null,
+    "before" jsonb,
+    "after" jsonb,
+    "ip_address" varchar(64),
+    "user_agent" text,
+    "created_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_created_at" ON "audit_logs" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_document_id" ON "audit_logs" ("document_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_target_id" ON "audit_logs" ("target_id");
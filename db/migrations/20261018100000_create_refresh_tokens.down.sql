@@ -0,0 +1,3 @@
+-- down.sql
+DROP TABLE IF EXISTS "refresh_tokens";
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_revoked_at;
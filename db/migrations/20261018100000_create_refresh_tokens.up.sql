@@ -0,0 +1,19 @@
+-- up.sql
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at timestamp DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+    "id" uuid,
+    "family_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "expires_at" timestamp NOT NULL,
+    "used_at" timestamp DEFAULT null,
+    "revoked_at" timestamp DEFAULT null,
+    "ip_address" varchar(64),
+    "user_agent" text,
+    "created_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_refresh_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_expires_at" ON "refresh_tokens" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
@@ -0,0 +1,3 @@
+-- down.sql
+DROP TABLE IF EXISTS "recovery_codes";
+DROP TABLE IF EXISTS "two_factor_auths";
@@ -0,0 +1,28 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "two_factor_auths" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "secret" varchar(64) NOT NULL,
+    "enabled" boolean DEFAULT false,
+    "confirmed_at" timestamp DEFAULT null,
+    "last_used_step" bigint DEFAULT 0,
+    "failed_attempts" bigint DEFAULT 0,
+    "locked_until" timestamp DEFAULT null,
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_two_factor_auths_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_two_factor_auths_user_id" ON "two_factor_auths" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "recovery_codes" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "code_hash" varchar(64) NOT NULL,
+    "used_at" timestamp DEFAULT null,
+    "created_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_recovery_codes_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_recovery_codes_user_id" ON "recovery_codes" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_recovery_codes_code_hash" ON "recovery_codes" ("code_hash");
@@ -0,0 +1,3 @@
+-- down.sql
+DROP TABLE IF EXISTS "o_id_c_auth_requests";
+DROP TABLE IF EXISTS "user_identities";
@@ -0,0 +1,28 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "user_identities" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "provider" varchar(64) NOT NULL,
+    "subject" varchar(255) NOT NULL,
+    "email" varchar(255),
+    "last_login_at" timestamp DEFAULT null,
+    "created_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_user_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_identity_provider_subject" ON "user_identities" ("provider","subject");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_identity_user_provider" ON "user_identities" ("user_id","provider");
+
+CREATE TABLE IF NOT EXISTS "o_id_c_auth_requests" (
+    "id" uuid,
+    "state_hash" varchar(64) NOT NULL,
+    "provider" varchar(64) NOT NULL,
+    "nonce" varchar(128) NOT NULL,
+    "code_verifier" varchar(128) NOT NULL,
+    "link_user_id" uuid DEFAULT null,
+    "expires_at" timestamp NOT NULL,
+    "created_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_o_id_c_auth_requests_expires_at" ON "o_id_c_auth_requests" ("expires_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_o_id_c_auth_requests_state_hash" ON "o_id_c_auth_requests" ("state_hash");
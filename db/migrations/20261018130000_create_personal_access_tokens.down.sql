@@ -0,0 +1,2 @@
+-- down.sql
+DROP TABLE IF EXISTS "personal_access_tokens";
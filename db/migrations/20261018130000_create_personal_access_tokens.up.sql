@@ -0,0 +1,18 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "personal_access_tokens" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "prefix" varchar(32) NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "scopes" jsonb,
+    "expires_at" timestamp DEFAULT null,
+    "last_used_at" timestamp DEFAULT null,
+    "last_used_ip" varchar(64),
+    "revoked_at" timestamp DEFAULT null,
+    "created_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_personal_access_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_personal_access_tokens_user_id" ON "personal_access_tokens" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_personal_access_tokens_token_hash" ON "personal_access_tokens" ("token_hash");
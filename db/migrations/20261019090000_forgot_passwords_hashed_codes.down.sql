@@ -0,0 +1,11 @@
+-- down.sql
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS reset_code varchar(255);
+DROP INDEX IF EXISTS "idx_forgot_passwords_reset_token_hash";
+DROP INDEX IF EXISTS "idx_forgot_passwords_user_id";
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS used_at;
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS attempts;
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS reset_token_hash;
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS code_hash;
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,14 @@
+-- up.sql
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS user_id uuid;
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS code_hash varchar(64);
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS reset_token_hash varchar(64);
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS attempts bigint NOT NULL DEFAULT 0;
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS expires_at timestamp;
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS verified_at timestamp DEFAULT NULL;
+ALTER TABLE forgot_passwords ADD COLUMN IF NOT EXISTS used_at timestamp DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS "idx_forgot_passwords_user_id" ON "forgot_passwords" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_forgot_passwords_reset_token_hash" ON "forgot_passwords" ("reset_token_hash");
+-- Reset codes used to be stored in plain text with no expiry or owner; they
+-- cannot be migrated, so drop them along with the column.
+DELETE FROM forgot_passwords WHERE user_id IS NULL OR expires_at IS NULL;
+ALTER TABLE forgot_passwords DROP COLUMN IF EXISTS reset_code;
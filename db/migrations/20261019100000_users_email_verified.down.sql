@@ -0,0 +1,2 @@
+-- down.sql
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
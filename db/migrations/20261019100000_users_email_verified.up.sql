@@ -0,0 +1,4 @@
+-- up.sql
+-- Accounts created before email verification existed are treated as verified.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamp DEFAULT NULL;
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
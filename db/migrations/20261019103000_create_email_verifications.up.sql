@@ -0,0 +1,14 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "email_verifications" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "expires_at" timestamp NOT NULL,
+    "used_at" timestamp DEFAULT null,
+    "created_at" timestamp,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_email_verifications_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_email_verifications_created_at" ON "email_verifications" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_email_verifications_user_id" ON "email_verifications" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_email_verifications_token_hash" ON "email_verifications" ("token_hash");
@@ -0,0 +1,3 @@
+-- down.sql
+ALTER TABLE invites DROP COLUMN IF EXISTS account_completed_at;
+ALTER TABLE invites DROP COLUMN IF EXISTS expires_at;
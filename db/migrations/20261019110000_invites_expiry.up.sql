@@ -0,0 +1,6 @@
+-- up.sql
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS expires_at timestamp DEFAULT NULL;
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS account_completed_at timestamp DEFAULT NULL;
+-- Pending invites sent before invites expired get the same 7 days from when
+-- they were sent.
+UPDATE invites SET expires_at = created_at + interval '7 days' WHERE expires_at IS NULL AND status = 'pending';
@@ -0,0 +1,16 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "login_throttles" (
+    "id" uuid,
+    "kind" varchar(16) NOT NULL,
+    "key" varchar(255) NOT NULL,
+    "failures" bigint NOT NULL DEFAULT 0,
+    "last_failure_at" timestamp NOT NULL,
+    "locked_until" timestamp DEFAULT null,
+    "unlock_token_hash" varchar(64),
+    "created_at" timestamp,
+    "updated_at" timestamp,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_login_throttles_last_failure_at" ON "login_throttles" ("last_failure_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_login_throttle_key" ON "login_throttles" ("kind","key");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_login_throttles_unlock_token_hash" ON "login_throttles" ("unlock_token_hash");
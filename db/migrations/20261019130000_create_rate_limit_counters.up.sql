@@ -0,0 +1,8 @@
+-- up.sql
+CREATE TABLE IF NOT EXISTS "rate_limit_counters" (
+    "key" varchar(255),
+    "count" bigint NOT NULL DEFAULT 0,
+    "expires_at" timestamp NOT NULL,
+    PRIMARY KEY ("key")
+);
+CREATE INDEX IF NOT EXISTS "idx_rate_limit_counters_expires_at" ON "rate_limit_counters" ("expires_at");
@@ -0,0 +1,27 @@
+// Package apispec loads the OpenAPI document that /api/v1 is generated
+// from. The router registers one route per operation, by operationId, and
+// validates requests against the operation before its handler runs.
+package apispec
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Path is where the spec is read from, relative to the working directory.
+const Path = "./api/swagger.yaml"
+
+// Load reads the spec at path and checks that it is a valid OpenAPI
+// document.
+func Load(path string) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading API spec: %w", err)
+	}
+	if err := spec.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid API spec: %w", err)
+	}
+	return spec, nil
+}
@@ -0,0 +1,137 @@
+// Package apperror defines the errors handlers report to clients. Handlers
+// record them with c.Error and return; middlewares.ErrorHandler writes them
+// as RFC 7807 problem details (application/problem+json).
+//
+// Any other error, such as a failed query, is a server error: it is logged
+// with its cause and the client only sees a generic detail. The exception is
+// gorm.ErrRecordNotFound, which becomes a 404.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// Code identifies the kind of problem; clients branch on it rather than on
+// the detail text.
+type Code string
+
+const (
+	CodeInvalidRequest  Code = "invalid_request"
+	CodeUnauthenticated Code = "unauthenticated"
+	CodeForbidden       Code = "forbidden"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodeGone            Code = "gone"
+	CodeRateLimited     Code = "rate_limited"
+	CodeInternal        Code = "internal"
+	CodeBadGateway      Code = "bad_gateway"
+)
+
+// Error is a problem to report to the client.
+type Error struct {
+	Status int
+	Code   Code
+	// Detail is shown to the client.
+	Detail string
+	// Extensions are added to the problem as extra members.
+	Extensions map[string]any
+	// Err is the underlying cause; it is logged, never shown.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// With adds an extension member to the problem, such as the values a field
+// accepts.
+func (e *Error) With(key string, value any) *Error {
+	if e.Extensions == nil {
+		e.Extensions = map[string]any{}
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+func Unauthorized(detail string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, detail)
+}
+
+func Forbidden(detail string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, detail)
+}
+
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+func Conflict(detail string) *Error {
+	return New(http.StatusConflict, CodeConflict, detail)
+}
+
+func Gone(detail string) *Error {
+	return New(http.StatusGone, CodeGone, detail)
+}
+
+func TooManyRequests(detail string) *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, detail)
+}
+
+// Internal is a server error caused by err.
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "Internal server error", Err: err}
+}
+
+// BadGateway is a failure of a service the request depends on, caused by
+// err.
+func BadGateway(detail string, err error) *Error {
+	return &Error{Status: http.StatusBadGateway, Code: CodeBadGateway, Detail: detail, Err: err}
+}
+
+// From returns err as an *Error: as is when it already is one, a 404 for a
+// missing record and a server error otherwise.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Detail: "Not found", Err: err}
+	}
+	return Internal(err)
+}
+
+// Problem returns the RFC 7807 body of e. The type is about:blank, so the
+// title is the status text and Code tells problems apart.
+func (e *Error) Problem() map[string]any {
+	problem := make(map[string]any, len(e.Extensions)+5)
+	for k, v := range e.Extensions {
+		problem[k] = v
+	}
+	problem["type"] = "about:blank"
+	problem["title"] = http.StatusText(e.Status)
+	problem["status"] = e.Status
+	problem["code"] = e.Code
+	if e.Detail != "" {
+		problem["detail"] = e.Detail
+	}
+	return problem
+}
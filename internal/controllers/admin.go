@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminController struct {
+	AuditLogRepository *repositories.AuditLogRepository
+}
+
+func NewAdminController(auditLogRepository *repositories.AuditLogRepository) *AdminController {
+	return &AdminController{
+		AuditLogRepository: auditLogRepository,
+	}
+}
+
+func (a *AdminController) PreviewEmailTemplate(c *gin.Context) {
+	templateName := c.Param("name")
+	locale := c.DefaultQuery("locale", c.GetHeader("Accept-Language"))
+	format := c.DefaultQuery("format", "html")
+
+	data, ok := handlers.TemplatePreviewData(templateName)
+	if !ok {
+		c.Error(apperror.NotFound("Template not found"))
+		return
+	}
+
+	mail, err := handlers.ParseTemplate(templateName, locale, data)
+	if err != nil {
+		c.Error(fmt.Errorf("error rendering preview: %w", err))
+		return
+	}
+
+	c.Header("Content-Language", mail.Locale)
+	c.Header("X-Email-Subject", mail.Subject)
+
+	switch format {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(mail.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(mail.Text))
+	case "json":
+		c.JSON(http.StatusOK, gin.H{
+			"locale":  mail.Locale,
+			"subject": mail.Subject,
+			"html":    mail.HTML,
+			"text":    mail.Text,
+		})
+	default:
+		c.Error(apperror.BadRequest("Format must be html, text or json"))
+	}
+}
+
+func (a *AdminController) ListEmailLocales(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"locales": handlers.SupportedLocales()})
+}
+
+// ExportAuditLog streams audit entries created in [from, to) as CSV or
+// newline-delimited JSON. from defaults to 30 days ago and to to now.
+func (a *AdminController) ExportAuditLog(c *gin.Context) {
+	now := time.Now().UTC()
+	from, to := now.AddDate(0, 0, -30), now
+
+	var err error
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.Error(apperror.BadRequest("Invalid from; use an RFC 3339 timestamp"))
+			return
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.Error(apperror.BadRequest("Invalid to; use an RFC 3339 timestamp"))
+			return
+		}
+	}
+	if !from.Before(to) {
+		c.Error(apperror.BadRequest("The from time must be before the to time"))
+		return
+	}
+	from, to = from.UTC(), to.UTC()
+
+	filename := "audit-log-" + from.Format("20060102") + "-" + to.Format("20060102")
+
+	switch format := c.DefaultQuery("format", "csv"); format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{
+			"id", "createdAt", "action", "actorId", "actorEmail", "targetType", "targetId",
+			"documentId", "before", "after", "ipAddress", "userAgent",
+		})
+		err = a.AuditLogRepository.StreamRange(from, to, func(batch []model.AuditLog) error {
+			for _, entry := range batch {
+				w.Write([]string{
+					entry.ID.String(),
+					entry.CreatedAt.Format(time.RFC3339Nano),
+					string(entry.Action),
+					uuidString(entry.ActorID),
+					entry.ActorEmail,
+					entry.TargetType,
+					entry.TargetID,
+					uuidString(entry.DocumentID),
+					string(entry.Before),
+					string(entry.After),
+					entry.IPAddress,
+					entry.UserAgent,
+				})
+			}
+			w.Flush()
+			return w.Error()
+		})
+	case "json":
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.jsonl"`)
+		c.Status(http.StatusOK)
+
+		enc := json.NewEncoder(c.Writer)
+		err = a.AuditLogRepository.StreamRange(from, to, func(batch []model.AuditLog) error {
+			for _, entry := range batch {
+				if err := enc.Encode(entry); err != nil {
+					return err
+				}
+			}
+			c.Writer.Flush()
+			return nil
+		})
+	default:
+		c.Error(apperror.BadRequest("Format must be csv or json"))
+		return
+	}
+
+	// Headers are already sent, so a failure can only be logged; the client
+	// sees a truncated file.
+	if err != nil {
+		logging.From(c).Error("Error exporting audit log", "error", err)
+	}
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
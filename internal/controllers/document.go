@@ -1,16 +1,21 @@
 package controllers
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/apperror"
 	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/logging"
 	"realTimeEditor/internal/model"
 	"realTimeEditor/internal/repositories"
-	"realTimeEditor/pkg/constants"
-	"realTimeEditor/pkg/jwt"
 	"realTimeEditor/pkg/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +23,13 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	// InviteTTL is how long an invite link can be accepted; resending an
+	// invite starts a new period.
+	InviteTTL      = 7 * 24 * time.Hour
+	maxBulkInvites = 50
+)
+
 type DocumentController struct {
 	DocumentRepository         *repositories.DocumentRepository
 	DocumentAccessRepository   *repositories.DocumentAccessRepository
@@ -25,6 +37,13 @@ type DocumentController struct {
 	UserRepository             *repositories.UserRepository
 	DocumentMetadataRepository *repositories.DocumentMetaDataRepository
 	DocumentMediaRepository    *repositories.DocumentMediaRepository
+	Notifier                   *handlers.Notifier
+	WebhookPublisher           *handlers.WebhookPublisher
+	Auditor                    *handlers.Auditor
+	Sessions                   *handlers.SessionManager
+	EmailVerifier              *handlers.EmailVerifier
+	Mailer                     *handlers.Mailer
+	Config                     *config.Config
 }
 
 func NewDocumentController(
@@ -34,6 +53,13 @@ func NewDocumentController(
 	userRepository *repositories.UserRepository,
 	documentMetadataRepository *repositories.DocumentMetaDataRepository,
 	documentMediaRepository *repositories.DocumentMediaRepository,
+	notifier *handlers.Notifier,
+	webhookPublisher *handlers.WebhookPublisher,
+	auditor *handlers.Auditor,
+	sessions *handlers.SessionManager,
+	emailVerifier *handlers.EmailVerifier,
+	mailer *handlers.Mailer,
+	cfg *config.Config,
 ) *DocumentController {
 	return &DocumentController{
 		DocumentRepository:         documentRepository,
@@ -42,34 +68,132 @@ func NewDocumentController(
 		UserRepository:             userRepository,
 		DocumentMetadataRepository: documentMetadataRepository,
 		DocumentMediaRepository:    documentMediaRepository,
+		Notifier:                   notifier,
+		WebhookPublisher:           webhookPublisher,
+		Auditor:                    auditor,
+		Sessions:                   sessions,
+		EmailVerifier:              emailVerifier,
+		Mailer:                     mailer,
+		Config:                     cfg,
+	}
+}
+
+// publish loads the document and queues event for its webhook subscribers.
+func (d *DocumentController) publish(ctx context.Context, event model.WebhookEvent, documentId uuid.UUID, actorId *uuid.UUID, data any) {
+	var document model.Document
+	if err := d.DocumentRepository.GetOne(documentId, &document); err != nil {
+		logging.FromContext(ctx).Error("Error loading document for webhook", "event", event, "error", err)
+		return
+	}
+	d.WebhookPublisher.Publish(ctx, event, &document, actorId, data)
+}
+
+// notify is best effort: a failed notification is logged and never fails the
+// request that triggered it.
+func (d *DocumentController) notify(ctx context.Context, recipientId uuid.UUID, notification model.Notification, sendEmail func() error) {
+	var recipient model.User
+	if err := d.UserRepository.GetById(&recipient, recipientId); err != nil {
+		logging.FromContext(ctx).Error("Error fetching notification recipient", "error", err)
+		return
+	}
+	if err := d.Notifier.Notify(ctx, &recipient, &notification, sendEmail); err != nil {
+		logging.FromContext(ctx).Error("Error sending notification", "error", err)
+	}
+}
+
+func (d *DocumentController) documentTitle(documentId uuid.UUID) string {
+	var document model.Document
+	if err := d.DocumentRepository.GetOne(documentId, &document); err != nil || document.Title == "" {
+		return "a document"
+	}
+	return fmt.Sprintf("%q", document.Title)
+}
+
+func documentLink(documentId uuid.UUID) string {
+	return fmt.Sprintf("/documents/%s", documentId.String())
+}
+
+// pathOrQuery returns the path parameter, or the query parameter on legacy
+// routes that take the id in the query string.
+func pathOrQuery(c *gin.Context, param, query string) string {
+	if value := c.Param(param); value != "" {
+		return value
 	}
+	return c.Query(query)
+}
+
+// inDocument reports whether documentId is the document named by the id path
+// parameter. Legacy routes have no such parameter and match any document.
+func inDocument(c *gin.Context, documentId uuid.UUID) bool {
+	param := c.Param("id")
+	if param == "" {
+		return true
+	}
+	parsed, err := uuid.Parse(param)
+	return err == nil && parsed == documentId
+}
+
+// deliverInvite sends invite to its recipient, who is nil if the email has no
+// account yet.
+func (d *DocumentController) deliverInvite(ctx context.Context, inviter model.User, invite *model.Invite, document *model.Document, recipient *model.User) error {
+	recipientLocale := inviter.Locale
+	if recipient != nil {
+		recipientLocale = recipient.Locale
+	}
+
+	inviteUrl := fmt.Sprintf("%s/invite/%s", d.Config.Frontend.RootURL, invite.Token)
+	sendInvite := func() error {
+		return d.Mailer.Send(ctx, *invite.Email, recipientLocale, "invite", handlers.Invite{
+			InviteLink:    inviteUrl,
+			DocumentTitle: document.Title,
+			Role:          invite.Role,
+			FullName:      *invite.Email,
+			Year:          time.Now().Year(),
+		})
+	}
+
+	// Verified users get the invite through their notification preferences.
+	// Anyone else can only be reached by email, including an unverified
+	// account that may not belong to whoever owns the address.
+	if recipient == nil || !recipient.EmailVerified() {
+		return sendInvite()
+	}
+
+	notification := model.Notification{
+		ActorID:    &inviter.ID,
+		DocumentID: &document.ID,
+		Event:      model.NotificationInviteReceived,
+		Title:      "You have been invited to a document",
+		Body:       fmt.Sprintf("%s invited you to %s %q.", inviter.FullName(), invite.Role, document.Title),
+		Link:       fmt.Sprintf("/invite/%s", invite.Token),
+	}
+	return d.Notifier.Notify(ctx, recipient, &notification, sendInvite)
 }
 
 func (d *DocumentController) Create(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	var newDocument model.Document
 	if err := c.ShouldBindJSON(&newDocument); err != nil {
-		log.Printf("Error binding JSON: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
 	newDocument.UserID = userDetails.ID
 
 	if err := d.DocumentRepository.Create(&newDocument); err != nil {
-		log.Printf("Error creating document: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error creating document: %w", err))
 		return
 	}
 
@@ -80,8 +204,7 @@ func (d *DocumentController) Create(c *gin.Context) {
 	}
 
 	if err := d.DocumentAccessRepository.Create(&newDocumentAccess); err != nil {
-		log.Printf("Error creating document: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error creating document: %w", err))
 		return
 	}
 
@@ -90,87 +213,84 @@ func (d *DocumentController) Create(c *gin.Context) {
 		Version:    1,
 	}
 	if err := d.DocumentMetadataRepository.Create(&documentMetaData); err != nil {
-		log.Printf("Error creating document: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error creating document: %w", err))
 		return
 	}
 
+	d.WebhookPublisher.Publish(c.Request.Context(), model.WebhookDocumentCreated, &newDocument, &userDetails.ID, gin.H{"title": newDocument.Title})
+
 	c.JSON(http.StatusCreated, gin.H{"message": "Document created successfully"})
 }
 
 func (d *DocumentController) GetUserCreatedDocuments(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documents, err := d.DocumentRepository.GetUserDocuments(userDetails.ID)
 	if err != nil {
-		log.Printf("Error fetching document: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error fetching document: %w", err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Documents fetched", "documents": documents})
+	c.JSON(http.StatusOK, gin.H{"message": "Documents fetched", "documents": documents})
 }
 
 func (d *DocumentController) GetSingleDocument(c *gin.Context) {
 	user, exists := c.Get("user")
-	documentId := c.Query("documentId")
+	documentId := pathOrQuery(c, "id", "documentId")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentUUID, err := uuid.Parse(documentId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var document model.Document
 	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "document not found"})
+			c.Error(apperror.NotFound("Document not found"))
 			return
 		}
-		log.Printf("Error fetching document: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error fetching document: %w", err))
 		return
 	}
 
 	if !document.PublicVisibility {
 		access, err := d.DocumentAccessRepository.HasReadAccess(userDetails.ID, documentUUID)
 		if err != nil {
-			log.Printf("Error: %s", err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error fetching document"})
+			c.Error(err)
 			return
 		}
 
 		if !access {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "you do not have access to this document"})
+			c.Error(apperror.Forbidden("You do not have access to this document"))
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{"message": "Document fetched", "document": document})
+		c.JSON(http.StatusOK, gin.H{"message": "Document fetched", "document": document})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "Document fetched", "document": document})
+	c.JSON(http.StatusOK, gin.H{"message": "Document fetched", "document": document})
 }
 
 func (d *DocumentController) ToggleVisibility(c *gin.Context) {
@@ -178,83 +298,91 @@ func (d *DocumentController) ToggleVisibility(c *gin.Context) {
 	documentId := c.Param("id")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentUUID, err := uuid.Parse(documentId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var document model.Document
 	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			c.Error(apperror.NotFound("Document not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
 	if userDetails.ID != document.UserID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission for this action"})
+		c.Error(apperror.Forbidden("You don't have permission for this action"))
 		return
 	}
 
 	if err := d.DocumentRepository.ToggleVisibility(documentUUID); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"error": "Visibility changed"})
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditVisibilityToggled,
+		TargetType: "document",
+		TargetID:   documentUUID.String(),
+		DocumentID: &documentUUID,
+	}, gin.H{"isPublic": document.PublicVisibility}, gin.H{"isPublic": !document.PublicVisibility})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Visibility changed"})
 }
 
 func (d *DocumentController) RevokeAccess(c *gin.Context) {
 	user, exists := c.Get("user")
-	documentAccessId := c.Param("documentAccessId")
+	documentAccessId := c.Param("accessId")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentAccessUUID, err := uuid.Parse(documentAccessId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var documentAccess model.DocumentAccess
 	if err := d.DocumentAccessRepository.GetOne(documentAccessUUID, &documentAccess); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "document access not found"})
+			c.Error(apperror.NotFound("Document access not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
+		return
+	}
+	if !inDocument(c, documentAccess.DocumentId) {
+		c.Error(apperror.NotFound("Document access not found"))
 		return
 	}
 
 	if documentAccess.Role == model.Creator {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot revoke access to a document created by you"})
+		c.Error(apperror.Forbidden("You cannot revoke access to a document created by you"))
 		return
 	}
 
@@ -262,24 +390,44 @@ func (d *DocumentController) RevokeAccess(c *gin.Context) {
 	err = d.DocumentAccessRepository.GetOneWithDocIdAndCollaboratorId(documentAccess.DocumentId, userDetails.ID, &requesterAccess)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "document access not found"})
+			c.Error(apperror.Forbidden("You do not have access to this document"))
 			return
 		}
-		log.Printf("Error checking requester access: %s", err.Error())
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		c.Error(fmt.Errorf("error checking requester access: %w", err))
 		return
 	}
 
 	if requesterAccess.Role != model.Creator {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can revoke access"})
+		c.Error(apperror.Forbidden("Only the creator can revoke access"))
 		return
 	}
 
 	if err := d.DocumentAccessRepository.Delete(&documentAccess, documentAccessUUID); err != nil {
-		log.Printf("Error deleting document access: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke access"})
-		return
-	}
+		c.Error(fmt.Errorf("error deleting document access: %w", err))
+		return
+	}
+
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditAccessRevoked,
+		TargetType: "user",
+		TargetID:   documentAccess.CollaboratorId.String(),
+		DocumentID: &documentAccess.DocumentId,
+	}, gin.H{"documentAccessId": documentAccess.ID, "role": documentAccess.Role}, nil)
+
+	d.notify(c.Request.Context(), documentAccess.CollaboratorId, model.Notification{
+		ActorID:    &userDetails.ID,
+		DocumentID: &documentAccess.DocumentId,
+		Event:      model.NotificationAccessRevoked,
+		Title:      "Your access was revoked",
+		Body:       fmt.Sprintf("%s removed your access to %s.", userDetails.FullName(), d.documentTitle(documentAccess.DocumentId)),
+	}, nil)
+
+	d.publish(c.Request.Context(), model.WebhookAccessRevoked, documentAccess.DocumentId, &userDetails.ID, gin.H{
+		"collaboratorId": documentAccess.CollaboratorId,
+		"role":           documentAccess.Role,
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "access revoked successfully"})
 }
@@ -289,84 +437,93 @@ func (d *DocumentController) DeleteDocument(c *gin.Context) {
 	documentId := c.Param("id")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentUUID, err := uuid.Parse(documentId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var document model.Document
 	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "document not found"})
+			c.Error(apperror.NotFound("Document not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
 	if userDetails.ID != document.UserID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "you do not have access to carry out delete action"})
+		c.Error(apperror.Forbidden("You do not have access to carry out delete action"))
 		return
 	}
 
 	if err := d.DocumentRepository.Delete(documentUUID); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting document"})
+		c.Error(err)
 		return
 	}
 
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditDocumentDeleted,
+		TargetType: "document",
+		TargetID:   documentUUID.String(),
+		DocumentID: &documentUUID,
+	}, gin.H{"title": document.Title, "ownerId": document.UserID, "isPublic": document.PublicVisibility}, nil)
+
+	d.WebhookPublisher.Publish(c.Request.Context(), model.WebhookDocumentDeleted, &document, &userDetails.ID, gin.H{"title": document.Title})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Document deleted"})
 }
 
 func (d *DocumentController) ModifyAccess(c *gin.Context) {
 	user, exists := c.Get("user")
-	documentAccessId := c.Param("documentAccessId")
-	newRole := c.Param("newRole")
+	documentAccessId := c.Param("accessId")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentAccessUUID, err := uuid.Parse(documentAccessId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var documentAccess model.DocumentAccess
 	if err := d.DocumentAccessRepository.GetOne(documentAccessUUID, &documentAccess); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "document access not found"})
+			c.Error(apperror.NotFound("Document access not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusNotFound, gin.H{"error": "document access not found"})
+		c.Error(err)
+		return
+	}
+	if !inDocument(c, documentAccess.DocumentId) {
+		c.Error(apperror.NotFound("Document access not found"))
 		return
 	}
 
 	if documentAccess.Role == model.Creator && documentAccess.CollaboratorId == userDetails.ID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot modify your role as document creator"})
+		c.Error(apperror.BadRequest("You cannot modify your role as document creator"))
 		return
 	}
 
@@ -374,21 +531,31 @@ func (d *DocumentController) ModifyAccess(c *gin.Context) {
 	err = d.DocumentAccessRepository.GetOneWithDocIdAndCollaboratorId(documentAccess.DocumentId, userDetails.ID, &requesterAccess)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "document access not found"})
+			c.Error(apperror.Forbidden("You do not have access to this document"))
 			return
 		}
-		log.Printf("Error checking requester access: %s", err.Error())
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		c.Error(fmt.Errorf("error checking requester access: %w", err))
 		return
 	}
 
 	if requesterAccess.Role != model.Creator {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can modify role"})
+		c.Error(apperror.Forbidden("Only the creator can modify role"))
 		return
 	}
 
+	// Legacy routes take the role in the path.
+	newRole := c.Param("role")
+	if newRole == "" {
+		var payload ModifyAccessPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.Error(apperror.BadRequest("Invalid request payload"))
+			return
+		}
+		newRole = string(payload.Role)
+	}
+
 	if newRole == string(model.Creator) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "a document can only have one creator"})
+		c.Error(apperror.Forbidden("A document can only have one creator"))
 		return
 	}
 
@@ -398,18 +565,36 @@ func (d *DocumentController) ModifyAccess(c *gin.Context) {
 	}
 
 	if !validRoles[newRole] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		c.Error(apperror.BadRequest("Invalid role"))
 		return
 	}
 
+	previousRole := documentAccess.Role
 	documentAccess.Role = model.Role(newRole)
 
 	if err := d.DocumentAccessRepository.Update(&documentAccess, documentAccessUUID); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditAccessModified,
+		TargetType: "user",
+		TargetID:   documentAccess.CollaboratorId.String(),
+		DocumentID: &documentAccess.DocumentId,
+	}, gin.H{"role": previousRole}, gin.H{"role": documentAccess.Role})
+
+	d.notify(c.Request.Context(), documentAccess.CollaboratorId, model.Notification{
+		ActorID:    &userDetails.ID,
+		DocumentID: &documentAccess.DocumentId,
+		Event:      model.NotificationAccessModified,
+		Title:      "Your access was changed",
+		Body:       fmt.Sprintf("%s changed your role on %s to %s.", userDetails.FullName(), d.documentTitle(documentAccess.DocumentId), newRole),
+		Link:       documentLink(documentAccess.DocumentId),
+	}, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Role updated"})
 }
 
@@ -417,13 +602,13 @@ func (d *DocumentController) FetchAllDocuments(c *gin.Context) {
 	user, exists := c.Get("user")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
@@ -433,8 +618,7 @@ func (d *DocumentController) FetchAllDocuments(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "You do not have documents yet"})
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Documents fetched", "documents": documents})
@@ -445,42 +629,35 @@ func (d *DocumentController) FetchCollaborators(c *gin.Context) {
 	documentId := c.Param("id")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentUUID, err := uuid.Parse(documentId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var docAccess model.DocumentAccess
 	if err := d.DocumentAccessRepository.GetOneWithDocIdAndCollaboratorId(documentUUID, userDetails.ID, &docAccess); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "You don't have necessary permission to view this"})
+			c.Error(apperror.Forbidden("You don't have necessary permission to view this"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
 	collaborators, err := d.DocumentAccessRepository.GetDocumentAccesses(documentUUID)
-	if err := d.DocumentAccessRepository.GetOneWithDocIdAndCollaboratorId(documentUUID, userDetails.ID, &docAccess); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "document access not found"})
-			return
-		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -489,94 +666,102 @@ func (d *DocumentController) FetchCollaborators(c *gin.Context) {
 
 func (d *DocumentController) TransferOwnership(c *gin.Context) {
 	user, exists := c.Get("user")
-	documentId := c.Param("documentId")
-	recipientId := c.Param("recipientId")
+	documentId := c.Param("id")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
+	// Legacy routes take the recipient in the path.
+	recipientId := c.Param("recipientId")
+	if recipientId == "" {
+		var payload TransferOwnershipPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.Error(apperror.BadRequest("Invalid request payload"))
+			return
+		}
+		recipientId = payload.UserId
+	}
+
 	documentUUID, err := uuid.Parse(documentId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	recipientUUID, err := uuid.Parse(recipientId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	if userDetails.ID == recipientUUID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "you already own this document"})
+		c.Error(apperror.BadRequest("You already own this document"))
 		return
 	}
 
 	var document model.Document
 	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			c.Error(apperror.NotFound("Document not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
 	if userDetails.ID != document.UserID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "you do not have access to carry out this action"})
+		c.Error(apperror.Forbidden("You do not have access to carry out this action"))
 		return
 	}
 
 	var creatorAccess model.DocumentAccess
 	if err := d.DocumentAccessRepository.GetOneWithDocIdAndCollaboratorId(documentUUID, userDetails.ID, &creatorAccess); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "document access for creator not found"})
+			c.Error(apperror.NotFound("Document access for creator not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
 	var recipientAccess model.DocumentAccess
 	if err := d.DocumentAccessRepository.GetOneWithDocIdAndCollaboratorId(documentUUID, recipientUUID, &recipientAccess); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "document access for collaborator not found"})
+			c.Error(apperror.NotFound("Document access for collaborator not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
+	// Webhooks fire against the pre-transfer document so the previous owner's
+	// unscoped subscriptions see the hand-off.
+	previousOwnerDoc := document
 	document.UserID = recipientUUID
 	creatorAccess.Role = model.Edit
 	recipientAccess.Role = model.Creator
 
 	err = d.DocumentRepository.ExecuteInTransaction(func(tx *gorm.DB) error {
 		if err := d.DocumentRepository.UpdateWithTransaction(tx, &document, documentUUID); err != nil {
-			log.Printf("Error: %s", err)
+			logging.From(c).Error("Error updating document owner", "error", err)
 			return fmt.Errorf("failed to update document details: %w", err)
 		}
 
 		if err := d.DocumentAccessRepository.UpdateWithTransaction(tx, &creatorAccess, creatorAccess.ID); err != nil {
-			log.Printf("Error: %s", err)
+			logging.From(c).Error("Error demoting previous owner", "error", err)
 			return fmt.Errorf("failed to update document details: %w", err)
 		}
 
 		if err := d.DocumentAccessRepository.UpdateWithTransaction(tx, &recipientAccess, recipientAccess.ID); err != nil {
-			log.Printf("Error: %s", err)
+			logging.From(c).Error("Error promoting new owner", "error", err)
 			return fmt.Errorf("failed to update document details: %w", err)
 		}
 
@@ -584,167 +769,541 @@ func (d *DocumentController) TransferOwnership(c *gin.Context) {
 	}, 3)
 
 	if err != nil {
-		log.Printf("Transaction failed: %s", err)
-		if originalErr := errors.Unwrap(err); originalErr != nil {
-			err = originalErr
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error transferring ownership"})
-		return
-	}
+		c.Error(fmt.Errorf("error transferring ownership: %w", err))
+		return
+	}
+
+	d.notify(c.Request.Context(), recipientUUID, model.Notification{
+		ActorID:    &userDetails.ID,
+		DocumentID: &documentUUID,
+		Event:      model.NotificationOwnershipTransferred,
+		Title:      "You are now a document owner",
+		Body:       fmt.Sprintf("%s transferred ownership of %q to you.", userDetails.FullName(), document.Title),
+		Link:       documentLink(documentUUID),
+	}, nil)
+
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditOwnershipTransferred,
+		TargetType: "user",
+		TargetID:   recipientUUID.String(),
+		DocumentID: &documentUUID,
+	}, gin.H{"ownerId": previousOwnerDoc.UserID}, gin.H{"ownerId": recipientUUID})
+
+	d.WebhookPublisher.Publish(c.Request.Context(), model.WebhookOwnershipTransferred, &previousOwnerDoc, &userDetails.ID, gin.H{
+		"previousOwnerId": previousOwnerDoc.UserID,
+		"newOwnerId":      recipientUUID,
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Document ownership transferred"})
 }
 
+// InviteCollaborator invites one email, or up to maxBulkInvites emails at
+// once, to the document. Each email gets its own result.
 func (d *DocumentController) InviteCollaborator(c *gin.Context) {
-	var payload struct {
-		DocumentId string     `json:"documentId"`
-		Email      string     `json:"email"`
-		Role       model.Role `json:"role"`
-	}
-
+	var payload InviteCollaboratorPayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
+	if documentId := c.Param("id"); documentId != "" {
+		payload.DocumentId = documentId
+	}
 
 	user, exists := c.Get("user")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	emails := payload.Emails
+	if payload.Email != "" {
+		emails = append([]string{payload.Email}, emails...)
+	}
+	if len(emails) == 0 || len(emails) > maxBulkInvites {
+		c.Error(apperror.BadRequest(fmt.Sprintf("Provide between 1 and %d emails", maxBulkInvites)))
+		return
+	}
+
+	var invalid []string
+	seen := make(map[string]bool, len(emails))
+	unique := emails[:0]
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if !emailRegex.MatchString(email) {
+			invalid = append(invalid, email)
+			continue
+		}
+		if seen[strings.ToLower(email)] {
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+		unique = append(unique, email)
+	}
+	if len(invalid) > 0 {
+		c.Error(apperror.BadRequest("Invalid email format").With("invalidEmails", invalid))
+		return
+	}
+
+	if payload.Role != model.Edit && payload.Role != model.Read {
+		c.Error(apperror.BadRequest("Role must be edit or read"))
 		return
 	}
 
 	documentUUID, err := uuid.Parse(payload.DocumentId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var document model.Document
-	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
+	if err := d.DocumentRepository.WithContext(c.Request.Context()).GetOne(documentUUID, &document); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"message": "Document not found"})
+			c.Error(apperror.NotFound("Document not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
-	token, err := utils.NewCodeGenerator().GenerateSecureToken(16)
-	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating invite token"})
+	if document.UserID != userDetails.ID {
+		c.Error(apperror.Forbidden("You don't have permission for this action"))
 		return
 	}
 
+	results := make([]InviteResult, 0, len(unique))
+	sent := 0
+	for _, email := range unique {
+		result := d.inviteEmail(c, userDetails, &document, email, payload.Role)
+		if result.Status == InviteResultSent {
+			sent++
+		}
+		results = append(results, result)
+	}
+
+	// A single invite keeps its original response shape.
+	if len(results) == 1 {
+		switch results[0].Status {
+		case InviteResultSent:
+			c.JSON(http.StatusOK, gin.H{"message": "Invite sent successfully", "results": results})
+		case InviteResultFailed:
+			c.Error(apperror.Internal(errors.New("invite failed")).With("results", results))
+		default:
+			c.JSON(http.StatusOK, gin.H{"message": "Already a collaborator", "results": results})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%d of %d invites sent", sent, len(results)),
+		"results": results,
+	})
+}
+
+// inviteEmail replaces any pending invite of email to the document with a new
+// one and delivers it.
+func (d *DocumentController) inviteEmail(c *gin.Context, inviter model.User, document *model.Document, email string, role model.Role) InviteResult {
+	ctx := c.Request.Context()
+	result := InviteResult{Email: email}
+
 	var findUser model.User
-	var collaboratorId *uuid.UUID
-	if err := d.UserRepository.GetByEmail(&findUser, payload.Email); err != nil {
+	var recipient *model.User
+	if err := d.UserRepository.WithContext(ctx).GetByEmail(&findUser, email); err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("Error retrieving user details: %s", err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
+			logging.From(c).Error("Error retrieving user details", "error", err)
+			result.Status = InviteResultFailed
+			return result
 		}
 	} else {
-		collaboratorId = &findUser.ID
+		recipient = &findUser
+		hasAccess, err := d.DocumentAccessRepository.WithContext(ctx).HasReadAccess(findUser.ID, document.ID)
+		if err != nil {
+			logging.From(c).Error("Error checking invitee access", "error", err)
+			result.Status = InviteResultFailed
+			return result
+		}
+		if hasAccess {
+			result.Status = InviteResultCollaborator
+			return result
+		}
 	}
 
-	var oldInvite model.Invite
-	if err := d.InviteRepository.GetOneByEmailAndDocId(&oldInvite, payload.Email, documentUUID); err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("Error: %s", err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
+	token, err := utils.NewCodeGenerator().GenerateSecureToken(16)
+	if err != nil {
+		logging.From(c).Error("Error generating invite token", "error", err)
+		result.Status = InviteResultFailed
+		return result
+	}
+
+	if err := d.InviteRepository.WithContext(ctx).DeletePending(email, document.ID); err != nil {
+		logging.From(c).Error("Error deleting pending invites", "error", err)
+		result.Status = InviteResultFailed
+		return result
+	}
+
+	expiresAt := time.Now().UTC().Add(InviteTTL)
+	newInvite := model.Invite{
+		Email:      &email,
+		DocumentId: document.ID,
+		InviterId:  inviter.ID,
+		Role:       role,
+		Status:     model.InviteStatus(model.Pending),
+		Token:      token,
+		ExpiresAt:  &expiresAt,
+	}
+	if recipient != nil {
+		newInvite.CollaboratorId = &recipient.ID
+	}
+
+	if err := d.InviteRepository.WithContext(ctx).Create(&newInvite); err != nil {
+		logging.From(c).Error("Error creating invite", "error", err)
+		result.Status = InviteResultFailed
+		return result
+	}
+	result.InviteID = &newInvite.ID
+
+	if err := d.deliverInvite(ctx, inviter, &newInvite, document, recipient); err != nil {
+		logging.From(c).Error("Error delivering invite", "error", err)
+		result.Status = InviteResultFailed
+		return result
+	}
+
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &inviter.ID,
+		ActorEmail: inviter.Email,
+		Action:     model.AuditInviteCreated,
+		TargetType: "invite",
+		TargetID:   newInvite.ID.String(),
+		DocumentID: &document.ID,
+	}, nil, gin.H{"email": email, "role": role})
+
+	result.Status = InviteResultSent
+	result.ExpiresAt = &expiresAt
+	return result
+}
+
+// ownedInvite loads the invite named by the inviteId path parameter and its
+// document, responding with an error unless the invite belongs to the
+// document in the path and userDetails owns it.
+func (d *DocumentController) ownedInvite(c *gin.Context, userDetails model.User) (*model.Invite, *model.Document, bool) {
+	inviteId, err := uuid.Parse(c.Param("inviteId"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid id"))
+		return nil, nil, false
+	}
+
+	var invite model.Invite
+	if err := d.InviteRepository.GetOne(inviteId, &invite); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Invitation not found"))
+			return nil, nil, false
 		}
+		c.Error(err)
+		return nil, nil, false
+	}
+
+	if !inDocument(c, invite.DocumentId) {
+		c.Error(apperror.NotFound("Invitation not found"))
+		return nil, nil, false
+	}
+
+	var document model.Document
+	if err := d.DocumentRepository.GetOne(invite.DocumentId, &document); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Document not found"))
+			return nil, nil, false
+		}
+		c.Error(err)
+		return nil, nil, false
+	}
+
+	if document.UserID != userDetails.ID {
+		c.Error(apperror.Forbidden("You don't have permission for this action"))
+		return nil, nil, false
+	}
+	return &invite, &document, true
+}
+
+// ResendInvite emails a pending or expired invite again with a new link and
+// a new expiry; the previous link stops working.
+func (d *DocumentController) ResendInvite(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	invite, document, ok := d.ownedInvite(c, userDetails)
+	if !ok {
+		return
+	}
+
+	if invite.Status != model.InviteStatus(model.Pending) && invite.Status != model.InviteStatus(model.Expired) {
+		c.Error(apperror.Conflict(fmt.Sprintf("Invite is %s and cannot be resent", invite.Status)))
+		return
+	}
+
+	token, err := utils.NewCodeGenerator().GenerateSecureToken(16)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(InviteTTL)
+	if err := d.InviteRepository.Reissue(invite.ID, token, expiresAt); err != nil {
+		c.Error(err)
+		return
+	}
+	previousStatus := invite.Status
+	invite.Token = token
+	invite.ExpiresAt = &expiresAt
+	invite.Status = model.InviteStatus(model.Pending)
+
+	var recipient *model.User
+	var findUser model.User
+	if err := d.UserRepository.GetByEmail(&findUser, *invite.Email); err == nil {
+		recipient = &findUser
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Error(fmt.Errorf("error retrieving user details: %w", err))
+		return
+	}
+
+	if err := d.deliverInvite(c.Request.Context(), userDetails, invite, document, recipient); err != nil {
+		c.Error(err)
+		return
+	}
+
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditInviteResent,
+		TargetType: "invite",
+		TargetID:   invite.ID.String(),
+		DocumentID: &invite.DocumentId,
+	}, gin.H{"status": previousStatus}, gin.H{"status": invite.Status, "expiresAt": expiresAt})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite resent", "expiresAt": expiresAt})
+}
+
+func (d *DocumentController) CancelInvite(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
 	}
 
-	if oldInvite.Status == model.InviteStatus(model.Accepted) {
-		c.JSON(http.StatusOK, gin.H{"error": "Invite already accepted"})
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	invite, _, ok := d.ownedInvite(c, userDetails)
+	if !ok {
 		return
 	}
 
-	if oldInvite.Status == model.InviteStatus(model.Pending) {
-		var deleteInvite model.Invite
-		if err := d.InviteRepository.Delete(&deleteInvite, oldInvite.ID); err != nil {
-			log.Printf("Error: %s", err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err := d.InviteRepository.SetStatus(invite.ID, model.Cancelled); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.Conflict("Only pending invites can be cancelled"))
 			return
 		}
+		c.Error(err)
+		return
 	}
 
-	newInvite := model.Invite{
-		Email:          &payload.Email,
-		DocumentId:     documentUUID,
-		InviterId:      userDetails.ID,
-		Role:           payload.Role,
-		Status:         model.InviteStatus(model.Pending),
-		Token:          token,
-		CollaboratorId: collaboratorId,
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditInviteCancelled,
+		TargetType: "invite",
+		TargetID:   invite.ID.String(),
+		DocumentID: &invite.DocumentId,
+	}, gin.H{"status": invite.Status}, gin.H{"status": model.Cancelled})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite cancelled"})
+}
+
+// checkInviteOpen reports whether invite can still be accepted or declined.
+// A pending invite whose link ran out is marked expired on the way.
+func (d *DocumentController) checkInviteOpen(invite *model.Invite) bool {
+	if invite.Open() {
+		return true
+	}
+	if invite.Status == model.InviteStatus(model.Pending) {
+		if err := d.InviteRepository.SetStatus(invite.ID, model.Expired); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error("Error expiring invite", "inviteId", invite.ID, "error", err)
+		}
+		invite.Status = model.InviteStatus(model.Expired)
+	}
+	return false
+}
+
+func respondClosedInvite(c *gin.Context, invite *model.Invite) {
+	if invite.Status == model.InviteStatus(model.Expired) {
+		c.Error(apperror.Gone("Invitation has expired; ask the document owner to resend it"))
+		return
+	}
+	c.Error(apperror.Gone(fmt.Sprintf("Invitation is %s", invite.Status)))
+}
+
+// ListDocumentInvites returns the document's pending invites to its owner.
+func (d *DocumentController) ListDocumentInvites(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
 	}
 
-	if err := d.InviteRepository.Create(&newInvite); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
 		return
 	}
 
-	envVars, err := constants.LoadEnv()
+	documentUUID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
-	inviteUrl := fmt.Sprintf("%s/invite/%s", envVars.FE_ROOT_URL, token)
-	err = handlers.SendMail(payload.Email, "invite", "Invite Mail", handlers.Invite{
-		InviteLink:    inviteUrl,
-		DocumentTitle: document.Title,
-		Role:          payload.Role,
-		FullName:      payload.Email,
-		Year:          time.Now().Year(),
-	})
+	var document model.Document
+	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Document not found"))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	if document.UserID != userDetails.ID {
+		c.Error(apperror.Forbidden("You don't have permission for this action"))
+		return
+	}
+
+	invites, err := d.InviteRepository.GetDocumentInvites(documentUUID, model.Pending)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Invite sent successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Invites fetched", "invites": invites})
 }
 
-func (d *DocumentController) AcceptInvitation(c *gin.Context) {
-	token := c.Param("token")
-	envVars, err := constants.LoadEnv()
+// ListMyInvites returns the open invites sent to the user's email.
+func (d *DocumentController) ListMyInvites(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	invites, err := d.InviteRepository.GetOpenForEmail(userDetails.Email)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
+	received := make([]ReceivedInvite, 0, len(invites))
+	for _, invite := range invites {
+		var document model.Document
+		if err := d.DocumentRepository.GetOne(invite.DocumentId, &document); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			c.Error(err)
+			return
+		}
+		received = append(received, ReceivedInvite{
+			Invite:        invite,
+			DocumentTitle: document.Title,
+			Link:          fmt.Sprintf("/invite/%s", invite.Token),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invites fetched", "invites": received})
+}
+
+func (d *DocumentController) DeclineInvitation(c *gin.Context) {
+	var invite model.Invite
+	if err := d.InviteRepository.GetOneByToken(c.Param("token"), &invite); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Invitation not found"))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	if !d.checkInviteOpen(&invite) {
+		respondClosedInvite(c, &invite)
+		return
+	}
+
+	if err := d.InviteRepository.SetStatus(invite.ID, model.Declined); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.Gone("Invitation is no longer pending"))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    invite.CollaboratorId,
+		ActorEmail: *invite.Email,
+		Action:     model.AuditInviteDeclined,
+		TargetType: "invite",
+		TargetID:   invite.ID.String(),
+		DocumentID: &invite.DocumentId,
+	}, gin.H{"status": model.Pending}, gin.H{"status": model.Declined})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+func (d *DocumentController) AcceptInvitation(c *gin.Context) {
+	token := c.Param("token")
+
 	var invite model.Invite
 	if err := d.InviteRepository.GetOneByToken(token, &invite); err != nil {
-		log.Printf("Error: %s", err.Error())
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"message": "Invitation not found"})
+			c.Error(apperror.NotFound("Invitation not found"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
+		return
+	}
+
+	if !d.checkInviteOpen(&invite) {
+		respondClosedInvite(c, &invite)
 		return
 	}
 
 	var user model.User
-	err = d.UserRepository.GetByEmail(&user, *invite.Email)
+	err := d.UserRepository.GetByEmail(&user, *invite.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Printf("Error retrieving user details: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error retrieving user details: %w", err))
 		return
 	}
 	if err == nil {
@@ -753,111 +1312,169 @@ func (d *DocumentController) AcceptInvitation(c *gin.Context) {
 			DocumentId:     invite.DocumentId,
 			Role:           invite.Role,
 		}
-		if err := d.DocumentAccessRepository.Create(&documentAccess); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error granting document access"})
-			return
-		}
-
-		invite.Status = model.InviteStatus(model.Accepted)
-		if err := d.InviteRepository.Update(&invite, invite.ID); err != nil {
-			log.Printf("Error: %s", err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
-		}
-
-		tokenGenerator, err := jwt.NewSession()
+		// Accepting first makes the invite single use: a concurrent accept
+		// finds it no longer pending and grants nothing.
+		err := d.DocumentRepository.ExecuteInTransaction(func(tx *gorm.DB) error {
+			if err := d.InviteRepository.AcceptWithTransaction(tx, invite.ID); err != nil {
+				return err
+			}
+			if err := d.DocumentAccessRepository.CreateWithTransaction(tx, &documentAccess); err != nil {
+				return fmt.Errorf("error granting document access: %w", err)
+			}
+			return nil
+		}, 2)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			respondAcceptError(c, err)
 			return
 		}
+		invite.Status = model.InviteStatus(model.Accepted)
 
-		accessToken, err := tokenGenerator.GenerateAccessToken(user.Email)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		// The invite link reached this inbox, which proves the email is theirs.
+		if err := d.EmailVerifier.Claim(&user); err != nil {
+			c.Error(err)
 			return
 		}
 
-		refreshToken, err := tokenGenerator.GenerateRefreshToken(user.Email)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
-			return
-		}
+		d.Auditor.Record(c, model.AuditLog{
+			ActorID:    &user.ID,
+			ActorEmail: user.Email,
+			Action:     model.AuditInviteAccepted,
+			TargetType: "invite",
+			TargetID:   invite.ID.String(),
+			DocumentID: &invite.DocumentId,
+		}, gin.H{"status": model.Pending}, gin.H{"status": invite.Status, "role": invite.Role})
+
+		d.publish(c.Request.Context(), model.WebhookInviteAccepted, invite.DocumentId, &user.ID, gin.H{"inviteId": invite.ID, "email": invite.Email})
+		d.publish(c.Request.Context(), model.WebhookAccessGranted, invite.DocumentId, &invite.InviterId, gin.H{
+			"collaboratorId": user.ID,
+			"role":           documentAccess.Role,
+		})
 
+		// The invite token is not a credential: the user signs in as usual,
+		// with their password and second factor, to open the document.
 		c.JSON(http.StatusOK, gin.H{
-			"message":      "Account setup complete",
-			"accessToken":  accessToken,
-			"refreshToken": refreshToken,
-			"redirectTo":   fmt.Sprintf("/documents/%s", invite.DocumentId.String()),
+			"message":    "Invitation accepted; sign in to open the document",
+			"redirectTo": fmt.Sprintf("/documents/%s", invite.DocumentId.String()),
 		})
 		return
 	}
 
-	newUser := model.User{
-		Email: *invite.Email,
-	}
-	createdUser, err := d.UserRepository.Create(&newUser)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
-		return
-	}
-
 	var document model.Document
 	if err := d.DocumentRepository.GetOne(invite.DocumentId, &document); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			c.Error(apperror.NotFound("Document not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(err)
 		return
 	}
 
-	invite.Status = model.InviteStatus(model.Accepted)
-	if err := d.InviteRepository.Update(&invite, invite.ID); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return
+	verifiedAt := time.Now().UTC()
+	createdUser := model.User{
+		Email:           *invite.Email,
+		Locale:          handlers.ResolveLocale(c.GetHeader("Accept-Language")),
+		EmailVerifiedAt: &verifiedAt,
 	}
+	var documentAccess model.DocumentAccess
+	// Access is granted now; the account can only be used once it has been
+	// completed with the emailed link. The link is sent before committing,
+	// so an invite is never used up without one reaching the invitee.
+	err = d.DocumentRepository.ExecuteInTransaction(func(tx *gorm.DB) error {
+		if err := d.InviteRepository.AcceptWithTransaction(tx, invite.ID); err != nil {
+			return err
+		}
+		if err := d.UserRepository.CreateWithTransaction(tx, &createdUser); err != nil {
+			return fmt.Errorf("error creating user: %w", err)
+		}
+		if err := d.InviteRepository.SetCollaboratorWithTransaction(tx, invite.ID, createdUser.ID); err != nil {
+			return fmt.Errorf("error updating invite: %w", err)
+		}
+		documentAccess = model.DocumentAccess{
+			CollaboratorId: createdUser.ID,
+			DocumentId:     invite.DocumentId,
+			Role:           invite.Role,
+		}
+		if err := d.DocumentAccessRepository.CreateWithTransaction(tx, &documentAccess); err != nil {
+			return fmt.Errorf("error granting document access: %w", err)
+		}
 
-	accountSetupUrl := fmt.Sprintf("%s/complete-registration/%s?documentId=%s", envVars.DB_URI, createdUser.ID, invite.DocumentId)
-	err = handlers.SendMail(user.Email, "welcome", "Welcome Mail", handlers.AccountSetup{
-		DocumentTitle:    document.Title,
-		Role:             invite.Role,
-		AccountSetupLink: accountSetupUrl,
-	})
+		completionToken, err := d.Sessions.Session.GenerateAccountCompletionToken(createdUser.ID.String(), invite.ID.String())
+		if err != nil {
+			return err
+		}
+		accountSetupUrl := fmt.Sprintf("%s/complete-registration?token=%s", d.Config.Frontend.RootURL, url.QueryEscape(completionToken))
+		return d.Mailer.Send(c.Request.Context(), createdUser.Email, createdUser.Locale, "accountCompletion", handlers.AccountSetup{
+			DocumentTitle:    document.Title,
+			Role:             invite.Role,
+			AccountSetupLink: accountSetupUrl,
+			Year:             time.Now().UTC().Year(),
+		})
+	}, 2)
 	if err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		respondAcceptError(c, err)
 		return
 	}
+	invite.Status = model.InviteStatus(model.Accepted)
+	invite.CollaboratorId = &createdUser.ID
+
+	d.Auditor.Record(c, model.AuditLog{
+		ActorID:    &createdUser.ID,
+		ActorEmail: createdUser.Email,
+		Action:     model.AuditInviteAccepted,
+		TargetType: "invite",
+		TargetID:   invite.ID.String(),
+		DocumentID: &invite.DocumentId,
+	}, gin.H{"status": model.Pending}, gin.H{"status": invite.Status, "role": invite.Role, "accountCreated": true})
+
+	d.WebhookPublisher.Publish(c.Request.Context(), model.WebhookInviteAccepted, &document, &createdUser.ID, gin.H{"inviteId": invite.ID, "email": invite.Email})
+	d.WebhookPublisher.Publish(c.Request.Context(), model.WebhookAccessGranted, &document, &invite.InviterId, gin.H{
+		"collaboratorId": createdUser.ID,
+		"role":           documentAccess.Role,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "invite accepted"})
 }
 
+// respondAcceptError reports a failed accept, as gone when another request
+// accepted, declined or expired the invite first.
+func respondAcceptError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Error(apperror.Gone("Invitation is no longer pending"))
+		return
+	}
+	c.Error(err)
+}
+
 func (d *DocumentController) VerifyInviteToken(c *gin.Context) {
-	token := c.Query("token")
+	token := pathOrQuery(c, "token", "token")
 	if token == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		c.Error(apperror.BadRequest("Token is required"))
 		return
 	}
 
 	var invite model.Invite
 	if err := d.InviteRepository.GetOneByToken(token, &invite); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+			c.Error(apperror.NotFound("Invitation not found"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
 	if invite.Status == model.InviteStatus(model.Accepted) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invite already accepted"})
+		c.Error(apperror.Gone("Invite already accepted"))
+		return
+	}
+
+	if !d.checkInviteOpen(&invite) {
+		respondClosedInvite(c, &invite)
 		return
 	}
 
 	var document model.Document
 	if err := d.DocumentRepository.GetOne(invite.DocumentId, &document); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Document not found"})
+		c.Error(fmt.Errorf("error fetching invited document: %w", err))
 		return
 	}
 
@@ -872,42 +1489,39 @@ func (d *DocumentController) GenerateDocPDF(c *gin.Context) {
 	user, exists := c.Get("user")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	_, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
-	documentId := c.Query("documentId")
+	ctx := c.Request.Context()
+	documentId := pathOrQuery(c, "id", "documentId")
 	documentUUID, err := uuid.Parse(documentId)
 	if err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var document model.Document
-	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err := d.DocumentRepository.WithContext(ctx).GetOne(documentUUID, &document); err != nil {
+		c.Error(err)
 		return
 	}
 
 	var documentMetaData model.DocumentMetadata
-	if err := d.DocumentMetadataRepository.GetOneByDocId(documentUUID, &documentMetaData); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err := d.DocumentMetadataRepository.WithContext(ctx).GetOneByDocId(documentUUID, &documentMetaData); err != nil {
+		c.Error(err)
 		return
 	}
 
-	uploaded, err := utils.DocumentHandler(&document, &documentMetaData)
+	uploaded, err := utils.DocumentHandler(ctx, &document, &documentMetaData)
 	if err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
@@ -918,11 +1532,67 @@ func (d *DocumentController) GenerateDocPDF(c *gin.Context) {
 		Format:     "pdf",
 	}
 
-	if err := d.DocumentMediaRepository.Create(&documentMedia); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err := d.DocumentMediaRepository.WithContext(ctx).Create(&documentMedia); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Document generated", "documentLink": uploaded.SecureURL})
 }
+
+func (d *DocumentController) GetAuditLog(c *gin.Context) {
+	user, exists := c.Get("user")
+	documentId := c.Param("id")
+
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	documentUUID, err := uuid.Parse(documentId)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid id"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.Error(apperror.BadRequest("Limit must be between 1 and 200"))
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.Error(apperror.BadRequest("Invalid offset"))
+		return
+	}
+
+	var document model.Document
+	if err := d.DocumentRepository.GetOne(documentUUID, &document); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Document not found"))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	if document.UserID != userDetails.ID {
+		c.Error(apperror.Forbidden("Only the document owner can view its audit log"))
+		return
+	}
+
+	entries, err := d.Auditor.AuditLogRepository.GetDocumentLogs(documentUUID, limit, offset)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Audit log fetched", "entries": entries})
+}
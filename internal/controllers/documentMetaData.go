@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/logging"
 	"realTimeEditor/internal/model"
 	"realTimeEditor/internal/repositories"
 
@@ -30,26 +32,25 @@ func NewDocumentMetaDataController(
 func (d *DocumentMetadataController) Create(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	_, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	var newDocumentMetaData model.DocumentMetadata
 	if err := c.ShouldBindJSON(&newDocumentMetaData); err != nil {
-		log.Printf("Error binding JSON: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
 	if err := d.DocumentMetaDataRepository.Create(&newDocumentMetaData); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
@@ -58,34 +59,32 @@ func (d *DocumentMetadataController) Create(c *gin.Context) {
 
 func (d *DocumentMetadataController) GetDocumentMetadata(c *gin.Context) {
 	user, exists := c.Get("user")
-	documentId := c.Param("documentId")
+	documentId := c.Param("id")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	_, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentUUID, err := uuid.Parse(documentId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var documentMetaData model.DocumentMetadata
 	if err := d.DocumentMetaDataRepository.GetOneByDocId(documentUUID, &documentMetaData); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "documentMetaData not found"})
+			c.Error(apperror.NotFound("Document metadata not found"))
 			return
 		}
-		log.Printf("Error fetching documentMetaData: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error fetching documentMetaData: %w", err))
 		return
 	}
 
@@ -94,47 +93,44 @@ func (d *DocumentMetadataController) GetDocumentMetadata(c *gin.Context) {
 
 func (d *DocumentMetadataController) Update(c *gin.Context) {
 	user, exists := c.Get("user")
-	documentMetadataId := c.Query("documentMetadataId")
+	documentMetadataId := pathOrQuery(c, "id", "documentMetadataId")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	_, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentMetadataUUID, err := uuid.Parse(documentMetadataId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var documentMetaData model.DocumentMetadata
 	if err := c.ShouldBindJSON(&documentMetaData); err != nil {
-		log.Printf("Error binding JSON: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
 	var existingMetadata model.DocumentMetadata
 	if err := d.DocumentMetaDataRepository.GetOne(documentMetadataUUID, &existingMetadata); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "documentMetaData not found"})
+			c.Error(apperror.NotFound("Document metadata not found"))
 			return
 		}
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
 	if err := d.DocumentMetaDataRepository.Update(&documentMetaData, documentMetadataUUID); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
@@ -143,30 +139,28 @@ func (d *DocumentMetadataController) Update(c *gin.Context) {
 
 func (d *DocumentMetadataController) Delete(c *gin.Context) {
 	user, exists := c.Get("user")
-	documentMetadataId := c.Query("documentMetadataId")
+	documentMetadataId := pathOrQuery(c, "id", "documentMetadataId")
 
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	_, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	documentMetadataUUID, err := uuid.Parse(documentMetadataId)
 	if err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		c.Error(apperror.BadRequest("Invalid id"))
 		return
 	}
 
 	var documentMetaData model.DocumentMetadata
 	if err := d.DocumentMetaDataRepository.Delete(&documentMetaData, documentMetadataUUID); err != nil {
-		log.Printf("Error: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
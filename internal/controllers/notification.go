@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationController struct {
+	NotificationRepository           *repositories.NotificationRepository
+	NotificationPreferenceRepository *repositories.NotificationPreferenceRepository
+}
+
+func NewNotificationController(
+	notificationRepository *repositories.NotificationRepository,
+	notificationPreferenceRepository *repositories.NotificationPreferenceRepository,
+) *NotificationController {
+	return &NotificationController{
+		NotificationRepository:           notificationRepository,
+		NotificationPreferenceRepository: notificationPreferenceRepository,
+	}
+}
+
+func (n *NotificationController) List(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.Error(apperror.BadRequest("Limit must be between 1 and 100"))
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.Error(apperror.BadRequest("Invalid offset"))
+		return
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, err := n.NotificationRepository.GetUserNotifications(userDetails.ID, unreadOnly, limit, offset)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching notifications: %w", err))
+		return
+	}
+
+	unread, err := n.NotificationRepository.CountUnread(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error counting unread notifications: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Notifications fetched",
+		"notifications": notifications,
+		"unreadCount":   unread,
+	})
+}
+
+func (n *NotificationController) UnreadCount(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	unread, err := n.NotificationRepository.CountUnread(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error counting unread notifications: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unreadCount": unread})
+}
+
+func (n *NotificationController) MarkRead(c *gin.Context) {
+	user, exists := c.Get("user")
+	notificationId := c.Param("id")
+
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	notificationUUID, err := uuid.Parse(notificationId)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid id"))
+		return
+	}
+
+	if err := n.NotificationRepository.MarkRead(notificationUUID, userDetails.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Notification not found"))
+			return
+		}
+		c.Error(fmt.Errorf("error marking notification read: %w", err))
+		return
+	}
+
+	unread, err := n.NotificationRepository.CountUnread(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error counting unread notifications: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read", "unreadCount": unread})
+}
+
+func (n *NotificationController) MarkAllRead(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	updated, err := n.NotificationRepository.MarkAllRead(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error marking notifications read: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "updated": updated, "unreadCount": 0})
+}
+
+func (n *NotificationController) GetPreferences(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	preferences, err := n.NotificationPreferenceRepository.GetUserPreferences(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching notification preferences: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+func (n *NotificationController) UpdatePreferences(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload struct {
+		Preferences []struct {
+			Event model.NotificationEvent `json:"event" binding:"required"`
+			InApp bool                    `json:"inApp"`
+			Email bool                    `json:"email"`
+		} `json:"preferences" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	for _, p := range payload.Preferences {
+		if !slices.Contains(model.NotificationEvents, p.Event) {
+			c.Error(apperror.BadRequest("Unknown event " + string(p.Event)))
+			return
+		}
+	}
+
+	for _, p := range payload.Preferences {
+		pref := model.NotificationPreference{
+			UserID: userDetails.ID,
+			Event:  p.Event,
+			InApp:  p.InApp,
+			Email:  p.Email,
+		}
+		if err := n.NotificationPreferenceRepository.Upsert(&pref); err != nil {
+			c.Error(fmt.Errorf("error saving notification preference: %w", err))
+			return
+		}
+	}
+
+	preferences, err := n.NotificationPreferenceRepository.GetUserPreferences(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching notification preferences: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification preferences updated", "preferences": preferences})
+}
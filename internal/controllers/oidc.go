@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OIDCController struct {
+	OIDC      *handlers.OIDCService
+	Sessions  *handlers.SessionManager
+	TwoFactor *handlers.TwoFactorService
+	Auditor   *handlers.Auditor
+	Config    *config.Config
+}
+
+func NewOIDCController(
+	oidc *handlers.OIDCService,
+	sessions *handlers.SessionManager,
+	twoFactor *handlers.TwoFactorService,
+	auditor *handlers.Auditor,
+	cfg *config.Config,
+) *OIDCController {
+	return &OIDCController{
+		OIDC:      oidc,
+		Sessions:  sessions,
+		TwoFactor: twoFactor,
+		Auditor:   auditor,
+		Config:    cfg,
+	}
+}
+
+func (o *OIDCController) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": o.OIDC.Providers()})
+}
+
+// Login redirects the browser to the provider to start signing in.
+func (o *OIDCController) Login(c *gin.Context) {
+	authURL, err := o.OIDC.Begin(c.Request.Context(), c.Param("provider"), nil)
+	if err != nil {
+		if errors.Is(err, handlers.ErrOIDCUnknownProvider) {
+			c.Error(apperror.NotFound("Unknown provider"))
+			return
+		}
+		c.Error(apperror.BadGateway("Could not reach the identity provider", fmt.Errorf("error starting OIDC login: %w", err)))
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes a login or link flow and hands the result to the
+// frontend in the URL fragment, which browsers never send to servers.
+func (o *OIDCController) Callback(c *gin.Context) {
+	providerName := c.Param("provider")
+
+	if providerError := c.Query("error"); providerError != "" {
+		o.redirectToFrontend(c, url.Values{"error": {providerError}})
+		return
+	}
+
+	result, err := o.OIDC.Complete(c.Request.Context(), providerName, c.Query("state"), c.Query("code"))
+	if err != nil {
+		code := "oidc_failed"
+		switch {
+		case errors.Is(err, handlers.ErrOIDCUnknownProvider), errors.Is(err, handlers.ErrOIDCInvalidState):
+			code = "invalid_request"
+		case errors.Is(err, handlers.ErrOIDCEmailNotVerified):
+			code = "email_not_verified"
+		case errors.Is(err, handlers.ErrOIDCNoAccount):
+			code = "no_account"
+		case errors.Is(err, handlers.ErrOIDCIdentityInUse):
+			code = "identity_in_use"
+		case errors.Is(err, handlers.ErrOIDCAlreadyLinked):
+			code = "provider_already_linked"
+		default:
+			logging.From(c).Error("Error completing OIDC flow", "error", err)
+		}
+		o.redirectToFrontend(c, url.Values{"error": {code}})
+		return
+	}
+
+	user := result.User
+	if result.NewIdentity {
+		o.Auditor.Record(c, model.AuditLog{
+			ActorID:    &user.ID,
+			ActorEmail: user.Email,
+			Action:     model.AuditIdentityLinked,
+			TargetType: "user",
+			TargetID:   user.ID.String(),
+		}, nil, gin.H{"provider": result.Provider, "provisioned": result.Provisioned})
+	}
+
+	if result.LinkFlow {
+		o.redirectToFrontend(c, url.Values{"linked": {result.Provider}})
+		return
+	}
+
+	mfaEnabled, err := o.TwoFactor.Enabled(user.ID)
+	if err != nil {
+		logging.From(c).Error("Error checking two-factor status", "error", err)
+		o.redirectToFrontend(c, url.Values{"error": {"oidc_failed"}})
+		return
+	}
+	if mfaEnabled {
+		mfaToken, err := o.Sessions.Session.GenerateMFAChallengeToken(user.Email)
+		if err != nil {
+			o.redirectToFrontend(c, url.Values{"error": {"oidc_failed"}})
+			return
+		}
+		o.redirectToFrontend(c, url.Values{"mfaRequired": {"true"}, "mfaToken": {mfaToken}})
+		return
+	}
+
+	o.Auditor.Record(c, model.AuditLog{
+		ActorID:    &user.ID,
+		ActorEmail: user.Email,
+		Action:     model.AuditLoginSucceeded,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	}, nil, gin.H{"method": "oidc", "provider": result.Provider})
+
+	tokens, err := o.Sessions.Issue(c, user)
+	if err != nil {
+		logging.From(c).Error("Error issuing session", "error", err)
+		o.redirectToFrontend(c, url.Values{"error": {"oidc_failed"}})
+		return
+	}
+
+	o.redirectToFrontend(c, url.Values{
+		"accessToken":  {tokens.AccessToken},
+		"refreshToken": {tokens.RefreshToken},
+	})
+}
+
+func (o *OIDCController) ListIdentities(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	identities, err := o.OIDC.UserIdentityRepository.GetUserIdentities(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching identities: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// Link starts a flow that adds the provider to the signed in user's account.
+// It returns the URL instead of redirecting because it needs the bearer token.
+func (o *OIDCController) Link(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	authURL, err := o.OIDC.Begin(c.Request.Context(), c.Param("provider"), &userDetails.ID)
+	if err != nil {
+		if errors.Is(err, handlers.ErrOIDCUnknownProvider) {
+			c.Error(apperror.NotFound("Unknown provider"))
+			return
+		}
+		c.Error(apperror.BadGateway("Could not reach the identity provider", fmt.Errorf("error starting OIDC link: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorizationUrl": authURL})
+}
+
+func (o *OIDCController) Unlink(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	identityId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid id"))
+		return
+	}
+
+	var identity model.UserIdentity
+	if err := o.OIDC.UserIdentityRepository.GetOneForUser(identityId, userDetails.ID, &identity); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Identity not found"))
+			return
+		}
+		c.Error(fmt.Errorf("error fetching identity: %w", err))
+		return
+	}
+
+	// Without a password the last identity is the only way to sign in.
+	if userDetails.Password == nil {
+		count, err := o.OIDC.UserIdentityRepository.CountForUser(userDetails.ID)
+		if err != nil {
+			c.Error(fmt.Errorf("error counting identities: %w", err))
+			return
+		}
+		if count <= 1 {
+			c.Error(apperror.Conflict("Set a password before unlinking your only sign-in method"))
+			return
+		}
+	}
+
+	if err := o.OIDC.UserIdentityRepository.Delete(identity.ID); err != nil {
+		c.Error(fmt.Errorf("error unlinking identity: %w", err))
+		return
+	}
+
+	o.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditIdentityUnlinked,
+		TargetType: "user",
+		TargetID:   userDetails.ID.String(),
+	}, gin.H{"provider": identity.Provider, "email": identity.Email}, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked"})
+}
+
+func (o *OIDCController) redirectToFrontend(c *gin.Context, params url.Values) {
+	c.Redirect(http.StatusFound, o.Config.Frontend.RootURL+"/auth/oidc/callback#"+params.Encode())
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/dbtest"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/oidctest"
+	"realTimeEditor/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	testProvider    = "stub"
+	testFrontendURL = "http://frontend.test"
+)
+
+type oidcControllerFixture struct {
+	router   *gin.Engine
+	provider *oidctest.Provider
+	db       *gorm.DB
+	user     model.User
+}
+
+// newOIDCControllerFixture serves the OIDC routes; requests to /link are
+// made as user, as if they had passed the auth middleware.
+func newOIDCControllerFixture(t *testing.T) *oidcControllerFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t,
+		&model.User{},
+		&model.UserIdentity{},
+		&model.OIDCAuthRequest{},
+		&model.Invite{},
+		&model.RefreshToken{},
+		&model.PersonalAccessToken{},
+		&model.AuditLog{},
+	)
+	provider := oidctest.NewProvider(t, "client")
+
+	now := time.Now().UTC()
+	user := model.User{Email: "ada@example.com", Locale: "en", EmailVerifiedAt: &now}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	userRepository := repositories.NewUserRepository(db)
+	service := handlers.NewOIDCService(
+		[]config.OIDCProvider{{
+			Name:        testProvider,
+			IssuerURL:   provider.URL,
+			ClientID:    provider.ClientID,
+			Scopes:      []string{"openid", "email"},
+			RedirectURL: "http://localhost/auth/oidc/stub/callback",
+		}},
+		repositories.NewOIDCAuthRequestRepository(db),
+		repositories.NewUserIdentityRepository(db),
+		userRepository,
+		repositories.NewInviteRepository(db),
+		handlers.NewEmailVerifier(nil, userRepository, repositories.NewRefreshTokenRepository(db), repositories.NewPersonalAccessTokenRepository(db), nil, nil),
+	)
+	controller := NewOIDCController(
+		service,
+		nil,
+		nil,
+		handlers.NewAuditor(repositories.NewAuditLogRepository(db)),
+		&config.Config{Frontend: config.FrontendConfig{RootURL: testFrontendURL}},
+	)
+
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler())
+	router.GET("/auth/oidc/:provider/login", controller.Login)
+	router.GET("/auth/oidc/:provider/callback", controller.Callback)
+	router.POST("/auth/oidc/:provider/link", func(c *gin.Context) {
+		c.Set("user", user)
+	}, controller.Link)
+
+	return &oidcControllerFixture{router: router, provider: provider, db: db, user: user}
+}
+
+func (f *oidcControllerFixture) serve(method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+// callback approves the flow at authURL with claims and returns the
+// parameters the frontend receives.
+func (f *oidcControllerFixture) callback(t *testing.T, authURL string, claims oidctest.Claims) url.Values {
+	t.Helper()
+	state, code := f.provider.Authorize(authURL, claims)
+	response := f.serve(http.MethodGet, "/auth/oidc/stub/callback?"+url.Values{"state": {state}, "code": {code}}.Encode())
+	return frontendParams(t, response)
+}
+
+// login starts a sign in flow and returns the provider URL it redirects to.
+func (f *oidcControllerFixture) login(t *testing.T) string {
+	t.Helper()
+	response := f.serve(http.MethodGet, "/auth/oidc/stub/login")
+	if response.Code != http.StatusFound {
+		t.Fatalf("login: status = %d, want %d", response.Code, http.StatusFound)
+	}
+	return response.Header().Get("Location")
+}
+
+func frontendParams(t *testing.T, response *httptest.ResponseRecorder) url.Values {
+	t.Helper()
+	location := response.Header().Get("Location")
+	if response.Code != http.StatusFound || !strings.HasPrefix(location, testFrontendURL+"/auth/oidc/callback#") {
+		t.Fatalf("callback: status = %d, location = %q, want a redirect to the frontend", response.Code, location)
+	}
+	params, err := url.ParseQuery(location[strings.Index(location, "#")+1:])
+	if err != nil {
+		t.Fatalf("parsing fragment: %v", err)
+	}
+	return params
+}
+
+func TestOIDCLoginRedirectsToProvider(t *testing.T) {
+	f := newOIDCControllerFixture(t)
+	if location := f.login(t); !strings.HasPrefix(location, f.provider.URL+"/authorize?") {
+		t.Fatalf("location = %q, want the provider's authorization endpoint", location)
+	}
+
+	if response := f.serve(http.MethodGet, "/auth/oidc/other/login"); response.Code != http.StatusNotFound {
+		t.Fatalf("unknown provider: status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+}
+
+func TestOIDCCallbackErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims oidctest.Claims
+		want   string
+	}{
+		{"unverified email", oidctest.Claims{Subject: "sub-1", Email: "ada@example.com"}, "email_not_verified"},
+		{"nonce mismatch", oidctest.Claims{Subject: "sub-1", Email: "ada@example.com", EmailVerified: true, Nonce: "replayed"}, "oidc_failed"},
+		{"no account", oidctest.Claims{Subject: "sub-1", Email: "grace@example.com", EmailVerified: true}, "no_account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCControllerFixture(t)
+			params := f.callback(t, f.login(t), tt.claims)
+			if got := params.Get("error"); got != tt.want {
+				t.Fatalf("error = %q, want %q", got, tt.want)
+			}
+			if params.Has("accessToken") {
+				t.Fatal("failed callback handed out an access token")
+			}
+		})
+	}
+
+	t.Run("reused state", func(t *testing.T) {
+		f := newOIDCControllerFixture(t)
+		state, code := f.provider.Authorize(f.login(t), oidctest.Claims{Subject: "sub-1"})
+		target := "/auth/oidc/stub/callback?" + url.Values{"state": {state}, "code": {code}}.Encode()
+		f.serve(http.MethodGet, target)
+		if got := frontendParams(t, f.serve(http.MethodGet, target)).Get("error"); got != "invalid_request" {
+			t.Fatalf("error = %q, want invalid_request", got)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		f := newOIDCControllerFixture(t)
+		params := frontendParams(t, f.serve(http.MethodGet, "/auth/oidc/stub/callback?error=access_denied"))
+		if got := params.Get("error"); got != "access_denied" {
+			t.Fatalf("error = %q, want access_denied", got)
+		}
+	})
+}
+
+func TestOIDCLinkFlow(t *testing.T) {
+	f := newOIDCControllerFixture(t)
+
+	response := f.serve(http.MethodPost, "/auth/oidc/stub/link")
+	if response.Code != http.StatusOK {
+		t.Fatalf("link: status = %d, want %d", response.Code, http.StatusOK)
+	}
+	var body struct {
+		AuthorizationURL string `json:"authorizationUrl"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding link response: %v", err)
+	}
+
+	params := f.callback(t, body.AuthorizationURL, oidctest.Claims{Subject: "sub-1", Email: "ada@work.example"})
+	if got := params.Get("linked"); got != testProvider {
+		t.Fatalf("callback params = %v, want linked=%s", params, testProvider)
+	}
+
+	var identity model.UserIdentity
+	if err := f.db.Where("provider = ? AND subject = ?", testProvider, "sub-1").First(&identity).Error; err != nil {
+		t.Fatalf("fetching identity: %v", err)
+	}
+	if identity.UserID != f.user.ID {
+		t.Fatalf("identity linked to %s, want %s", identity.UserID, f.user.ID)
+	}
+
+	var audits int64
+	f.db.Model(&model.AuditLog{}).Where("action = ? AND target_id = ?", model.AuditIdentityLinked, f.user.ID.String()).Count(&audits)
+	if audits != 1 {
+		t.Fatalf("%d identity_linked audit entries, want 1", audits)
+	}
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxTokenLifetimeDays = 365
+
+type PersonalAccessTokenController struct {
+	PersonalAccessTokenRepository *repositories.PersonalAccessTokenRepository
+	Auditor                       *handlers.Auditor
+}
+
+func NewPersonalAccessTokenController(
+	personalAccessTokenRepository *repositories.PersonalAccessTokenRepository,
+	auditor *handlers.Auditor,
+) *PersonalAccessTokenController {
+	return &PersonalAccessTokenController{
+		PersonalAccessTokenRepository: personalAccessTokenRepository,
+		Auditor:                       auditor,
+	}
+}
+
+type PersonalAccessTokenPayload struct {
+	Name          string             `json:"name" binding:"required"`
+	Scopes        []model.TokenScope `json:"scopes" binding:"required"`
+	ExpiresInDays *int               `json:"expiresInDays"`
+}
+
+func (p *PersonalAccessTokenController) Create(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload PersonalAccessTokenPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" || len(payload.Name) > 100 {
+		c.Error(apperror.BadRequest("Name must be between 1 and 100 characters"))
+		return
+	}
+
+	if !validTokenScopes(payload.Scopes) {
+		c.Error(apperror.BadRequest("Scopes must be a non-empty list of supported scopes").With("supportedScopes", model.TokenScopes))
+		return
+	}
+
+	token := model.PersonalAccessToken{
+		UserID: userDetails.ID,
+		Name:   payload.Name,
+		Scopes: payload.Scopes,
+	}
+
+	if payload.ExpiresInDays != nil {
+		if *payload.ExpiresInDays < 1 || *payload.ExpiresInDays > maxTokenLifetimeDays {
+			c.Error(apperror.BadRequest("Expiry must be between 1 and 365 days"))
+			return
+		}
+		expiresAt := time.Now().UTC().AddDate(0, 0, *payload.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	secret, err := utils.NewCodeGenerator().GenerateSecureToken(32)
+	if err != nil {
+		c.Error(fmt.Errorf("error generating personal access token: %w", err))
+		return
+	}
+	plaintext := model.PersonalAccessTokenPrefix + secret
+	token.Prefix = plaintext[:len(model.PersonalAccessTokenPrefix)+8]
+	token.TokenHash = utils.HashToken(plaintext)
+
+	if err := p.PersonalAccessTokenRepository.Create(&token); err != nil {
+		c.Error(fmt.Errorf("error creating personal access token: %w", err))
+		return
+	}
+
+	p.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditTokenCreated,
+		TargetType: "personal_access_token",
+		TargetID:   token.ID.String(),
+	}, nil, gin.H{"name": token.Name, "scopes": token.Scopes, "expiresAt": token.ExpiresAt})
+
+	// The plaintext token is only ever returned here.
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Token created. Copy it now; it will not be shown again",
+		"token":   plaintext,
+		"details": token,
+	})
+}
+
+func (p *PersonalAccessTokenController) List(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	tokens, err := p.PersonalAccessTokenRepository.GetUserTokens(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching personal access tokens: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (p *PersonalAccessTokenController) Revoke(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	tokenId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid id"))
+		return
+	}
+
+	var token model.PersonalAccessToken
+	if err := p.PersonalAccessTokenRepository.GetOneForUser(tokenId, userDetails.ID, &token); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Token not found"))
+			return
+		}
+		c.Error(fmt.Errorf("error fetching personal access token: %w", err))
+		return
+	}
+
+	if token.RevokedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Token already revoked"})
+		return
+	}
+
+	if err := p.PersonalAccessTokenRepository.Revoke(token.ID); err != nil {
+		c.Error(fmt.Errorf("error revoking personal access token: %w", err))
+		return
+	}
+
+	p.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditTokenRevoked,
+		TargetType: "personal_access_token",
+		TargetID:   token.ID.String(),
+	}, gin.H{"name": token.Name, "scopes": token.Scopes}, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+func validTokenScopes(scopes []model.TokenScope) bool {
+	if len(scopes) == 0 {
+		return false
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(model.TokenScopes, scope) {
+			return false
+		}
+	}
+	return true
+}
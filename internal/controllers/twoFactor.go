@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct {
+	TwoFactor      *handlers.TwoFactorService
+	Auditor        *handlers.Auditor
+	PasswordHasher *utils.PasswordHasher
+}
+
+func NewTwoFactorController(twoFactor *handlers.TwoFactorService, auditor *handlers.Auditor, passwordHasher *utils.PasswordHasher) *TwoFactorController {
+	return &TwoFactorController{
+		TwoFactor:      twoFactor,
+		Auditor:        auditor,
+		PasswordHasher: passwordHasher,
+	}
+}
+
+// reauthPayload is required by every endpoint that changes 2FA settings. Code
+// is a TOTP or recovery code and is only checked once 2FA is enabled.
+type reauthPayload struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (t *TwoFactorController) Status(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	twoFactor, err := t.TwoFactor.Status(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching two-factor status: %w", err))
+		return
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	remaining, err := t.TwoFactor.TwoFactorRepository.CountUnusedRecoveryCodes(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error counting recovery codes: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                true,
+		"confirmedAt":            twoFactor.ConfirmedAt,
+		"recoveryCodesRemaining": remaining,
+	})
+}
+
+func (t *TwoFactorController) Enroll(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload reauthPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if !t.reauthenticate(c, &userDetails, payload, false) {
+		return
+	}
+
+	enrollment, err := t.TwoFactor.Enroll(&userDetails)
+	if err != nil {
+		if errors.Is(err, handlers.ErrTwoFactorAlreadyEnabled) {
+			c.Error(apperror.Conflict("Two-factor authentication is already enabled"))
+			return
+		}
+		c.Error(fmt.Errorf("error enrolling two-factor authentication: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Scan the QR code and confirm with a code from your authenticator app",
+		"secret":     enrollment.Secret,
+		"otpauthUri": enrollment.OTPAuthURI,
+	})
+}
+
+func (t *TwoFactorController) Confirm(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	recoveryCodes, err := t.TwoFactor.Confirm(userDetails.ID, payload.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrTwoFactorNotEnrolled):
+			c.Error(apperror.BadRequest("Start enrollment first"))
+		case errors.Is(err, handlers.ErrTwoFactorAlreadyEnabled):
+			c.Error(apperror.Conflict("Two-factor authentication is already enabled"))
+		case errors.Is(err, handlers.ErrTwoFactorInvalidCode):
+			c.Error(apperror.BadRequest("Invalid code"))
+		default:
+			c.Error(fmt.Errorf("error confirming two-factor authentication: %w", err))
+		}
+		return
+	}
+
+	t.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditTwoFactorEnabled,
+		TargetType: "user",
+		TargetID:   userDetails.ID.String(),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Two-factor authentication enabled. Store these recovery codes safely; they will not be shown again",
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+func (t *TwoFactorController) Disable(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload reauthPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if !t.reauthenticate(c, &userDetails, payload, true) {
+		return
+	}
+
+	if err := t.TwoFactor.Disable(userDetails.ID); err != nil {
+		c.Error(fmt.Errorf("error disabling two-factor authentication: %w", err))
+		return
+	}
+
+	t.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditTwoFactorDisabled,
+		TargetType: "user",
+		TargetID:   userDetails.ID.String(),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+func (t *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload reauthPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if !t.reauthenticate(c, &userDetails, payload, true) {
+		return
+	}
+
+	recoveryCodes, err := t.TwoFactor.RegenerateRecoveryCodes(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error regenerating recovery codes: %w", err))
+		return
+	}
+
+	t.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditRecoveryCodesRenewed,
+		TargetType: "user",
+		TargetID:   userDetails.ID.String(),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Recovery codes regenerated; the previous codes no longer work",
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// reauthenticate checks the user's password, if the account has one, and
+// when requireCode is set a current 2FA code. It writes the error response
+// and returns false on failure.
+func (t *TwoFactorController) reauthenticate(c *gin.Context, user *model.User, payload reauthPayload, requireCode bool) bool {
+	if user.Password != nil {
+		matches, err := t.PasswordHasher.VerifyPassword(*user.Password, payload.Password)
+		if err != nil || !matches {
+			c.Error(apperror.Unauthorized("Invalid credentials"))
+			return false
+		}
+	}
+
+	if !requireCode {
+		return true
+	}
+
+	if payload.Code == "" {
+		c.Error(apperror.BadRequest("Code is required"))
+		return false
+	}
+
+	if err := t.TwoFactor.Verify(user.ID, payload.Code); err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrTwoFactorNotEnrolled):
+			c.Error(apperror.BadRequest("Two-factor authentication is not enabled"))
+		case errors.Is(err, handlers.ErrTwoFactorLocked):
+			c.Error(apperror.TooManyRequests("Too many invalid codes, try again later"))
+		case errors.Is(err, handlers.ErrTwoFactorInvalidCode):
+			c.Error(apperror.Unauthorized("Invalid code"))
+		default:
+			c.Error(fmt.Errorf("error verifying two-factor code: %w", err))
+		}
+		return false
+	}
+	return true
+}
@@ -1,11 +1,87 @@
 package controllers
 
+import (
+	"errors"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errInvalidUserType means the auth middleware stored something other than a
+// model.User, which is a server bug rather than a bad request.
+var errInvalidUserType = errors.New("invalid user type")
+
 type LoginPayload struct {
 	Email    *string `json:"email"`
-	UserName *string `json:"userName"`
 	Password string  `json:"password"`
 }
 
+type CompleteAccountPayload struct {
+	Token     string `json:"token" binding:"required"`
+	FirstName string `json:"firstName" binding:"required"`
+	LastName  string `json:"lastName" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UnlockAccountPayload struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" binding:"required"`
+}
+
 type ResetCodePayload struct {
-	ResetCode string `json:"resetCode"`
+	Email     string `json:"email" binding:"required"`
+	ResetCode string `json:"resetCode" binding:"required"`
+}
+
+type ResetPasswordPayload struct {
+	ResetToken  string `json:"resetToken" binding:"required"`
+	NewPassword string `json:"password" binding:"required"`
+}
+
+type InviteCollaboratorPayload struct {
+	DocumentId string     `json:"documentId"`
+	Email      string     `json:"email"`
+	Emails     []string   `json:"emails"`
+	Role       model.Role `json:"role"`
+}
+
+// ModifyAccessPayload is the body of PATCH /documents/:id/access/:accessId.
+type ModifyAccessPayload struct {
+	Role model.Role `json:"role" binding:"required"`
+}
+
+// TransferOwnershipPayload is the body of PUT /documents/:id/owner.
+type TransferOwnershipPayload struct {
+	UserId string `json:"userId" binding:"required"`
+}
+
+type InviteResultStatus string
+
+const (
+	InviteResultSent         InviteResultStatus = "sent"
+	InviteResultCollaborator InviteResultStatus = "already_collaborator"
+	InviteResultFailed       InviteResultStatus = "failed"
+)
+
+// InviteResult is the outcome of inviting one email.
+type InviteResult struct {
+	Email     string             `json:"email"`
+	Status    InviteResultStatus `json:"status"`
+	InviteID  *uuid.UUID         `json:"inviteId,omitempty"`
+	ExpiresAt *time.Time         `json:"expiresAt,omitempty"`
+}
+
+// ReceivedInvite is an open invite as shown to its recipient.
+type ReceivedInvite struct {
+	model.Invite
+	DocumentTitle string `json:"documentTitle"`
+	Link          string `json:"link"`
 }
@@ -1,16 +1,21 @@
 package controllers
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
+	"realTimeEditor/internal/apperror"
 	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/logging"
 	"realTimeEditor/internal/model"
 	"realTimeEditor/internal/repositories"
-	"realTimeEditor/pkg/jwt"
 	"realTimeEditor/pkg/utils"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dlclark/regexp2"
@@ -22,15 +27,39 @@ import (
 type UserController struct {
 	UserRepository           repositories.UserRepository
 	ForgotPasswordRepository repositories.ForgotPasswordRepository
+	InviteRepository         *repositories.InviteRepository
+	Auditor                  *handlers.Auditor
+	Sessions                 *handlers.SessionManager
+	TwoFactor                *handlers.TwoFactorService
+	EmailVerifier            *handlers.EmailVerifier
+	Throttle                 *handlers.LoginThrottler
+	Mailer                   *handlers.Mailer
+	PasswordHasher           *utils.PasswordHasher
 }
 
 func NewUserHandler(
 	userRepository *repositories.UserRepository,
 	forgotPasswordRepository *repositories.ForgotPasswordRepository,
+	inviteRepository *repositories.InviteRepository,
+	auditor *handlers.Auditor,
+	sessions *handlers.SessionManager,
+	twoFactor *handlers.TwoFactorService,
+	emailVerifier *handlers.EmailVerifier,
+	throttle *handlers.LoginThrottler,
+	mailer *handlers.Mailer,
+	passwordHasher *utils.PasswordHasher,
 ) *UserController {
 	return &UserController{
 		UserRepository:           *userRepository,
 		ForgotPasswordRepository: *forgotPasswordRepository,
+		InviteRepository:         inviteRepository,
+		Auditor:                  auditor,
+		Sessions:                 sessions,
+		TwoFactor:                twoFactor,
+		EmailVerifier:            emailVerifier,
+		Throttle:                 throttle,
+		Mailer:                   mailer,
+		PasswordHasher:           passwordHasher,
 	}
 }
 
@@ -41,11 +70,17 @@ var (
 	codeRegex     = regexp.MustCompile(`^[A-Za-z0-9]{6}$`)
 )
 
+const (
+	resetCodeTTL     = 15 * time.Minute
+	resetTokenTTL    = 15 * time.Minute
+	maxResetAttempts = 5
+)
+
 func (u *UserController) Create(c *gin.Context) {
 	var user model.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		log.Printf("Error binding JSON: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
@@ -53,153 +88,243 @@ func (u *UserController) Create(c *gin.Context) {
 
 	ok, err := nameRegex.MatchString(*user.FirstName)
 	if err != nil {
-		log.Printf("Regex match error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("regex match error: %w", err))
 		return
 	}
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid first name format"})
+		c.Error(apperror.BadRequest("Invalid first name format"))
 		return
 	}
 
 	ok, err = nameRegex.MatchString(*user.LastName)
 	if err != nil {
-		log.Printf("Regex match error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("regex match error: %w", err))
 		return
 	}
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last name format"})
+		c.Error(apperror.BadRequest("Invalid last name format"))
 		return
 	}
 
 	if !emailRegex.MatchString(user.Email) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
+		c.Error(apperror.BadRequest("Invalid email format"))
 		return
 	}
 
 	if !passwordRegex.MatchString(*user.Password) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password format"})
+		c.Error(apperror.BadRequest("Invalid password format"))
 		return
 	}
 
 	err = u.UserRepository.GetByEmail(&existingUser, user.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Printf("Error retrieving user details: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error retrieving user details: %w", err))
 		return
 	}
 
 	if existingUser.Email != "" {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		c.Error(apperror.Conflict("Email already exists"))
 		return
 	}
 
 	err = u.UserRepository.GetByEmail(&existingUser, user.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Printf("Error retrieving user details: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error retrieving user details: %w", err))
 		return
 	}
 
-	passwordHasher, err := utils.NewPasswordHasher()
+	hashedPassword, err := u.PasswordHasher.HashPassword(*user.Password)
 	if err != nil {
-		log.Printf("Error creating password hasher: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error hashing password: %w", err))
 		return
 	}
 
-	hashedPassword, err := passwordHasher.HashPassword(*user.Password)
+	user.Password = &hashedPassword
+	user.IsAdmin = false
+	user.EmailVerifiedAt = nil
+	if user.Locale == "" {
+		user.Locale = c.GetHeader("Accept-Language")
+	}
+	user.Locale = handlers.ResolveLocale(user.Locale)
+
+	_, err = u.UserRepository.Create(&user)
+
 	if err != nil {
-		log.Printf("Error hashing password: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error creating user: %w", err))
 		return
 	}
 
-	user.Password = &hashedPassword
+	// The welcome mail is sent once the email is verified. If this send
+	// fails the user can ask for another link after logging in.
+	if err := u.EmailVerifier.Send(c.Request.Context(), &user); err != nil {
+		logging.From(c).Error("Error sending verification mail", "error", err)
+	}
 
-	_, err = u.UserRepository.Create(&user)
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully; check your email to verify your address"})
+}
+
+func (u *UserController) VerifyEmail(c *gin.Context) {
+	var payload VerifyEmailPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
 
+	user, err := u.EmailVerifier.Verify(payload.Token)
 	if err != nil {
-		log.Printf("Error creating user: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		switch {
+		case errors.Is(err, handlers.ErrEmailAlreadyVerified):
+			c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+		case errors.Is(err, handlers.ErrVerificationInvalid):
+			c.Error(apperror.BadRequest("Invalid or expired verification link"))
+		default:
+			c.Error(err)
+		}
 		return
 	}
 
-	err = handlers.SendMail(user.Email, "welcome", "Welcome Mail", handlers.WelcomeMessage{
-		FullName: fmt.Sprintf("%s %s", *user.FirstName, *user.LastName),
-		Year:     time.Now().UTC().UTC().Year(),
+	u.Auditor.Record(c, model.AuditLog{
+		ActorID:    &user.ID,
+		ActorEmail: user.Email,
+		Action:     model.AuditEmailVerified,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	}, nil, nil)
+
+	err = u.Mailer.Send(c.Request.Context(), user.Email, user.Locale, "welcome", handlers.WelcomeMessage{
+		FullName: user.FullName(),
+		Year:     time.Now().UTC().Year(),
 	})
 	if err != nil {
-		log.Printf("Error sending welcome mail: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return
+		logging.From(c).Error("Error sending welcome mail", "error", err)
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
 }
 
-func (u *UserController) CompleteAccount(c *gin.Context) {
-	userIDStr := c.Param("userId")
-	documentID := c.Query("documentId")
+func (u *UserController) ResendVerification(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	if userDetails.EmailVerified() {
+		c.Error(apperror.BadRequest("Email already verified"))
+		return
+	}
 
-	userID, err := uuid.Parse(userIDStr)
+	wait, err := u.EmailVerifier.RetryAfter(userDetails.ID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(err)
+		return
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.Error(apperror.TooManyRequests("Too many verification emails requested; try again later"))
 		return
 	}
 
-	var userInput struct {
-		FirstName string `json:"firstName"`
-		LastName  string `json:"lastName"`
-		Password  string `json:"password"`
+	if err := u.EmailVerifier.Send(c.Request.Context(), &userDetails); err != nil {
+		if errors.Is(err, handlers.ErrVerificationRateLimited) {
+			c.Error(apperror.TooManyRequests("Too many verification emails requested; try again later"))
+			return
+		}
+		c.Error(fmt.Errorf("error sending verification mail: %w", err))
+		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// CompleteAccount sets the name and password of a user created by accepting
+// an invite. It requires the account completion token emailed at that point.
+func (u *UserController) CompleteAccount(c *gin.Context) {
+	var userInput CompleteAccountPayload
 	if err := c.ShouldBindJSON(&userInput); err != nil {
-		log.Printf("Error binding JSON: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid input"))
 		return
 	}
 
 	ok, err := nameRegex.MatchString(userInput.FirstName)
 	if err != nil || !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid first name"})
+		c.Error(apperror.BadRequest("Invalid first name"))
 		return
 	}
 
 	ok, err = nameRegex.MatchString(userInput.LastName)
 	if err != nil || !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last name"})
+		c.Error(apperror.BadRequest("Invalid last name"))
 		return
 	}
 
 	if !passwordRegex.MatchString(userInput.Password) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password format"})
+		c.Error(apperror.BadRequest("Invalid password format"))
+		return
+	}
+
+	claims, err := u.Sessions.Session.VerifyAccountCompletionToken(userInput.Token)
+	if err != nil {
+		c.Error(apperror.Unauthorized("Invalid or expired account completion link"))
+		return
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		c.Error(apperror.Unauthorized("Invalid or expired account completion link"))
+		return
+	}
+	inviteID, err := uuid.Parse(claims.InviteID)
+	if err != nil {
+		c.Error(apperror.Unauthorized("Invalid or expired account completion link"))
 		return
 	}
 
 	var user model.User
 	if err := u.UserRepository.GetById(&user, userID); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			c.Error(apperror.Unauthorized("Invalid or expired account completion link"))
 			return
 		}
-		log.Printf("Error fetching user: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error fetching user: %w", err))
 		return
 	}
 
-	hasher, err := utils.NewPasswordHasher()
-	if err != nil {
-		log.Printf("Error creating password hasher: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if user.Password != nil {
+		c.Error(apperror.Conflict("Account already set up"))
+		return
+	}
+
+	var invite model.Invite
+	if err := u.InviteRepository.GetOne(inviteID, &invite); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.Unauthorized("Invalid or expired account completion link"))
+			return
+		}
+		c.Error(fmt.Errorf("error fetching invite: %w", err))
 		return
 	}
 
-	hashedPassword, err := hasher.HashPassword(userInput.Password)
+	hashedPassword, err := u.PasswordHasher.HashPassword(userInput.Password)
 	if err != nil {
-		log.Printf("Error hashing password: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(fmt.Errorf("error hashing password: %w", err))
+		return
+	}
+
+	// Consuming the invite first makes the link single-use even if two
+	// requests race.
+	if err := u.InviteRepository.CompleteAccount(inviteID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.Unauthorized("Invalid or expired account completion link"))
+			return
+		}
+		c.Error(err)
 		return
 	}
 
@@ -208,45 +333,29 @@ func (u *UserController) CompleteAccount(c *gin.Context) {
 	user.Password = &hashedPassword
 
 	if err := u.UserRepository.Update(&user, userID); err != nil {
-		log.Printf("Error updating user: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete account setup"})
+		c.Error(fmt.Errorf("error updating user: %w", err))
 		return
 	}
 
-	err = handlers.SendMail(user.Email, "welcome", "Welcome to FileEditor", handlers.WelcomeMessage{
+	err = u.Mailer.Send(c.Request.Context(), user.Email, user.Locale, "welcome", handlers.WelcomeMessage{
 		FullName: fmt.Sprintf("%s %s", userInput.FirstName, userInput.LastName),
-		Year:     time.Now().UTC().UTC().Year(),
+		Year:     time.Now().UTC().Year(),
 	})
 	if err != nil {
-		log.Printf("Error sending welcome email: %s", err)
+		logging.From(c).Error("Error sending welcome email", "error", err)
 	}
 
-	// redirectURL := fmt.Sprintf("%s/get-document/%s", envVars.FE_ROOT_URL, documentID)
-	// c.Redirect(http.StatusFound, redirectURL)
-
-	tokenGenerator, err := jwt.NewSession()
+	tokens, err := u.Sessions.Issue(c, &user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return
-	}
-
-	accessToken, err := tokenGenerator.GenerateAccessToken(user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
-		return
-	}
-
-	refreshToken, err := tokenGenerator.GenerateRefreshToken(user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":      "Account setup complete",
-		"accessToken":  accessToken,
-		"refreshToken": refreshToken,
-		"redirectTo":   fmt.Sprintf("/get-document/%s", documentID),
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"redirectTo":   fmt.Sprintf("/documents/%s", invite.DocumentId),
 	})
 }
 
@@ -254,19 +363,18 @@ func (u *UserController) Login(c *gin.Context) {
 	var payload LoginPayload
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
-	// Validate at least one identifier exists
-	if payload.Email == nil && payload.UserName == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email or username is required"})
+	if payload.Email == nil {
+		c.Error(apperror.BadRequest("Email is required"))
 		return
 	}
 
 	// Validate password meets requirements
 	if len(payload.Password) < 6 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 6 characters"})
+		c.Error(apperror.BadRequest("Password must be at least 6 characters"))
 		return
 	}
 
@@ -274,7 +382,11 @@ func (u *UserController) Login(c *gin.Context) {
 	var err error
 
 	if !emailRegex.MatchString(*payload.Email) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
+		c.Error(apperror.BadRequest("Invalid email format"))
+		return
+	}
+
+	if u.throttled(c, model.ThrottleAccount, *payload.Email) {
 		return
 	}
 
@@ -282,42 +394,135 @@ func (u *UserController) Login(c *gin.Context) {
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid login credentials"})
+			u.Throttle.RecordFailure(c, model.ThrottleAccount, *payload.Email)
+			u.Auditor.Record(c, model.AuditLog{
+				ActorEmail: *payload.Email,
+				Action:     model.AuditLoginFailed,
+				TargetType: "user",
+				TargetID:   *payload.Email,
+			}, nil, gin.H{"reason": "unknown email"})
+			c.Error(apperror.Unauthorized("Invalid credentials"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
 		return
 	}
 
 	// Verify password
-	passwordHasher, err := utils.NewPasswordHasher()
+	matches := false
+	if existingUser.Password != nil {
+		matches, err = u.PasswordHasher.VerifyPassword(*existingUser.Password, payload.Password)
+	}
+	if err != nil || !matches {
+		u.Throttle.RecordFailure(c, model.ThrottleAccount, *payload.Email)
+		u.Auditor.Record(c, model.AuditLog{
+			ActorID:    &existingUser.ID,
+			ActorEmail: existingUser.Email,
+			Action:     model.AuditLoginFailed,
+			TargetType: "user",
+			TargetID:   existingUser.ID.String(),
+		}, nil, gin.H{"reason": "invalid password"})
+		c.Error(apperror.Unauthorized("Invalid credentials"))
+		return
+	}
+
+	u.Throttle.Reset(model.ThrottleAccount, existingUser.Email)
+
+	// Upgrade legacy or outdated hashes now that we have the plaintext.
+	if u.PasswordHasher.NeedsRehash(*existingUser.Password) {
+		rehashed, err := u.PasswordHasher.HashPassword(payload.Password)
+		if err == nil {
+			err = u.UserRepository.UpdatePassword(existingUser.ID, rehashed)
+		}
+		if err != nil {
+			logging.From(c).Error("Error rehashing password", "userId", existingUser.ID, "error", err)
+		}
+	}
+
+	// With 2FA on, the password only earns a challenge token to exchange
+	// with a code at /auth/login/mfa.
+	mfaEnabled, err := u.TwoFactor.Enabled(existingUser.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
+		return
+	}
+	if mfaEnabled {
+		mfaToken, err := u.Sessions.Session.GenerateMFAChallengeToken(existingUser.Email)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate token: %w", err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"mfaRequired": true, "mfaToken": mfaToken})
 		return
 	}
 
-	matches, err := passwordHasher.VerifyPassword(*existingUser.Password, payload.Password)
-	if err != nil || !matches {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	u.completeLogin(c, &existingUser)
+}
+
+func (u *UserController) LoginMFA(c *gin.Context) {
+	var payload struct {
+		MFAToken string `json:"mfaToken" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
-	// Generate tokens
-	tokenGenerator, err := jwt.NewSession()
+	email, err := u.Sessions.Session.VerifyMFAChallengeToken(payload.MFAToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(apperror.Unauthorized("Invalid or expired MFA token"))
 		return
 	}
 
-	accessToken, err := tokenGenerator.GenerateAccessToken(existingUser.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	var existingUser model.User
+	if err := u.UserRepository.GetByEmail(&existingUser, email); err != nil {
+		c.Error(apperror.Unauthorized("Invalid or expired MFA token"))
+		return
+	}
+
+	if err := u.TwoFactor.Verify(existingUser.ID, payload.Code); err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrTwoFactorInvalidCode), errors.Is(err, handlers.ErrTwoFactorLocked):
+			u.Auditor.Record(c, model.AuditLog{
+				ActorID:    &existingUser.ID,
+				ActorEmail: existingUser.Email,
+				Action:     model.AuditLoginFailed,
+				TargetType: "user",
+				TargetID:   existingUser.ID.String(),
+			}, nil, gin.H{"reason": err.Error()})
+			if errors.Is(err, handlers.ErrTwoFactorLocked) {
+				c.Error(apperror.TooManyRequests("Too many invalid codes, try again later"))
+				return
+			}
+			c.Error(apperror.Unauthorized("Invalid code"))
+		case errors.Is(err, handlers.ErrTwoFactorNotEnrolled):
+			c.Error(apperror.Unauthorized("Invalid or expired MFA token"))
+		default:
+			c.Error(err)
+		}
 		return
 	}
 
-	refreshToken, err := tokenGenerator.GenerateRefreshToken(existingUser.Email)
+	u.completeLogin(c, &existingUser)
+}
+
+// completeLogin records a successful login and responds with a new session.
+func (u *UserController) completeLogin(c *gin.Context, existingUser *model.User) {
+	u.Auditor.Record(c, model.AuditLog{
+		ActorID:    &existingUser.ID,
+		ActorEmail: existingUser.Email,
+		Action:     model.AuditLoginSucceeded,
+		TargetType: "user",
+		TargetID:   existingUser.ID.String(),
+	}, nil, nil)
+
+	// Generate tokens
+	tokens, err := u.Sessions.Issue(c, existingUser)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.Error(err)
 		return
 	}
 
@@ -327,65 +532,64 @@ func (u *UserController) Login(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"accessToken":  accessToken,
-		"refreshToken": refreshToken,
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
 		"user": gin.H{
 			"email":        existingUser.Email,
-			"name":         fmt.Sprintf("%s %s", *existingUser.FirstName, *existingUser.LastName),
+			"name":         existingUser.FullName(),
 			"profilePhoto": profilePhoto,
 		},
 	})
-
 }
 
 func (u *UserController) UploadProfilePicture(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
 	// Parse multipart form
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form data"})
+		c.Error(apperror.BadRequest("Invalid form data"))
 		return
 	}
 
 	// Get profile picture file
 	files, exists := form.File["profilePicture"]
 	if !exists || len(files) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "profile picture file is required"})
+		c.Error(apperror.BadRequest("Profile picture file is required"))
 		return
 	}
 
 	fileHeader := files[0]
 	file, err := fileHeader.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open uploaded file"})
+		c.Error(fmt.Errorf("failed to open uploaded file: %w", err))
 		return
 	}
 	defer file.Close()
 
 	// Delete existing photo if exists
 	if userDetails.ProfilePhoto != nil {
-		_, err := repositories.CloudinaryDelete(userDetails.ProfilePhoto.Public_ID, repositories.ImageResource)
+		_, err := repositories.CloudinaryDelete(c.Request.Context(), userDetails.ProfilePhoto.Public_ID, repositories.ImageResource)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete existing profile photo"})
+			c.Error(fmt.Errorf("failed to delete existing profile photo: %w", err))
 			return
 		}
 	}
 
 	// Upload new photo
-	uploaded, err := repositories.CloudinaryUploaderStream(file, fileHeader.Filename, repositories.ImageResource)
+	uploaded, err := repositories.CloudinaryUploaderStream(c.Request.Context(), file, fileHeader.Filename, repositories.ImageResource)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upload profile photo"})
+		c.Error(fmt.Errorf("failed to upload profile photo: %w", err))
 		return
 	}
 
@@ -396,7 +600,7 @@ func (u *UserController) UploadProfilePicture(c *gin.Context) {
 	}
 
 	if err := u.UserRepository.Update(&userDetails, userDetails.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user profile"})
+		c.Error(fmt.Errorf("failed to update user profile: %w", err))
 		return
 	}
 
@@ -407,199 +611,344 @@ func (u *UserController) UploadProfilePicture(c *gin.Context) {
 }
 
 func (u *UserController) ForgotPassword(c *gin.Context) {
-	var user model.User
+	var payload ForgotPasswordPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
 
-	if err := c.ShouldBindJSON(&user); err != nil {
-		log.Printf("Error binding JSON: %s", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !emailRegex.MatchString(payload.Email) {
+		c.Error(apperror.BadRequest("Invalid email format"))
 		return
 	}
 
-	if !emailRegex.MatchString(user.Email) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
+	if u.throttled(c, model.ThrottlePasswordReset, payload.Email) {
 		return
 	}
 
-	var existingUser model.User
-	err := u.UserRepository.GetByEmail(&existingUser, user.Email)
+	// Every request counts towards the reset throttle, so the endpoint cannot
+	// be used to flood an inbox with reset codes. It is kept apart from the
+	// sign-in counter, which would otherwise let anyone lock the account.
+	u.Throttle.RecordFailure(c, model.ThrottlePasswordReset, payload.Email)
 
-	if err != nil {
-		log.Printf("Error:%s", err)
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Email not found"})
+	// The response is the same whether or not the email has an account, so
+	// this endpoint cannot be used to discover registered emails.
+	const message = "If an account exists for this email, a reset code has been sent"
+
+	var existingUser model.User
+	if err := u.UserRepository.GetByEmail(&existingUser, payload.Email); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(fmt.Errorf("error getting user: %w", err))
 			return
 		}
-		log.Printf("Error getting user: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusOK, gin.H{"message": message})
 		return
 	}
 
 	resetCode := utils.NewCodeGenerator().GenerateEmailVerificationCode(6)
 	forgotPassword := &model.ForgotPassword{
+		UserID:    existingUser.ID,
 		Email:     existingUser.Email,
-		ResetCode: resetCode,
+		CodeHash:  utils.HashToken(resetCode),
+		ExpiresAt: time.Now().UTC().Add(resetCodeTTL),
 	}
 
-	if err := u.ForgotPasswordRepository.Create(forgotPassword); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err := u.ForgotPasswordRepository.Reissue(forgotPassword); err != nil {
+		c.Error(err)
 		return
 	}
 
-	err = handlers.SendMail(user.Email, "forgotPassword", "Password Reset Mail", handlers.PasswordResetCode{
-		FullName:  fmt.Sprintf("%s %s", *existingUser.FirstName, *existingUser.LastName),
-		ResetCode: resetCode,
-		Year:      time.Now().UTC().UTC().Year(),
+	// Sent in the background so the response time does not reveal that the
+	// account exists either.
+	ctx, logger := context.WithoutCancel(c.Request.Context()), logging.From(c)
+	u.Mailer.Go(func() {
+		err := u.Mailer.Send(ctx, existingUser.Email, existingUser.Locale, "forgotPassword", handlers.PasswordResetCode{
+			FullName:         existingUser.FullName(),
+			ResetCode:        resetCode,
+			ExpiresInMinutes: int(resetCodeTTL.Minutes()),
+			Year:             time.Now().UTC().Year(),
+		})
+		if err != nil {
+			logger.Error("Error sending forgot password mail", "error", err)
+		}
 	})
-	if err != nil {
-		log.Printf("Error:%s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending forgot password mail"})
-		return
-	}
+
+	u.Auditor.Record(c, model.AuditLog{
+		ActorEmail: existingUser.Email,
+		Action:     model.AuditPasswordResetRequested,
+		TargetType: "user",
+		TargetID:   existingUser.ID.String(),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
 }
 
 func (u *UserController) VerifyResetCode(c *gin.Context) {
 	var payload ResetCodePayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
-	if !codeRegex.MatchString(payload.ResetCode) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+	if !emailRegex.MatchString(payload.Email) || !codeRegex.MatchString(payload.ResetCode) {
+		c.Error(apperror.BadRequest("Invalid or expired reset code"))
+		return
+	}
+
+	if u.throttled(c, model.ThrottlePasswordReset, payload.Email) {
+		return
 	}
 
 	var forgotPassword model.ForgotPassword
-	if err := u.ForgotPasswordRepository.GetOneByCode(payload.ResetCode, &forgotPassword); err != nil {
+	if err := u.ForgotPasswordRepository.GetPendingByEmail(payload.Email, &forgotPassword); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("Error: %s", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reset code"})
+			u.Throttle.RecordFailure(c, model.ThrottlePasswordReset, payload.Email)
+			c.Error(apperror.BadRequest("Invalid or expired reset code"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var user model.User
-	if err := u.UserRepository.GetByEmail(&user, forgotPassword.Email); err != nil {
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(payload.ResetCode)), []byte(forgotPassword.CodeHash)) != 1 {
+		u.Throttle.RecordFailure(c, model.ThrottlePasswordReset, payload.Email)
+		attempts, err := u.ForgotPasswordRepository.RecordFailedAttempt(forgotPassword.ID, maxResetAttempts)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if attempts >= maxResetAttempts {
+			c.Error(apperror.TooManyRequests("Too many incorrect attempts; request a new reset code"))
+			return
+		}
+		c.Error(apperror.BadRequest("Invalid or expired reset code"))
+		return
+	}
+
+	resetToken, err := utils.NewCodeGenerator().GenerateSecureToken(32)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(resetTokenTTL)
+	if err := u.ForgotPasswordRepository.MarkVerified(forgotPassword.ID, utils.HashToken(resetToken), expiresAt); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("Error: %s", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Email does not exists"})
+			c.Error(apperror.BadRequest("Invalid or expired reset code"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+
+	u.Auditor.Record(c, model.AuditLog{
+		ActorID:    &forgotPassword.UserID,
+		ActorEmail: forgotPassword.Email,
+		Action:     model.AuditPasswordResetVerified,
+		TargetType: "user",
+		TargetID:   forgotPassword.UserID.String(),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"resetToken": resetToken, "expiresAt": expiresAt})
+}
+
+func (u *UserController) ResetPassword(c *gin.Context) {
+	var payload ResetPasswordPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
-	if err := u.ForgotPasswordRepository.Delete(&forgotPassword, forgotPassword.ID); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if !passwordRegex.MatchString(payload.NewPassword) {
+		c.Error(apperror.BadRequest("Invalid password format"))
 		return
 	}
 
-	tokenGenerator, err := jwt.NewSession()
-	if err != nil {
-		log.Printf("Error:%s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var forgotPassword model.ForgotPassword
+	if err := u.ForgotPasswordRepository.ConsumeResetToken(utils.HashToken(payload.ResetToken), &forgotPassword); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.BadRequest("Invalid or expired reset token"))
+			return
+		}
+		c.Error(err)
 		return
 	}
 
-	token, err := tokenGenerator.GenerateAccessToken(user.Email)
+	hashedPassword, err := u.PasswordHasher.HashPassword(payload.NewPassword)
 	if err != nil {
-		log.Printf("Error:%s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(fmt.Errorf("error hashing password: %w", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
-}
-
-func (u *UserController) ResetPassword(c *gin.Context) {
-	// Extract the authenticated user from the context
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	if err := u.UserRepository.UpdatePassword(forgotPassword.UserID, hashedPassword); err != nil {
+		c.Error(fmt.Errorf("error updating password: %w", err))
 		return
 	}
 
-	userDetails, ok := user.(model.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type"})
+	// Whoever knew the old password may still hold a session.
+	if err := u.Sessions.LogoutAll(forgotPassword.UserID); err != nil {
+		c.Error(fmt.Errorf("error revoking sessions: %w", err))
 		return
 	}
 
-	var payload struct {
-		NewPassword string `json:"password" binding:"required"`
+	if err := u.ForgotPasswordRepository.InvalidateForUser(forgotPassword.UserID); err != nil {
+		logging.From(c).Error("Error invalidating reset codes", "error", err)
 	}
+	u.Throttle.Reset(model.ThrottleAccount, forgotPassword.Email)
+	u.Throttle.Reset(model.ThrottlePasswordReset, forgotPassword.Email)
+
+	u.Auditor.Record(c, model.AuditLog{
+		ActorID:    &forgotPassword.UserID,
+		ActorEmail: forgotPassword.Email,
+		Action:     model.AuditPasswordReset,
+		TargetType: "user",
+		TargetID:   forgotPassword.UserID.String(),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully; please log in again"})
+}
+
+// UnlockAccount lifts a lockout using the token from the account locked
+// email. Failures counted against the client IP are not affected.
+func (u *UserController) UnlockAccount(c *gin.Context) {
+	var payload UnlockAccountPayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
-	passwordHasher, err := utils.NewPasswordHasher()
-	if err != nil {
-		log.Printf("Error creating password hasher: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err := u.Throttle.Unlock(c, payload.Token); err != nil {
+		if errors.Is(err, handlers.ErrUnlockInvalid) {
+			c.Error(apperror.BadRequest("Invalid or expired unlock link"))
+			return
+		}
+		c.Error(err)
 		return
 	}
 
-	hashedPassword, err := passwordHasher.HashPassword(payload.NewPassword)
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked; you can sign in again"})
+}
+
+// throttled responds with 429 and reports true when the client must wait
+// before another attempt for email. The response is the same for locked
+// accounts, delayed attempts and unknown emails.
+func (u *UserController) throttled(c *gin.Context, kind model.ThrottleKind, email string) bool {
+	wait, err := u.Throttle.RetryAfter(kind, c.ClientIP(), email)
 	if err != nil {
-		log.Printf("Error hashing password: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Error(err)
+		return true
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.Error(apperror.TooManyRequests("Too many failed attempts; try again later"))
+	return true
+}
+
+func (u *UserController) GenerateAccessToken(c *gin.Context) {
+	var payload struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
-	userDetails.Password = &hashedPassword
-	if err := u.UserRepository.Update(&userDetails, userDetails.ID); err != nil {
-		log.Printf("Error updating password: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	tokens, user, err := u.Sessions.Rotate(c, payload.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrRefreshTokenReused):
+			u.Auditor.Record(c, model.AuditLog{
+				ActorID:    &user.ID,
+				ActorEmail: user.Email,
+				Action:     model.AuditRefreshTokenReused,
+				TargetType: "user",
+				TargetID:   user.ID.String(),
+			}, nil, nil)
+			c.Error(apperror.Unauthorized("Refresh token has already been used; please log in again"))
+		case errors.Is(err, handlers.ErrRefreshTokenInvalid):
+			c.Error(apperror.Unauthorized("Invalid or expired refresh token"))
+		default:
+			c.Error(err)
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+	})
 }
 
-func (u *UserController) GenerateAccessToken(c *gin.Context) {
+func (u *UserController) Logout(c *gin.Context) {
 	var payload struct {
 		RefreshToken string `json:"refreshToken" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		log.Printf("Error: %s", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logging.From(c).Warn("Error binding JSON", "error", err)
+		c.Error(apperror.BadRequest("Invalid request payload"))
 		return
 	}
 
-	tokenGenerator, err := jwt.NewSession()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err := u.Sessions.Logout(payload.RefreshToken, nil); err != nil {
+		if errors.Is(err, handlers.ErrRefreshTokenInvalid) {
+			c.Error(apperror.Unauthorized("Invalid or expired refresh token"))
+			return
+		}
+		c.Error(err)
 		return
 	}
 
-	accessToken, err := tokenGenerator.VerifyRefreshToken(payload.RefreshToken)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+func (u *UserController) LogoutAll(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"accessToken": accessToken})
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	if err := u.Sessions.LogoutAll(userDetails.ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	u.Auditor.Record(c, model.AuditLog{
+		ActorID:    &userDetails.ID,
+		ActorEmail: userDetails.Email,
+		Action:     model.AuditLogoutAll,
+		TargetType: "user",
+		TargetID:   userDetails.ID.String(),
+	}, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
 }
 
 func (u *UserController) Profile(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		log.Printf("Error: user not found in context")
-		c.JSON(http.StatusForbidden, gin.H{"error": "invalid session"})
+		c.Error(apperror.Unauthorized("Invalid session"))
 		return
 	}
 
 	userDetails, ok := user.(model.User)
 	if !ok {
-		log.Printf("Error: invalid user type")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type"})
+		c.Error(errInvalidUserType)
 		return
 	}
 
@@ -607,8 +956,7 @@ func (u *UserController) Profile(c *gin.Context) {
 	var freshMember model.User
 	err := u.UserRepository.GetById(&freshMember, userDetails.ID)
 	if err != nil {
-		log.Printf("Error fetching latest user data: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch profile"})
+		c.Error(fmt.Errorf("error fetching latest user data: %w", err))
 		return
 	}
 
@@ -618,11 +966,48 @@ func (u *UserController) Profile(c *gin.Context) {
 		Email:     freshMember.Email,
 
 		ProfilePhoto: freshMember.ProfilePhoto,
+		Locale:       freshMember.Locale,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"user": newDetails})
 }
 
+func (u *UserController) UpdateLocale(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload struct {
+		Locale string `json:"locale" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	locale := handlers.ResolveLocale(payload.Locale)
+	if !strings.HasPrefix(strings.ToLower(payload.Locale), locale) {
+		c.Error(apperror.BadRequest("Unsupported locale").With("supportedLocales", handlers.SupportedLocales()))
+		return
+	}
+
+	userDetails.Locale = locale
+	if err := u.UserRepository.Update(&userDetails, userDetails.ID); err != nil {
+		c.Error(fmt.Errorf("error updating locale: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Locale updated", "locale": locale})
+}
+
 func (u *UserController) VerifyExpiredToken(c *gin.Context) {
 
 }
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookController struct {
+	WebhookRepository         *repositories.WebhookRepository
+	WebhookDeliveryRepository *repositories.WebhookDeliveryRepository
+	DocumentRepository        *repositories.DocumentRepository
+	Publisher                 *handlers.WebhookPublisher
+}
+
+func NewWebhookController(
+	webhookRepository *repositories.WebhookRepository,
+	webhookDeliveryRepository *repositories.WebhookDeliveryRepository,
+	documentRepository *repositories.DocumentRepository,
+	publisher *handlers.WebhookPublisher,
+) *WebhookController {
+	return &WebhookController{
+		WebhookRepository:         webhookRepository,
+		WebhookDeliveryRepository: webhookDeliveryRepository,
+		DocumentRepository:        documentRepository,
+		Publisher:                 publisher,
+	}
+}
+
+type WebhookPayload struct {
+	URL        string               `json:"url"`
+	DocumentID *string              `json:"documentId"`
+	Events     []model.WebhookEvent `json:"events"`
+	Secret     string               `json:"secret"`
+	Active     *bool                `json:"active"`
+}
+
+// validWebhookURL rejects URLs that obviously point at internal hosts. Names
+// that resolve to them are refused by the dispatcher when it connects.
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return utils.IsPublicAddress(addr)
+	}
+	return true
+}
+
+func validWebhookEvents(events []model.WebhookEvent) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for _, event := range events {
+		if !slices.Contains(model.WebhookEvents, event) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *WebhookController) Create(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	var payload WebhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if !validWebhookURL(payload.URL) {
+		c.Error(apperror.BadRequest("URL must be an absolute http(s) URL of a public host"))
+		return
+	}
+
+	if !validWebhookEvents(payload.Events) {
+		c.Error(apperror.BadRequest("Events must be a non-empty list of supported events").With("supportedEvents", model.WebhookEvents))
+		return
+	}
+
+	webhook := model.Webhook{
+		UserID: userDetails.ID,
+		URL:    payload.URL,
+		Events: payload.Events,
+		Active: true,
+	}
+
+	if payload.DocumentID != nil {
+		documentUUID, err := uuid.Parse(*payload.DocumentID)
+		if err != nil {
+			c.Error(apperror.BadRequest("Invalid id"))
+			return
+		}
+
+		var document model.Document
+		if err := w.DocumentRepository.GetOne(documentUUID, &document); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.Error(apperror.NotFound("Document not found"))
+				return
+			}
+			c.Error(fmt.Errorf("error fetching webhook document: %w", err))
+			return
+		}
+
+		if document.UserID != userDetails.ID {
+			c.Error(apperror.Forbidden("Only the document owner can subscribe to its events"))
+			return
+		}
+		webhook.DocumentID = &documentUUID
+	}
+
+	webhook.Secret = payload.Secret
+	if webhook.Secret == "" {
+		secret, err := utils.NewCodeGenerator().GenerateSecureToken(32)
+		if err != nil {
+			c.Error(fmt.Errorf("error generating webhook secret: %w", err))
+			return
+		}
+		webhook.Secret = secret
+	}
+
+	if err := w.WebhookRepository.Create(&webhook); err != nil {
+		c.Error(fmt.Errorf("error creating webhook: %w", err))
+		return
+	}
+
+	// The secret is only ever returned here.
+	c.JSON(http.StatusCreated, gin.H{"message": "Webhook created", "webhook": webhook, "secret": webhook.Secret})
+}
+
+func (w *WebhookController) List(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return
+	}
+
+	webhooks, err := w.WebhookRepository.GetUserWebhooks(userDetails.ID)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching webhooks: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhooks fetched", "webhooks": webhooks})
+}
+
+// userWebhook loads the :id webhook for the session user, writing the error
+// response itself when it returns false.
+func (w *WebhookController) userWebhook(c *gin.Context, webhook *model.Webhook) bool {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Error(apperror.Unauthorized("Invalid session"))
+		return false
+	}
+
+	userDetails, ok := user.(model.User)
+	if !ok {
+		c.Error(errInvalidUserType)
+		return false
+	}
+
+	webhookUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid id"))
+		return false
+	}
+
+	if err := w.WebhookRepository.GetOneForUser(webhookUUID, userDetails.ID, webhook); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(apperror.NotFound("Webhook not found"))
+			return false
+		}
+		c.Error(fmt.Errorf("error fetching webhook: %w", err))
+		return false
+	}
+	return true
+}
+
+func (w *WebhookController) GetOne(c *gin.Context) {
+	var webhook model.Webhook
+	if !w.userWebhook(c, &webhook) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook fetched", "webhook": webhook})
+}
+
+func (w *WebhookController) Update(c *gin.Context) {
+	var webhook model.Webhook
+	if !w.userWebhook(c, &webhook) {
+		return
+	}
+
+	var payload WebhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.Error(apperror.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if payload.URL != "" {
+		if !validWebhookURL(payload.URL) {
+			c.Error(apperror.BadRequest("URL must be an absolute http(s) URL of a public host"))
+			return
+		}
+		webhook.URL = payload.URL
+	}
+
+	if payload.Events != nil {
+		if !validWebhookEvents(payload.Events) {
+			c.Error(apperror.BadRequest("Events must be a non-empty list of supported events").With("supportedEvents", model.WebhookEvents))
+			return
+		}
+		webhook.Events = payload.Events
+	}
+
+	if payload.Active != nil {
+		webhook.Active = *payload.Active
+	}
+
+	if err := w.WebhookRepository.Update(&webhook, webhook.ID); err != nil {
+		c.Error(fmt.Errorf("error updating webhook: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook updated", "webhook": webhook})
+}
+
+func (w *WebhookController) Delete(c *gin.Context) {
+	var webhook model.Webhook
+	if !w.userWebhook(c, &webhook) {
+		return
+	}
+
+	if err := w.WebhookRepository.Delete(webhook.ID); err != nil {
+		c.Error(fmt.Errorf("error deleting webhook: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+func (w *WebhookController) Deliveries(c *gin.Context) {
+	var webhook model.Webhook
+	if !w.userWebhook(c, &webhook) {
+		return
+	}
+
+	deliveries, err := w.WebhookDeliveryRepository.GetByWebhookID(webhook.ID, 50)
+	if err != nil {
+		c.Error(fmt.Errorf("error fetching webhook deliveries: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deliveries fetched", "deliveries": deliveries})
+}
+
+func (w *WebhookController) Ping(c *gin.Context) {
+	var webhook model.Webhook
+	if !w.userWebhook(c, &webhook) {
+		return
+	}
+
+	delivery, err := w.Publisher.Ping(&webhook)
+	if err != nil {
+		c.Error(fmt.Errorf("error queueing webhook ping: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Ping queued", "delivery": delivery})
+}
@@ -0,0 +1,40 @@
+// Package dbtest gives tests a throwaway database. It is an in-memory SQLite
+// database rather than PostgreSQL, so it only suits repositories whose
+// queries are portable.
+package dbtest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var databases atomic.Int64
+
+// Open returns a new empty database with the tables of models, dropped when
+// the test ends.
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:dbtest%d?mode=memory&cache=shared", databases.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Discard,
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Auditor struct {
+	AuditLogRepository *repositories.AuditLogRepository
+}
+
+func NewAuditor(auditLogRepository *repositories.AuditLogRepository) *Auditor {
+	return &Auditor{
+		AuditLogRepository: auditLogRepository,
+	}
+}
+
+// AuditState is a before/after snapshot; any JSON-serialisable value works.
+type AuditState = any
+
+// Record appends entry, filling the request metadata from c. An audit failure
+// is logged but does not undo the action that has already happened.
+func (a *Auditor) Record(c *gin.Context, entry model.AuditLog, before, after AuditState) {
+	if c != nil {
+		entry.IPAddress = c.ClientIP()
+		entry.UserAgent = c.Request.UserAgent()
+	}
+	entry.Before = marshalState(before)
+	entry.After = marshalState(after)
+
+	if err := a.AuditLogRepository.Create(&entry); err != nil {
+		logger := slog.Default()
+		if c != nil {
+			logger = logging.From(c)
+		}
+		logger.Error("Error writing audit log", "action", entry.Action, "error", err)
+	}
+}
+
+func marshalState(state AuditState) []byte {
+	if state == nil {
+		return nil
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		slog.Error("Error encoding audit state", "error", err)
+		return nil
+	}
+	return b
+}
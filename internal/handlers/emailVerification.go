@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+
+	// A user may ask for a new link once a minute and five times an hour.
+	verificationResendInterval = time.Minute
+	verificationHourlyLimit    = 5
+)
+
+var (
+	ErrEmailAlreadyVerified    = errors.New("email is already verified")
+	ErrVerificationInvalid     = errors.New("invalid or expired verification link")
+	ErrVerificationRateLimited = errors.New("too many verification emails requested")
+)
+
+type EmailVerifier struct {
+	EmailVerificationRepository   *repositories.EmailVerificationRepository
+	UserRepository                *repositories.UserRepository
+	RefreshTokenRepository        *repositories.RefreshTokenRepository
+	PersonalAccessTokenRepository *repositories.PersonalAccessTokenRepository
+	Mailer                        *Mailer
+	Config                        *config.Config
+}
+
+func NewEmailVerifier(
+	emailVerificationRepository *repositories.EmailVerificationRepository,
+	userRepository *repositories.UserRepository,
+	refreshTokenRepository *repositories.RefreshTokenRepository,
+	personalAccessTokenRepository *repositories.PersonalAccessTokenRepository,
+	mailer *Mailer,
+	cfg *config.Config,
+) *EmailVerifier {
+	return &EmailVerifier{
+		EmailVerificationRepository:   emailVerificationRepository,
+		UserRepository:                userRepository,
+		RefreshTokenRepository:        refreshTokenRepository,
+		PersonalAccessTokenRepository: personalAccessTokenRepository,
+		Mailer:                        mailer,
+		Config:                        cfg,
+	}
+}
+
+// RetryAfter returns how long the user must wait before another verification
+// email may be sent, or zero if one may be sent now.
+func (e *EmailVerifier) RetryAfter(userId uuid.UUID) (time.Duration, error) {
+	now := time.Now().UTC()
+	sent, err := e.EmailVerificationRepository.GetSentSince(userId, now.Add(-time.Hour))
+	if err != nil {
+		return 0, err
+	}
+	if len(sent) == 0 {
+		return 0, nil
+	}
+
+	var wait time.Duration
+	if since := now.Sub(sent[0].CreatedAt); since < verificationResendInterval {
+		wait = verificationResendInterval - since
+	}
+	if len(sent) >= verificationHourlyLimit {
+		oldest := sent[verificationHourlyLimit-1]
+		wait = max(wait, oldest.CreatedAt.Add(time.Hour).Sub(now))
+	}
+	return wait, nil
+}
+
+// Send emails the user a new verification link. Earlier links stay valid
+// until they expire.
+func (e *EmailVerifier) Send(ctx context.Context, user *model.User) error {
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	wait, err := e.RetryAfter(user.ID)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return ErrVerificationRateLimited
+	}
+
+	token, err := utils.NewCodeGenerator().GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	err = e.EmailVerificationRepository.Create(&model.EmailVerification{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("error storing email verification: %w", err)
+	}
+
+	return e.Mailer.Send(ctx, user.Email, user.Locale, "verifyEmail", EmailVerification{
+		FullName:         user.FullName(),
+		VerificationLink: fmt.Sprintf("%s/verify-email?token=%s", e.Config.Frontend.RootURL, token),
+		ExpiresInHours:   int(emailVerificationTTL.Hours()),
+		Year:             time.Now().UTC().Year(),
+	})
+}
+
+// Verify consumes a verification link and marks the user's email verified.
+func (e *EmailVerifier) Verify(token string) (*model.User, error) {
+	var verification model.EmailVerification
+	if err := e.EmailVerificationRepository.Consume(utils.HashToken(token), &verification); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerificationInvalid
+		}
+		return nil, err
+	}
+
+	var user model.User
+	if err := e.UserRepository.GetById(&user, verification.UserID); err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.EmailVerified() {
+		return &user, ErrEmailAlreadyVerified
+	}
+
+	now := time.Now().UTC()
+	if err := e.UserRepository.MarkEmailVerified(user.ID, now); err != nil {
+		return nil, fmt.Errorf("error verifying email: %w", err)
+	}
+	user.EmailVerifiedAt = &now
+	return &user, nil
+}
+
+// Claim is called when someone proves they own the email of an existing
+// account by other means, such as an invite link or a verified OIDC email.
+// An unverified account may have been registered by someone else, so it is
+// taken over: marked verified, its password cleared and its sessions and
+// personal access tokens revoked.
+func (e *EmailVerifier) Claim(user *model.User) error {
+	if user.EmailVerified() {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	claimed, err := e.UserRepository.ClaimEmail(user.ID, now)
+	if err != nil {
+		return err
+	}
+	if claimed {
+		if err := e.RefreshTokenRepository.RevokeAllForUser(user.ID); err != nil {
+			return err
+		}
+		if err := e.PersonalAccessTokenRepository.RevokeAllForUser(user.ID); err != nil {
+			return fmt.Errorf("error revoking personal access tokens: %w", err)
+		}
+		user.Password = nil
+	}
+	user.EmailVerifiedAt = &now
+	return nil
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Failures are counted per account email and per client IP over a sliding
+// hour. Past the delay threshold each further attempt must wait twice as long
+// as the last, up to maxThrottleDelay; at the lock threshold the key is locked
+// out for throttleLockout. Accounts tolerate fewer failures than IPs, which
+// may be shared by many users behind a NAT.
+const (
+	throttleWindow   = time.Hour
+	throttleLockout  = 30 * time.Minute
+	maxThrottleDelay = 30 * time.Second
+)
+
+type throttlePolicy struct {
+	delayAfter int
+	lockAfter  int
+}
+
+var throttlePolicies = map[model.ThrottleKind]throttlePolicy{
+	model.ThrottleAccount:       {delayAfter: 3, lockAfter: 10},
+	model.ThrottleIP:            {delayAfter: 10, lockAfter: 50},
+	model.ThrottlePasswordReset: {delayAfter: 3, lockAfter: 10},
+}
+
+var ErrUnlockInvalid = errors.New("invalid or expired unlock link")
+
+type LoginThrottler struct {
+	LoginThrottleRepository *repositories.LoginThrottleRepository
+	UserRepository          *repositories.UserRepository
+	Auditor                 *Auditor
+	Mailer                  *Mailer
+	Config                  *config.Config
+}
+
+func NewLoginThrottler(
+	loginThrottleRepository *repositories.LoginThrottleRepository,
+	userRepository *repositories.UserRepository,
+	auditor *Auditor,
+	mailer *Mailer,
+	cfg *config.Config,
+) *LoginThrottler {
+	return &LoginThrottler{
+		LoginThrottleRepository: loginThrottleRepository,
+		UserRepository:          userRepository,
+		Auditor:                 auditor,
+		Mailer:                  mailer,
+		Config:                  cfg,
+	}
+}
+
+// RetryAfter returns how long the client at ip must wait before its next
+// attempt for email, or zero if it may try now. kind is the per-email
+// counter to check: ThrottleAccount for sign-in, ThrottlePasswordReset for
+// password resets.
+func (t *LoginThrottler) RetryAfter(kind model.ThrottleKind, ip, email string) (time.Duration, error) {
+	now := time.Now().UTC()
+	var wait time.Duration
+	for kind, key := range throttleKeys(kind, ip, email) {
+		var throttle model.LoginThrottle
+		if err := t.LoginThrottleRepository.Get(kind, key, &throttle); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return 0, fmt.Errorf("error fetching login throttle: %w", err)
+		}
+		wait = max(wait, throttleWait(&throttle, now))
+	}
+	return wait, nil
+}
+
+// RecordFailure counts a failed attempt for email on the kind counter and
+// for the request's client IP, locking out whichever crosses its threshold.
+// Errors are logged rather than returned so that they never change the
+// response to the attempt.
+func (t *LoginThrottler) RecordFailure(c *gin.Context, kind model.ThrottleKind, email string) {
+	windowStart := time.Now().UTC().Add(-throttleWindow)
+	for kind, key := range throttleKeys(kind, c.ClientIP(), email) {
+		var throttle model.LoginThrottle
+		if err := t.LoginThrottleRepository.RecordFailure(kind, key, windowStart, &throttle); err != nil {
+			logging.From(c).Error("Error recording login failure", "kind", kind, "error", err)
+			continue
+		}
+		if throttle.Failures < throttlePolicies[kind].lockAfter || throttle.Locked(time.Now().UTC()) {
+			continue
+		}
+		if err := t.lock(c, &throttle, email); err != nil {
+			slog.Error("Error locking out", "kind", kind, "error", err)
+		}
+	}
+}
+
+// Reset clears the kind failures of an email after a successful sign-in or
+// password reset. Failures counted against the IP are kept so one valid
+// account cannot be used to launder a credential-stuffing run.
+func (t *LoginThrottler) Reset(kind model.ThrottleKind, email string) {
+	if err := t.LoginThrottleRepository.Reset(kind, normalizeEmail(email)); err != nil {
+		slog.Error("Error resetting login throttle", "kind", kind, "error", err)
+	}
+}
+
+// Unlock lifts an account lockout using the token from the unlock email.
+func (t *LoginThrottler) Unlock(c *gin.Context, token string) error {
+	var throttle model.LoginThrottle
+	if err := t.LoginThrottleRepository.Unlock(utils.HashToken(token), &throttle); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnlockInvalid
+		}
+		return err
+	}
+
+	entry := model.AuditLog{
+		ActorEmail: throttle.Key,
+		Action:     model.AuditAccountUnlocked,
+		TargetType: "user",
+		TargetID:   throttle.Key,
+	}
+	var user model.User
+	if err := t.UserRepository.GetByEmail(&user, throttle.Key); err == nil {
+		entry.ActorID = &user.ID
+		entry.TargetID = user.ID.String()
+	}
+	t.Auditor.Record(c, entry, nil, nil)
+	return nil
+}
+
+func (t *LoginThrottler) lock(c *gin.Context, throttle *model.LoginThrottle, email string) error {
+	lockedUntil := time.Now().UTC().Add(throttleLockout)
+
+	// Only password resets are locked, so there is nothing to unlock early.
+	if throttle.Kind == model.ThrottlePasswordReset {
+		_, err := t.LoginThrottleRepository.Lock(throttle, lockedUntil, nil)
+		return err
+	}
+
+	if throttle.Kind == model.ThrottleIP {
+		locked, err := t.LoginThrottleRepository.Lock(throttle, lockedUntil, nil)
+		if err != nil || !locked {
+			return err
+		}
+		t.Auditor.Record(c, model.AuditLog{
+			Action:     model.AuditIPLockedOut,
+			TargetType: "ip",
+			TargetID:   throttle.Key,
+		}, nil, gin.H{"failures": throttle.Failures, "lockedUntil": lockedUntil})
+		return nil
+	}
+
+	// Unknown emails are locked the same way as real accounts so a lockout
+	// does not reveal which emails are registered; only real accounts are
+	// sent an unlock link.
+	var user *model.User
+	var existing model.User
+	if err := t.UserRepository.GetByEmail(&existing, email); err == nil {
+		user = &existing
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+
+	var token string
+	var tokenHash *string
+	if user != nil {
+		var err error
+		token, err = utils.NewCodeGenerator().GenerateSecureToken(32)
+		if err != nil {
+			return err
+		}
+		hash := utils.HashToken(token)
+		tokenHash = &hash
+	}
+
+	locked, err := t.LoginThrottleRepository.Lock(throttle, lockedUntil, tokenHash)
+	if err != nil || !locked {
+		return err
+	}
+
+	entry := model.AuditLog{
+		ActorEmail: throttle.Key,
+		Action:     model.AuditAccountLockedOut,
+		TargetType: "user",
+		TargetID:   throttle.Key,
+	}
+	if user != nil {
+		entry.ActorID = &user.ID
+		entry.TargetID = user.ID.String()
+	}
+	t.Auditor.Record(c, entry, nil, gin.H{"failures": throttle.Failures, "lockedUntil": lockedUntil})
+
+	if user == nil {
+		return nil
+	}
+
+	// Sent in the background so the response time does not reveal that the
+	// account exists.
+	ctx, logger := context.WithoutCancel(c.Request.Context()), logging.From(c)
+	t.Mailer.Go(func() {
+		if err := t.sendUnlockMail(ctx, user, token); err != nil {
+			logger.Error("Error sending account locked mail", "error", err)
+		}
+	})
+	return nil
+}
+
+func (t *LoginThrottler) sendUnlockMail(ctx context.Context, user *model.User, token string) error {
+	return t.Mailer.Send(ctx, user.Email, user.Locale, "accountLocked", AccountLocked{
+		FullName:      user.FullName(),
+		UnlockLink:    fmt.Sprintf("%s/unlock-account?token=%s", t.Config.Frontend.RootURL, token),
+		LockedMinutes: int(throttleLockout.Minutes()),
+		Year:          time.Now().UTC().Year(),
+	})
+}
+
+// throttleWait returns how long the key must wait at now: the rest of an
+// active lockout, or the progressive delay since its last failure.
+func throttleWait(throttle *model.LoginThrottle, now time.Time) time.Duration {
+	if throttle.Locked(now) {
+		return throttle.LockedUntil.Sub(now)
+	}
+	if throttle.LockedUntil != nil || throttle.LastFailureAt.Before(now.Add(-throttleWindow)) {
+		return 0
+	}
+
+	policy := throttlePolicies[throttle.Kind]
+	if throttle.Failures < policy.delayAfter {
+		return 0
+	}
+	delay := maxThrottleDelay
+	if shift := throttle.Failures - policy.delayAfter; shift < 5 {
+		delay = min(time.Second<<shift, maxThrottleDelay)
+	}
+	return max(throttle.LastFailureAt.Add(delay).Sub(now), 0)
+}
+
+// throttleKeys returns the counters an attempt is checked against: the IP
+// and the kind counter of email. Emails are compared case-insensitively; an
+// empty email only checks the IP.
+func throttleKeys(kind model.ThrottleKind, ip, email string) map[model.ThrottleKind]string {
+	keys := map[model.ThrottleKind]string{model.ThrottleIP: ip}
+	if email = normalizeEmail(email); email != "" {
+		keys[kind] = email
+	}
+	return keys
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -2,123 +2,289 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
-	"realTimeEditor/pkg/constants"
-
+	"errors"
 	"fmt"
+	"html"
 	"html/template"
-	"log"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
 	"net/smtp"
-	"path/filepath"
+	"net/textproto"
+	"path"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/metrics"
+	"realTimeEditor/internal/tracing"
+	"realTimeEditor/pkg/utils"
+	"realTimeEditor/templates"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// func LoadEnvironment() (*MailerVars, error) {
-// 	env, err := constants.LoadEnv()
-// 	if err != nil {
-// 		return nil, fmt.Errorf("error loading environment variables: %s", err)
-// 	}
-// 	return &MailerVars{
-// 		SMTP_HOST: env.SMTP_HOST,
-// 		SMTP_PORT: env.SMTP_PORT,
-// 		SMTP_USER: env.SMTP_USER,
-// 		SMTP_PASS: env.SMTP_PASS,
-// 	}, nil
-// }
-
-func ParseTemplate[T any](fileName string, data T) (string, error) {
-	// Automatically look in templates directory
-	templatePath := filepath.Join("templates", fileName+".html")
-
-	tpl, err := template.ParseFiles(templatePath)
+// RenderedMail is the output of rendering an email template for one locale.
+type RenderedMail struct {
+	Locale  string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// SupportedLocales lists the locales that have a template set in templates.FS.
+func SupportedLocales() []string {
+	entries, err := fs.ReadDir(templates.FS, ".")
 	if err != nil {
-		log.Printf("Failed to parse template %s: %v", fileName, err)
-		return "", fmt.Errorf("template parsing error: %w", err)
+		return []string{templates.DefaultLocale}
 	}
 
-	var body bytes.Buffer
-	if err := tpl.Execute(&body, data); err != nil {
-		log.Printf("Failed to execute template %s: %v", fileName, err)
-		return "", fmt.Errorf("template execution error: %w", err)
+	var locales []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			locales = append(locales, entry.Name())
+		}
+	}
+	return locales
+}
+
+// ResolveLocale maps a user or Accept-Language style locale ("fr-CA", "fr_FR")
+// onto a supported template set, falling back to the default locale.
+func ResolveLocale(locale string) string {
+	parts := strings.FieldsFunc(strings.ToLower(strings.TrimSpace(locale)), func(r rune) bool {
+		return r == '-' || r == '_' || r == ',' || r == ';'
+	})
+	if len(parts) == 0 {
+		return templates.DefaultLocale
+	}
+
+	base := parts[0]
+	for _, supported := range SupportedLocales() {
+		if supported == base {
+			return supported
+		}
 	}
-	return body.String(), nil
+	return templates.DefaultLocale
 }
 
-func SendMail(to, templatePath, subject string, data any) error {
-	// Load config
-	config, err := constants.LoadEnv()
+func ParseTemplate[T any](fileName, locale string, data T) (*RenderedMail, error) {
+	locale = ResolveLocale(locale)
+
+	tpl, err := template.ParseFS(
+		templates.FS,
+		"layout.html",
+		path.Join(locale, "common.html"),
+		path.Join(locale, fileName+".html"),
+	)
 	if err != nil {
-		log.Println(err)
-		return fmt.Errorf("config load failed: %w", err)
+		slog.Error("Failed to parse email template", "locale", locale, "template", fileName, "error", err)
+		return nil, fmt.Errorf("template parsing error: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tpl.ExecuteTemplate(&body, "layout", data); err != nil {
+		slog.Error("Failed to execute email template", "locale", locale, "template", fileName, "error", err)
+		return nil, fmt.Errorf("template execution error: %w", err)
+	}
+
+	var subject bytes.Buffer
+	if err := tpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		slog.Error("Failed to execute email subject", "locale", locale, "template", fileName, "error", err)
+		return nil, fmt.Errorf("template execution error: %w", err)
+	}
+
+	text, err := HTMLToText(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("plain text conversion error: %w", err)
+	}
+
+	return &RenderedMail{
+		Locale:  locale,
+		Subject: strings.TrimSpace(html.UnescapeString(subject.String())),
+		HTML:    body.String(),
+		Text:    text,
+	}, nil
+}
+
+// BuildMessage assembles a multipart/alternative message with the plain text
+// part first so clients that prefer HTML pick the last one.
+func BuildMessage(from, to string, mail *RenderedMail) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("UTF-8", mail.Subject),
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z),
+		"Content-Language: " + mail.Locale,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=UTF-8", mail.Text},
+		{"text/html; charset=UTF-8", mail.HTML},
 	}
+	for _, p := range parts {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(part, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
 
-	// Validate config
-	if config.SMTP_HOST == "" || config.SMTP_PORT == "" || config.SMTP_USER == "" || config.SMTP_PASS == "" {
-		return fmt.Errorf("incomplete SMTP configuration")
+var ErrMailDisabled = errors.New("email is not configured")
+
+// Mailer sends templated emails over SMTP with implicit TLS.
+type Mailer struct {
+	SMTP       *config.SMTPConfig
+	background sync.WaitGroup
+}
+
+// NewMailer returns a mailer for smtpConfig; when it is nil every send fails
+// with ErrMailDisabled.
+func NewMailer(smtpConfig *config.SMTPConfig) *Mailer {
+	return &Mailer{
+		SMTP: smtpConfig,
 	}
+}
+
+// Send renders templateName in locale with data and emails it to to. ctx
+// only carries the trace; connecting and the SMTP exchange after it are each
+// bounded by the configured timeout.
+func (m *Mailer) Send(ctx context.Context, to, locale, templateName string, data any) error {
+	_, span := tracing.Start(ctx, "smtp.send", attribute.String("email.template", templateName))
+	err := m.send(to, locale, templateName, data)
+	result := "sent"
+	if errors.Is(err, ErrMailDisabled) {
+		result = "disabled"
+		span.SetAttributes(attribute.Bool("email.disabled", true))
+		tracing.End(span, nil)
+	} else {
+		if err != nil {
+			result = "failed"
+		}
+		tracing.End(span, err)
+	}
+	metrics.EmailsSent.WithLabelValues(templateName, result).Inc()
+	return err
+}
 
-	body, err := ParseTemplate(templatePath, data)
+// Go runs send in the background, for emails that must not delay or shape
+// the response. Wait waits for them on shutdown.
+func (m *Mailer) Go(send func()) {
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+		send()
+	}()
+}
+
+// Wait waits for the emails started with Go, or until ctx is done.
+func (m *Mailer) Wait(ctx context.Context) error {
+	return utils.WaitContext(ctx, &m.background)
+}
+
+func (m *Mailer) send(to, locale, templateName string, data any) error {
+	if m.SMTP == nil {
+		return ErrMailDisabled
+	}
+	smtpConfig := m.SMTP
+	port := strconv.Itoa(smtpConfig.Port)
+
+	mail, err := ParseTemplate(templateName, locale, data)
 	if err != nil {
-		log.Println(err)
 		return fmt.Errorf("template processing failed: %w", err)
 	}
 
-	// Message construction
-	msg := fmt.Sprintf(
-		"From: %s\r\n"+
-			"To: %s\r\n"+
-			"Subject:"+subject+"\r\n"+
-			"MIME-Version: 1.0\r\n"+
-			"Content-Type: text/html; charset=UTF-8\r\n\r\n"+
-			"%s",
-		config.SMTP_USER,
-		to,
-		body,
-	)
+	msg, err := BuildMessage(smtpConfig.User, to, mail)
+	if err != nil {
+		return fmt.Errorf("message construction failed: %w", err)
+	}
 
 	// TLS config
 	tlsConfig := &tls.Config{
-		ServerName: config.SMTP_HOST,
+		ServerName: smtpConfig.Host,
 	}
 
 	// Connect to SMTP server
-	conn, err := tls.Dial("tcp", config.SMTP_HOST+":"+config.SMTP_PORT, tlsConfig)
+	dialer := &net.Dialer{Timeout: smtpConfig.Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(smtpConfig.Host, port), tlsConfig)
 	if err != nil {
-		log.Println(err)
 		return fmt.Errorf("TLS connection failed: %w", err)
 	}
 	defer conn.Close()
+	// The dialer only bounds connecting; a server that stalls later would
+	// otherwise block the exchange, and shutdown, indefinitely.
+	if err := conn.SetDeadline(time.Now().Add(smtpConfig.Timeout)); err != nil {
+		return fmt.Errorf("setting SMTP deadline failed: %w", err)
+	}
 
 	// Create SMTP client
-	client, err := smtp.NewClient(conn, config.SMTP_HOST)
+	client, err := smtp.NewClient(conn, smtpConfig.Host)
 	if err != nil {
-		log.Println(err)
 		return fmt.Errorf("SMTP client creation failed: %w", err)
 	}
 	defer client.Close()
-	auth := smtp.PlainAuth("", config.SMTP_USER, config.SMTP_PASS, config.SMTP_HOST)
+	auth := smtp.PlainAuth("", smtpConfig.User, smtpConfig.Password.Value(), smtpConfig.Host)
 	if err := client.Auth(auth); err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
-	if err := client.Mail(config.SMTP_USER); err != nil {
-		log.Println(err)
+	if err := client.Mail(smtpConfig.User); err != nil {
 		return fmt.Errorf("sender set failed: %w", err)
 	}
 	if err := client.Rcpt(to); err != nil {
-		log.Println(err)
 		return fmt.Errorf("recipient set failed: %w", err)
 	}
 	w, err := client.Data()
 	if err != nil {
-		log.Println(err)
 		return fmt.Errorf("data command failed: %w", err)
 	}
-	defer w.Close()
-
-	if _, err := w.Write([]byte(msg)); err != nil {
-		log.Println(err)
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
 		return fmt.Errorf("message write failed: %w", err)
 	}
+	// The server accepts or rejects the message in its reply to the end of
+	// the data, which Close reads.
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+	// The message is accepted at this point; a failed QUIT does not undo it.
+	if err := client.Quit(); err != nil {
+		slog.Warn("SMTP quit failed", "error", err)
+	}
 
-	log.Printf("Email successfully sent to %s", to)
+	slog.Info("Email sent", "template", templateName)
 	return nil
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"strings"
+	"time"
+)
+
+// Broadcaster is the part of the socket server the notifier needs. It is
+// satisfied by *socketio.Server.
+type Broadcaster interface {
+	BroadcastToRoom(namespace, room, event string, args ...interface{}) bool
+}
+
+const SocketNamespace = "/ws"
+
+// UserRoom is the socket room every authenticated connection of a user joins.
+func UserRoom(userId string) string {
+	return "user:" + userId
+}
+
+type Notifier struct {
+	NotificationRepository           *repositories.NotificationRepository
+	NotificationPreferenceRepository *repositories.NotificationPreferenceRepository
+	Mailer                           *Mailer
+	Config                           *config.Config
+	broadcaster                      Broadcaster
+}
+
+func NewNotifier(
+	notificationRepository *repositories.NotificationRepository,
+	notificationPreferenceRepository *repositories.NotificationPreferenceRepository,
+	broadcaster Broadcaster,
+	mailer *Mailer,
+	cfg *config.Config,
+) *Notifier {
+	return &Notifier{
+		NotificationRepository:           notificationRepository,
+		NotificationPreferenceRepository: notificationPreferenceRepository,
+		Mailer:                           mailer,
+		Config:                           cfg,
+		broadcaster:                      broadcaster,
+	}
+}
+
+// Notify delivers a notification to recipient over the channels they have
+// enabled for its event. sendEmail overrides the generic notification email,
+// e.g. to send the invite template instead.
+func (n *Notifier) Notify(ctx context.Context, recipient *model.User, notification *model.Notification, sendEmail func() error) error {
+	pref, err := n.NotificationPreferenceRepository.GetOne(recipient.ID, notification.Event)
+	if err != nil {
+		return fmt.Errorf("error loading notification preference: %w", err)
+	}
+
+	notification.UserID = recipient.ID
+
+	if pref.InApp {
+		if err := n.NotificationRepository.Create(notification); err != nil {
+			return fmt.Errorf("error storing notification: %w", err)
+		}
+		n.push(ctx, notification)
+	}
+
+	if pref.Email {
+		if sendEmail == nil {
+			sendEmail = func() error {
+				link := notification.Link
+				if strings.HasPrefix(link, "/") {
+					link = n.Config.Frontend.RootURL + link
+				}
+				return n.Mailer.Send(ctx, recipient.Email, recipient.Locale, "notification", NotificationMessage{
+					FullName: recipient.FullName(),
+					Title:    notification.Title,
+					Body:     notification.Body,
+					Link:     link,
+					Year:     time.Now().UTC().Year(),
+				})
+			}
+		}
+		if err := sendEmail(); err != nil {
+			return fmt.Errorf("error emailing notification: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (n *Notifier) push(ctx context.Context, notification *model.Notification) {
+	if n.broadcaster == nil {
+		return
+	}
+
+	unread, err := n.NotificationRepository.CountUnread(notification.UserID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error counting unread notifications", "error", err)
+	}
+
+	n.broadcaster.BroadcastToRoom(SocketNamespace, UserRoom(notification.UserID.String()), "notification", map[string]any{
+		"notification": notification,
+		"unreadCount":  unread,
+	})
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/utils"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const oidcAuthRequestTTL = 10 * time.Minute
+
+var (
+	ErrOIDCUnknownProvider  = errors.New("unknown OIDC provider")
+	ErrOIDCInvalidState     = errors.New("invalid or expired OIDC state")
+	ErrOIDCEmailNotVerified = errors.New("the provider did not return a verified email")
+	ErrOIDCNoAccount        = errors.New("no account or pending invite for this email")
+	ErrOIDCIdentityInUse    = errors.New("identity is linked to another account")
+	ErrOIDCAlreadyLinked    = errors.New("a different identity from this provider is already linked")
+)
+
+// OIDCClaims are the ID token claims used to find or create a user.
+type OIDCClaims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Locale        string `json:"locale"`
+}
+
+// OIDCResult is the outcome of a completed authorization code flow. User is
+// the signed in user, or for a link flow the user the identity was added to.
+type OIDCResult struct {
+	Provider    string
+	User        *model.User
+	LinkFlow    bool
+	NewIdentity bool
+	Provisioned bool
+}
+
+// oidcProvider discovers its issuer lazily so an unreachable provider does
+// not stop the server from booting.
+type oidcProvider struct {
+	config   config.OIDCProvider
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func (p *oidcProvider) init(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return nil
+	}
+
+	// The provider keeps this context for later key set refreshes, so it must
+	// outlive the request that happened to trigger discovery.
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), p.config.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("error discovering OIDC provider %s: %w", p.config.Name, err)
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret.Value(),
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       p.config.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+	return nil
+}
+
+type OIDCService struct {
+	providers                 map[string]*oidcProvider
+	OIDCAuthRequestRepository *repositories.OIDCAuthRequestRepository
+	UserIdentityRepository    *repositories.UserIdentityRepository
+	UserRepository            *repositories.UserRepository
+	InviteRepository          *repositories.InviteRepository
+	EmailVerifier             *EmailVerifier
+}
+
+func NewOIDCService(
+	configs []config.OIDCProvider,
+	oidcAuthRequestRepository *repositories.OIDCAuthRequestRepository,
+	userIdentityRepository *repositories.UserIdentityRepository,
+	userRepository *repositories.UserRepository,
+	inviteRepository *repositories.InviteRepository,
+	emailVerifier *EmailVerifier,
+) *OIDCService {
+	providers := make(map[string]*oidcProvider, len(configs))
+	for _, cfg := range configs {
+		providers[cfg.Name] = &oidcProvider{config: cfg}
+	}
+	return &OIDCService{
+		providers:                 providers,
+		OIDCAuthRequestRepository: oidcAuthRequestRepository,
+		UserIdentityRepository:    userIdentityRepository,
+		UserRepository:            userRepository,
+		InviteRepository:          inviteRepository,
+		EmailVerifier:             emailVerifier,
+	}
+}
+
+func (o *OIDCService) Providers() []string {
+	names := make([]string, 0, len(o.providers))
+	for name := range o.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Begin starts an authorization code flow with PKCE and returns the URL to
+// send the browser to. linkUserId is set when an already signed in user is
+// adding this provider to their account.
+func (o *OIDCService) Begin(ctx context.Context, providerName string, linkUserId *uuid.UUID) (string, error) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return "", ErrOIDCUnknownProvider
+	}
+	if err := provider.init(ctx); err != nil {
+		return "", err
+	}
+
+	codeGenerator := utils.NewCodeGenerator()
+	state, err := codeGenerator.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := codeGenerator.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	err = o.OIDCAuthRequestRepository.Create(&model.OIDCAuthRequest{
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserId,
+		ExpiresAt:    time.Now().UTC().Add(oidcAuthRequestTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error storing OIDC auth request: %w", err)
+	}
+
+	return provider.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Complete handles the provider's callback: it exchanges the code, verifies
+// the ID token and then links, finds or provisions the user.
+func (o *OIDCService) Complete(ctx context.Context, providerName, state, code string) (*OIDCResult, error) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return nil, ErrOIDCUnknownProvider
+	}
+
+	var request model.OIDCAuthRequest
+	if err := o.OIDCAuthRequestRepository.Consume(utils.HashToken(state), &request); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOIDCInvalidState
+		}
+		return nil, err
+	}
+	if request.Provider != providerName {
+		return nil, ErrOIDCInvalidState
+	}
+
+	if err := provider.init(ctx); err != nil {
+		return nil, err
+	}
+
+	token, err := provider.oauth.Exchange(ctx, code, oauth2.VerifierOption(request.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging OIDC code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("OIDC token response has no id_token")
+	}
+	idToken, err := provider.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying ID token: %w", err)
+	}
+	if idToken.Nonce != request.Nonce {
+		return nil, fmt.Errorf("ID token nonce mismatch")
+	}
+
+	var claims OIDCClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("error decoding ID token claims: %w", err)
+	}
+	claims.Subject = idToken.Subject
+
+	if request.LinkUserID != nil {
+		return o.link(providerName, *request.LinkUserID, &claims)
+	}
+	return o.signIn(providerName, &claims)
+}
+
+func (o *OIDCService) link(providerName string, userId uuid.UUID, claims *OIDCClaims) (*OIDCResult, error) {
+	var user model.User
+	if err := o.UserRepository.GetById(&user, userId); err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	var existing model.UserIdentity
+	err := o.UserIdentityRepository.GetByProviderSubject(providerName, claims.Subject, &existing)
+	if err == nil {
+		if existing.UserID != userId {
+			return nil, ErrOIDCIdentityInUse
+		}
+		return &OIDCResult{Provider: providerName, User: &user, LinkFlow: true}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error fetching identity: %w", err)
+	}
+
+	if err := o.createIdentity(providerName, userId, claims); err != nil {
+		return nil, err
+	}
+	return &OIDCResult{Provider: providerName, User: &user, LinkFlow: true, NewIdentity: true}, nil
+}
+
+func (o *OIDCService) signIn(providerName string, claims *OIDCClaims) (*OIDCResult, error) {
+	var identity model.UserIdentity
+	err := o.UserIdentityRepository.GetByProviderSubject(providerName, claims.Subject, &identity)
+	if err == nil {
+		if err := o.UserIdentityRepository.TouchLogin(identity.ID); err != nil {
+			return nil, fmt.Errorf("error updating identity: %w", err)
+		}
+		return &OIDCResult{Provider: providerName, User: &identity.User}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error fetching identity: %w", err)
+	}
+
+	// First sign in with this identity: only a verified email may be trusted
+	// to match an existing account or an invite.
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	result := &OIDCResult{Provider: providerName, NewIdentity: true}
+
+	var user model.User
+	err = o.UserRepository.GetByEmail(&user, claims.Email)
+	switch {
+	case err == nil:
+		if err := o.EmailVerifier.Claim(&user); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		invited, err := o.InviteRepository.HasPendingInvite(claims.Email)
+		if err != nil {
+			return nil, err
+		}
+		if !invited {
+			return nil, ErrOIDCNoAccount
+		}
+
+		verifiedAt := time.Now().UTC()
+		user = model.User{
+			Email:           claims.Email,
+			Locale:          ResolveLocale(claims.Locale),
+			EmailVerifiedAt: &verifiedAt,
+		}
+		if claims.GivenName != "" {
+			user.FirstName = &claims.GivenName
+		}
+		if claims.FamilyName != "" {
+			user.LastName = &claims.FamilyName
+		}
+		if _, err := o.UserRepository.Create(&user); err != nil {
+			return nil, fmt.Errorf("error provisioning user: %w", err)
+		}
+		result.Provisioned = true
+	default:
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	if err := o.createIdentity(providerName, user.ID, claims); err != nil {
+		return nil, err
+	}
+	result.User = &user
+	return result, nil
+}
+
+func (o *OIDCService) createIdentity(providerName string, userId uuid.UUID, claims *OIDCClaims) error {
+	now := time.Now().UTC()
+	err := o.UserIdentityRepository.Create(&model.UserIdentity{
+		UserID:      userId,
+		Provider:    providerName,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrOIDCAlreadyLinked
+	}
+	if err != nil {
+		return fmt.Errorf("error linking identity: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/dbtest"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/oidctest"
+	"realTimeEditor/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const testProvider = "stub"
+
+type oidcFixture struct {
+	service  *OIDCService
+	provider *oidctest.Provider
+	db       *gorm.DB
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	db := dbtest.Open(t,
+		&model.User{},
+		&model.UserIdentity{},
+		&model.OIDCAuthRequest{},
+		&model.Invite{},
+		&model.RefreshToken{},
+		&model.PersonalAccessToken{},
+	)
+	provider := oidctest.NewProvider(t, "client")
+
+	userRepository := repositories.NewUserRepository(db)
+	service := NewOIDCService(
+		[]config.OIDCProvider{{
+			Name:         testProvider,
+			IssuerURL:    provider.URL,
+			ClientID:     provider.ClientID,
+			ClientSecret: "secret",
+			Scopes:       []string{"openid", "email", "profile"},
+			RedirectURL:  "http://localhost/auth/oidc/stub/callback",
+		}},
+		repositories.NewOIDCAuthRequestRepository(db),
+		repositories.NewUserIdentityRepository(db),
+		userRepository,
+		repositories.NewInviteRepository(db),
+		NewEmailVerifier(nil, userRepository, repositories.NewRefreshTokenRepository(db), repositories.NewPersonalAccessTokenRepository(db), nil, nil),
+	)
+	return &oidcFixture{service: service, provider: provider, db: db}
+}
+
+// login runs a flow up to the callback and returns its state and code.
+func (f *oidcFixture) login(t *testing.T, linkUserId *uuid.UUID, claims oidctest.Claims) (string, string) {
+	t.Helper()
+	authURL, err := f.service.Begin(context.Background(), testProvider, linkUserId)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	return f.provider.Authorize(authURL, claims)
+}
+
+func (f *oidcFixture) createUser(t *testing.T, email string, verified bool) model.User {
+	t.Helper()
+	password := "hash"
+	user := model.User{Email: email, Password: &password, Locale: "en"}
+	if verified {
+		now := time.Now().UTC()
+		user.EmailVerifiedAt = &now
+	}
+	if err := f.db.Create(&user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	return user
+}
+
+func TestOIDCSignInExistingUser(t *testing.T) {
+	f := newOIDCFixture(t)
+	user := f.createUser(t, "ada@example.com", true)
+
+	state, code := f.login(t, nil, oidctest.Claims{Subject: "sub-1", Email: user.Email, EmailVerified: true})
+	result, err := f.service.Complete(context.Background(), testProvider, state, code)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if result.User.ID != user.ID || !result.NewIdentity || result.Provisioned || result.LinkFlow {
+		t.Fatalf("result = %+v, want a new identity for user %s", result, user.ID)
+	}
+
+	// The state is single use.
+	if _, err := f.service.Complete(context.Background(), testProvider, state, code); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("reusing state: err = %v, want ErrOIDCInvalidState", err)
+	}
+
+	// The identity now signs in by subject, whatever email it reports.
+	state, code = f.login(t, nil, oidctest.Claims{Subject: "sub-1", Email: "other@example.com"})
+	result, err = f.service.Complete(context.Background(), testProvider, state, code)
+	if err != nil {
+		t.Fatalf("Complete again: %v", err)
+	}
+	if result.User.ID != user.ID || result.NewIdentity {
+		t.Fatalf("result = %+v, want the linked user without a new identity", result)
+	}
+}
+
+func TestOIDCStateAndPKCE(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.createUser(t, "ada@example.com", true)
+	claims := oidctest.Claims{Subject: "sub-1", Email: "ada@example.com", EmailVerified: true}
+
+	t.Run("unknown state", func(t *testing.T) {
+		_, code := f.login(t, nil, claims)
+		if _, err := f.service.Complete(context.Background(), testProvider, "forged", code); !errors.Is(err, ErrOIDCInvalidState) {
+			t.Fatalf("err = %v, want ErrOIDCInvalidState", err)
+		}
+	})
+
+	t.Run("other provider", func(t *testing.T) {
+		state, code := f.login(t, nil, claims)
+		if _, err := f.service.Complete(context.Background(), "other", state, code); !errors.Is(err, ErrOIDCUnknownProvider) {
+			t.Fatalf("err = %v, want ErrOIDCUnknownProvider", err)
+		}
+	})
+
+	t.Run("code of another flow", func(t *testing.T) {
+		// The code was issued for the first flow's PKCE challenge, so the
+		// second flow's verifier does not redeem it.
+		_, code := f.login(t, nil, claims)
+		state, _ := f.login(t, nil, claims)
+		_, err := f.service.Complete(context.Background(), testProvider, state, code)
+		if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+			t.Fatalf("err = %v, want invalid_grant from the token endpoint", err)
+		}
+	})
+
+	t.Run("expired state", func(t *testing.T) {
+		state, code := f.login(t, nil, claims)
+		if err := f.db.Model(&model.OIDCAuthRequest{}).Where("1 = 1").
+			Update("expires_at", time.Now().UTC().Add(-time.Minute)).Error; err != nil {
+			t.Fatalf("expiring request: %v", err)
+		}
+		if _, err := f.service.Complete(context.Background(), testProvider, state, code); !errors.Is(err, ErrOIDCInvalidState) {
+			t.Fatalf("err = %v, want ErrOIDCInvalidState", err)
+		}
+	})
+}
+
+func TestOIDCNonceMismatch(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.createUser(t, "ada@example.com", true)
+
+	state, code := f.login(t, nil, oidctest.Claims{
+		Subject:       "sub-1",
+		Email:         "ada@example.com",
+		EmailVerified: true,
+		Nonce:         "replayed",
+	})
+	_, err := f.service.Complete(context.Background(), testProvider, state, code)
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("err = %v, want a nonce mismatch", err)
+	}
+
+	var count int64
+	f.db.Model(&model.UserIdentity{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("%d identities linked, want none", count)
+	}
+}
+
+func TestOIDCUnverifiedEmail(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.createUser(t, "ada@example.com", true)
+
+	state, code := f.login(t, nil, oidctest.Claims{Subject: "sub-1", Email: "ada@example.com", EmailVerified: false})
+	if _, err := f.service.Complete(context.Background(), testProvider, state, code); !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Fatalf("err = %v, want ErrOIDCEmailNotVerified", err)
+	}
+}
+
+func TestOIDCClaimsUnverifiedAccount(t *testing.T) {
+	f := newOIDCFixture(t)
+	user := f.createUser(t, "ada@example.com", false)
+	token := model.PersonalAccessToken{UserID: user.ID, Name: "squatter", Prefix: "pat", TokenHash: "hash"}
+	if err := f.db.Create(&token).Error; err != nil {
+		t.Fatalf("creating token: %v", err)
+	}
+
+	state, code := f.login(t, nil, oidctest.Claims{Subject: "sub-1", Email: user.Email, EmailVerified: true})
+	result, err := f.service.Complete(context.Background(), testProvider, state, code)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if result.User.ID != user.ID {
+		t.Fatalf("signed in as %s, want %s", result.User.ID, user.ID)
+	}
+
+	// Whoever registered the address without verifying it loses the
+	// password they set and the tokens they created.
+	var stored model.User
+	if err := f.db.First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatalf("fetching user: %v", err)
+	}
+	if !stored.EmailVerified() || stored.Password != nil {
+		t.Fatalf("user = %+v, want verified without a password", stored)
+	}
+	if err := f.db.First(&token, "id = ?", token.ID).Error; err != nil {
+		t.Fatalf("fetching token: %v", err)
+	}
+	if token.RevokedAt == nil {
+		t.Fatal("personal access token was not revoked")
+	}
+}
+
+func TestOIDCNoAccount(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	state, code := f.login(t, nil, oidctest.Claims{Subject: "sub-1", Email: "ada@example.com", EmailVerified: true})
+	if _, err := f.service.Complete(context.Background(), testProvider, state, code); !errors.Is(err, ErrOIDCNoAccount) {
+		t.Fatalf("err = %v, want ErrOIDCNoAccount", err)
+	}
+}
+
+func TestOIDCProvisionsInvitedUser(t *testing.T) {
+	f := newOIDCFixture(t)
+	email := "ada@example.com"
+	invite := model.Invite{Email: &email, DocumentId: uuid.New(), Role: model.Edit, Token: "token", Status: model.InviteStatus(model.Pending)}
+	if err := f.db.Create(&invite).Error; err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+
+	state, code := f.login(t, nil, oidctest.Claims{
+		Subject:       "sub-1",
+		Email:         email,
+		EmailVerified: true,
+		GivenName:     "Ada",
+		FamilyName:    "Lovelace",
+		Locale:        "fr-FR",
+	})
+	result, err := f.service.Complete(context.Background(), testProvider, state, code)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if !result.Provisioned || !result.User.EmailVerified() || result.User.Locale != "fr" {
+		t.Fatalf("user = %+v, want a provisioned, verified user with locale fr", result.User)
+	}
+}
+
+func TestOIDCLinkToExistingAccount(t *testing.T) {
+	f := newOIDCFixture(t)
+	user := f.createUser(t, "ada@example.com", true)
+	other := f.createUser(t, "grace@example.com", true)
+
+	// The provider's email does not have to match the account's.
+	state, code := f.login(t, &user.ID, oidctest.Claims{Subject: "sub-1", Email: "ada@work.example"})
+	result, err := f.service.Complete(context.Background(), testProvider, state, code)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if !result.LinkFlow || !result.NewIdentity || result.User.ID != user.ID {
+		t.Fatalf("result = %+v, want a new identity linked to %s", result, user.ID)
+	}
+
+	t.Run("again", func(t *testing.T) {
+		state, code := f.login(t, &user.ID, oidctest.Claims{Subject: "sub-1"})
+		result, err := f.service.Complete(context.Background(), testProvider, state, code)
+		if err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		if !result.LinkFlow || result.NewIdentity {
+			t.Fatalf("result = %+v, want the existing link", result)
+		}
+	})
+
+	t.Run("identity of another account", func(t *testing.T) {
+		state, code := f.login(t, &other.ID, oidctest.Claims{Subject: "sub-1"})
+		if _, err := f.service.Complete(context.Background(), testProvider, state, code); !errors.Is(err, ErrOIDCIdentityInUse) {
+			t.Fatalf("err = %v, want ErrOIDCIdentityInUse", err)
+		}
+	})
+
+	t.Run("second identity from the provider", func(t *testing.T) {
+		state, code := f.login(t, &user.ID, oidctest.Claims{Subject: "sub-2"})
+		if _, err := f.service.Complete(context.Background(), testProvider, state, code); !errors.Is(err, ErrOIDCAlreadyLinked) {
+			t.Fatalf("err = %v, want ErrOIDCAlreadyLinked", err)
+		}
+	})
+}
+
+func TestOIDCBeginUnknownProvider(t *testing.T) {
+	f := newOIDCFixture(t)
+	if _, err := f.service.Begin(context.Background(), "other", nil); !errors.Is(err, ErrOIDCUnknownProvider) {
+		t.Fatalf("err = %v, want ErrOIDCUnknownProvider", err)
+	}
+
+	authURL, err := f.service.Begin(context.Background(), testProvider, nil)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if !strings.HasPrefix(authURL, f.provider.URL+"/authorize?") {
+		t.Fatalf("auth URL = %s, want the provider's authorization endpoint", authURL)
+	}
+	if query, _ := url.Parse(authURL); query.Query().Get("client_id") != f.provider.ClientID {
+		t.Fatalf("auth URL = %s, want client_id %s", authURL, f.provider.ClientID)
+	}
+}
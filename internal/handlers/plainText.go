@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	blankLines = regexp.MustCompile(`\n{3,}`)
+	spaceRuns  = regexp.MustCompile(`[ \t]+`)
+)
+
+// HTMLToText generates the plain text alternative for a rendered email.
+// Links keep their target in brackets so they stay usable in text clients.
+func HTMLToText(source string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "head", "style", "script", "img":
+				return
+			case "br":
+				sb.WriteString("\n")
+				return
+			}
+		}
+
+		if n.Type == html.TextNode {
+			sb.WriteString(spaceRuns.ReplaceAllString(strings.ReplaceAll(n.Data, "\n", " "), " "))
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch n.Data {
+		case "a":
+			href := attr(n, "href")
+			if href != "" && strings.TrimSpace(textContent(n)) != href {
+				sb.WriteString(" [" + href + "]")
+			}
+		case "h1", "h2", "h3", "p", "div", "li", "tr":
+			sb.WriteString("\n\n")
+		}
+	}
+	walk(doc)
+
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text := blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text) + "\n", nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"realTimeEditor/internal/model"
+	"time"
+)
+
+// TemplatePreviewData returns sample data for each email template so admins can
+// preview every locale without triggering a real send.
+func TemplatePreviewData(templateName string) (any, bool) {
+	year := time.Now().UTC().Year()
+	samples := map[string]any{
+		"welcome": WelcomeMessage{
+			FullName: "Ada Lovelace",
+			Year:     year,
+		},
+		"verifyEmail": EmailVerification{
+			FullName:         "Ada Lovelace",
+			VerificationLink: "https://example.com/verify-email?token=sample-token",
+			ExpiresInHours:   24,
+			Year:             year,
+		},
+		"accountLocked": AccountLocked{
+			FullName:      "Ada Lovelace",
+			UnlockLink:    "https://example.com/unlock-account?token=sample-token",
+			LockedMinutes: 30,
+			Year:          year,
+		},
+		"forgotPassword": PasswordResetCode{
+			FullName:         "Ada Lovelace",
+			ResetCode:        "A1B2C3",
+			ExpiresInMinutes: 15,
+			Year:             year,
+		},
+		"invite": Invite{
+			FullName:      "ada@example.com",
+			DocumentTitle: "Quarterly Report",
+			Role:          model.Edit,
+			InviteLink:    "https://example.com/invite/sample-token",
+			Year:          year,
+		},
+		"accountCompletion": AccountSetup{
+			DocumentTitle:    "Quarterly Report",
+			Role:             model.Read,
+			AccountSetupLink: "https://example.com/complete-registration?token=sample-token",
+			Year:             year,
+		},
+		"notification": NotificationMessage{
+			FullName: "Ada Lovelace",
+			Title:    "Your access was changed",
+			Body:     "Your role on Quarterly Report is now read.",
+			Link:     "https://example.com/documents/sample",
+			Year:     year,
+		},
+	}
+
+	data, ok := samples[templateName]
+	return data, ok
+}
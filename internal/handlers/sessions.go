@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/jwt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused means an already rotated token was presented
+	// again; its family has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// SessionManager issues access and refresh tokens and keeps the refresh token
+// families in the database so they can be rotated and revoked.
+type SessionManager struct {
+	Session                       *jwt.Session
+	RefreshTokenRepository        *repositories.RefreshTokenRepository
+	UserRepository                *repositories.UserRepository
+	PersonalAccessTokenRepository *repositories.PersonalAccessTokenRepository
+}
+
+func NewSessionManager(
+	session *jwt.Session,
+	refreshTokenRepository *repositories.RefreshTokenRepository,
+	userRepository *repositories.UserRepository,
+	personalAccessTokenRepository *repositories.PersonalAccessTokenRepository,
+) *SessionManager {
+	return &SessionManager{
+		Session:                       session,
+		RefreshTokenRepository:        refreshTokenRepository,
+		UserRepository:                userRepository,
+		PersonalAccessTokenRepository: personalAccessTokenRepository,
+	}
+}
+
+// Issue starts a new refresh token family for user, e.g. on login.
+func (s *SessionManager) Issue(c *gin.Context, user *model.User) (*TokenPair, error) {
+	return s.issue(c, user, uuid.New())
+}
+
+// Rotate consumes refreshToken and returns a new pair in the same family.
+// Presenting a token that has already been rotated revokes the family.
+func (s *SessionManager) Rotate(c *gin.Context, refreshToken string) (*TokenPair, *model.User, error) {
+	stored, err := s.lookup(refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	consumed, err := s.RefreshTokenRepository.MarkUsed(stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !consumed {
+		// A token that was live when read but could not be consumed lost a
+		// race with another rotation, which is reuse as well.
+		live := stored.RevokedAt == nil && stored.ExpiresAt.After(time.Now().UTC())
+		if stored.UsedAt != nil || live {
+			if err := s.RefreshTokenRepository.RevokeFamily(stored.FamilyID); err != nil {
+				return nil, nil, err
+			}
+			return nil, &stored.User, ErrRefreshTokenReused
+		}
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	pair, err := s.issue(c, &stored.User, stored.FamilyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &stored.User, nil
+}
+
+// Logout revokes the family of refreshToken. userId, if set, must own it.
+func (s *SessionManager) Logout(refreshToken string, userId *uuid.UUID) error {
+	stored, err := s.lookup(refreshToken)
+	if err != nil {
+		return err
+	}
+	if userId != nil && stored.UserID != *userId {
+		return ErrRefreshTokenInvalid
+	}
+	return s.RefreshTokenRepository.RevokeFamily(stored.FamilyID)
+}
+
+// LogoutAll revokes every refresh token of the user, every access token
+// issued so far and their personal access tokens. It runs when an account
+// may be in the wrong hands, e.g. on a password reset, and a token minted by
+// whoever had it must not outlive the reset.
+func (s *SessionManager) LogoutAll(userId uuid.UUID) error {
+	if err := s.RefreshTokenRepository.RevokeAllForUser(userId); err != nil {
+		return err
+	}
+	if err := s.PersonalAccessTokenRepository.RevokeAllForUser(userId); err != nil {
+		return fmt.Errorf("error revoking personal access tokens: %w", err)
+	}
+
+	if err := s.UserRepository.RevokeTokens(userId, model.TokensRevokedAfter(time.Now())); err != nil {
+		return fmt.Errorf("error revoking access tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionManager) issue(c *gin.Context, user *model.User, familyId uuid.UUID) (*TokenPair, error) {
+	stored := model.RefreshToken{
+		FamilyID:  familyId,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(jwt.RefreshTokenTTL),
+	}
+	if c != nil {
+		stored.IPAddress = c.ClientIP()
+		stored.UserAgent = c.Request.UserAgent()
+	}
+	if err := s.RefreshTokenRepository.Create(&stored); err != nil {
+		return nil, fmt.Errorf("error storing refresh token: %w", err)
+	}
+
+	accessToken, err := s.Session.GenerateAccessToken(user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.Session.GenerateRefreshToken(user.Email, stored.ID.String(), stored.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (s *SessionManager) lookup(refreshToken string) (*model.RefreshToken, error) {
+	claims, err := s.Session.VerifyRefreshToken(refreshToken)
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	tokenId, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	var stored model.RefreshToken
+	if err := s.RefreshTokenRepository.GetOne(tokenId, &stored); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, fmt.Errorf("error fetching refresh token: %w", err)
+	}
+	if stored.User.Email != claims.Email {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return &stored, nil
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	TOTPIssuer = "RealTimeEditor"
+
+	recoveryCodeCount    = 10
+	twoFactorMaxAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+
+	// recoveryCodeAlphabet is Crockford base32: 32 symbols so random bytes map
+	// onto it without bias, and no easily confused letters.
+	recoveryCodeAlphabet   = "0123456789abcdefghjkmnpqrstvwxyz"
+	recoveryCodeHalfLength = 5
+)
+
+var (
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor auth is not enrolled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor auth is already enabled")
+	ErrTwoFactorInvalidCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorLocked         = errors.New("too many invalid two-factor codes")
+)
+
+var recoveryCodeSeparators = strings.NewReplacer("-", "", " ", "")
+
+// Enrollment is what a client needs to add the account to an authenticator
+// app; OTPAuthURI is also the QR code payload.
+type Enrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type TwoFactorService struct {
+	TwoFactorRepository *repositories.TwoFactorRepository
+}
+
+func NewTwoFactorService(twoFactorRepository *repositories.TwoFactorRepository) *TwoFactorService {
+	return &TwoFactorService{
+		TwoFactorRepository: twoFactorRepository,
+	}
+}
+
+// Status returns the user's enrollment, or nil if they have none.
+func (t *TwoFactorService) Status(userId uuid.UUID) (*model.TwoFactorAuth, error) {
+	var twoFactor model.TwoFactorAuth
+	if err := t.TwoFactorRepository.GetByUser(userId, &twoFactor); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching two-factor auth: %w", err)
+	}
+	return &twoFactor, nil
+}
+
+func (t *TwoFactorService) Enabled(userId uuid.UUID) (bool, error) {
+	twoFactor, err := t.Status(userId)
+	if err != nil {
+		return false, err
+	}
+	return twoFactor != nil && twoFactor.Enabled, nil
+}
+
+// Enroll generates a new secret. 2FA stays off until Confirm succeeds.
+func (t *TwoFactorService) Enroll(user *model.User) (*Enrollment, error) {
+	enabled, err := t.Enabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating TOTP secret: %w", err)
+	}
+	if err := t.TwoFactorRepository.StartEnrollment(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables 2FA once the user proves their app generates valid codes,
+// and returns the plaintext recovery codes. They are not retrievable later.
+func (t *TwoFactorService) Confirm(userId uuid.UUID, code string) ([]string, error) {
+	twoFactor, err := t.Status(userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if twoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok, err := utils.ValidateTOTP(twoFactor.Secret, code, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.TwoFactorRepository.Enable(userId, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts a current TOTP code or an unused recovery code. Repeated
+// failures lock verification for a while.
+func (t *TwoFactorService) Verify(userId uuid.UUID, code string) error {
+	twoFactor, err := t.Status(userId)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+	if twoFactor.LockedUntil != nil && twoFactor.LockedUntil.After(time.Now().UTC()) {
+		return ErrTwoFactorLocked
+	}
+
+	step, ok, err := utils.ValidateTOTP(twoFactor.Secret, code, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if ok {
+		fresh, err := t.TwoFactorRepository.UseStep(userId, step)
+		if err != nil {
+			return err
+		}
+		if fresh {
+			return nil
+		}
+	} else {
+		used, err := t.TwoFactorRepository.ConsumeRecoveryCode(userId, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		if used {
+			return t.TwoFactorRepository.ResetFailures(userId)
+		}
+	}
+
+	lockedUntil, err := t.TwoFactorRepository.RecordFailure(userId, twoFactorMaxAttempts, twoFactorLockout)
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return ErrTwoFactorLocked
+	}
+	return ErrTwoFactorInvalidCode
+}
+
+func (t *TwoFactorService) Disable(userId uuid.UUID) error {
+	return t.TwoFactorRepository.Disable(userId)
+}
+
+func (t *TwoFactorService) RegenerateRecoveryCodes(userId uuid.UUID) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.TwoFactorRepository.ReplaceRecoveryCodes(userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx and their
+// hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		raw := make([]byte, recoveryCodeHalfLength*2)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery codes: %w", err)
+		}
+		for j, b := range raw {
+			raw[j] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+		codes[i] = string(raw[:recoveryCodeHalfLength]) + "-" + string(raw[recoveryCodeHalfLength:])
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	return utils.HashToken(recoveryCodeSeparators.Replace(strings.ToLower(strings.TrimSpace(code))))
+}
@@ -3,9 +3,24 @@ package handlers
 import "realTimeEditor/internal/model"
 
 type PasswordResetCode struct {
-	FullName  string
-	ResetCode string
-	Year      int
+	FullName         string
+	ResetCode        string
+	ExpiresInMinutes int
+	Year             int
+}
+
+type EmailVerification struct {
+	FullName         string
+	VerificationLink string
+	ExpiresInHours   int
+	Year             int
+}
+
+type AccountLocked struct {
+	FullName      string
+	UnlockLink    string
+	LockedMinutes int
+	Year          int
 }
 
 type WelcomeMessage struct {
@@ -25,4 +40,13 @@ type AccountSetup struct {
 	DocumentTitle    string
 	Role             model.Role
 	AccountSetupLink string
+	Year             int
+}
+
+type NotificationMessage struct {
+	FullName string
+	Title    string
+	Body     string
+	Link     string
+	Year     int
 }
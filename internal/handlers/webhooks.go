@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookPublisher turns lifecycle events into pending deliveries. It only
+// writes outbox rows; the HTTP calls happen in jobs.WebhookDispatcher.
+type WebhookPublisher struct {
+	WebhookRepository         *repositories.WebhookRepository
+	WebhookDeliveryRepository *repositories.WebhookDeliveryRepository
+	pending                   chan struct{}
+}
+
+func NewWebhookPublisher(
+	webhookRepository *repositories.WebhookRepository,
+	webhookDeliveryRepository *repositories.WebhookDeliveryRepository,
+) *WebhookPublisher {
+	return &WebhookPublisher{
+		WebhookRepository:         webhookRepository,
+		WebhookDeliveryRepository: webhookDeliveryRepository,
+		pending:                   make(chan struct{}, 1),
+	}
+}
+
+// Pending is signalled whenever new deliveries are queued so the dispatcher
+// does not have to wait for its next poll.
+func (w *WebhookPublisher) Pending() <-chan struct{} {
+	return w.pending
+}
+
+type WebhookPayload struct {
+	Event      model.WebhookEvent `json:"event"`
+	DocumentID uuid.UUID          `json:"documentId"`
+	ActorID    *uuid.UUID         `json:"actorId,omitempty"`
+	OccurredAt time.Time          `json:"occurredAt"`
+	Data       any                `json:"data,omitempty"`
+}
+
+// Publish queues event for every subscriber of document. Failures are logged;
+// webhooks never fail the request that triggered them.
+func (w *WebhookPublisher) Publish(ctx context.Context, event model.WebhookEvent, document *model.Document, actorId *uuid.UUID, data any) {
+	logger := logging.FromContext(ctx)
+	webhooks, err := w.WebhookRepository.GetSubscribers(document.ID, document.UserID)
+	if err != nil {
+		logger.Error("Error publishing webhook event", "event", event, "error", err)
+		return
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Event:      event,
+		DocumentID: document.ID,
+		ActorID:    actorId,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		logger.Error("Error encoding webhook payload", "event", event, "error", err)
+		return
+	}
+
+	queued := 0
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		if err := w.enqueue(webhook.ID, event, body); err != nil {
+			logger.Error("Error queueing webhook delivery", "event", event, "webhookId", webhook.ID, "error", err)
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		w.signal()
+	}
+}
+
+// Ping queues a test delivery for a single webhook.
+func (w *WebhookPublisher) Ping(webhook *model.Webhook) (*model.WebhookDelivery, error) {
+	body, err := json.Marshal(map[string]any{
+		"event":      model.WebhookPing,
+		"webhookId":  webhook.ID,
+		"occurredAt": time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &model.WebhookDelivery{
+		WebhookID: webhook.ID,
+		Event:     model.WebhookPing,
+		Payload:   body,
+		Status:    model.DeliveryPending,
+	}
+	if err := w.WebhookDeliveryRepository.Create(delivery); err != nil {
+		return nil, fmt.Errorf("error queueing ping: %w", err)
+	}
+	w.signal()
+	return delivery, nil
+}
+
+func (w *WebhookPublisher) enqueue(webhookId uuid.UUID, event model.WebhookEvent, body []byte) error {
+	return w.WebhookDeliveryRepository.Create(&model.WebhookDelivery{
+		WebhookID: webhookId,
+		Event:     event,
+		Payload:   body,
+		Status:    model.DeliveryPending,
+	})
+}
+
+func (w *WebhookPublisher) signal() {
+	select {
+	case w.pending <- struct{}{}:
+	default:
+	}
+}
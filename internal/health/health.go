@@ -0,0 +1,189 @@
+// Package health serves the liveness and readiness endpoints.
+//
+// Liveness checks cover failures that a restart fixes, such as a stopped
+// socket server or a stuck worker. Readiness runs those too, plus checks of
+// the dependencies needed to serve traffic, and fails while the server is
+// draining so load balancers stop sending requests before it shuts down.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"realTimeEditor/internal/logging"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check returns nil when the component it checks is healthy.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// CheckResult is the outcome of one check in a response. The probes are
+// unauthenticated, so why a check failed is logged rather than returned.
+type CheckResult struct {
+	Status string `json:"status"`
+}
+
+// Report is the body of /livez and /readyz.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Checker holds the registered checks. Checks of one probe run
+// concurrently, each bounded by the checker's timeout.
+type Checker struct {
+	timeout   time.Duration
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+	draining  atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// AddLiveness registers a check for /livez and /readyz.
+func (h *Checker) AddLiveness(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, namedCheck{name, check})
+}
+
+// AddReadiness registers a check for /readyz only.
+func (h *Checker) AddReadiness(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, namedCheck{name, check})
+}
+
+// Drain makes /readyz fail from now on; liveness is unaffected.
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+// Livez serves the liveness checks.
+func (h *Checker) Livez() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		checks := append([]namedCheck(nil), h.liveness...)
+		h.mu.RUnlock()
+		h.serve(w, r, checks, false)
+	})
+}
+
+// Readyz serves the liveness and readiness checks, and fails while
+// draining.
+func (h *Checker) Readyz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		checks := append(append([]namedCheck(nil), h.liveness...), h.readiness...)
+		h.mu.RUnlock()
+		h.serve(w, r, checks, h.draining.Load())
+	})
+}
+
+func (h *Checker) serve(w http.ResponseWriter, r *http.Request, checks []namedCheck, draining bool) {
+	report := h.run(r.Context(), checks)
+	if draining {
+		report.Status = "draining"
+	}
+
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *Checker) run(ctx context.Context, checks []namedCheck) Report {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := Report{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := c.check(ctx)
+			result := CheckResult{Status: "ok"}
+			if err != nil {
+				result.Status = "failed"
+				logging.FromContext(ctx).Warn("Health check failed", "check", c.name, "duration", time.Since(start), "error", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if err != nil {
+				report.Status = "failed"
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// Cached runs check at most once per ttl and reuses its result in between,
+// for dependencies that rate limit or bill their status endpoint.
+func Cached(check Check, ttl time.Duration) Check {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		last    error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return last
+		}
+		last = check(ctx)
+		checked = time.Now()
+		return last
+	}
+}
+
+// Heartbeat records when a background worker last completed a cycle.
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+// NewHeartbeat returns a heartbeat that counts its creation as the first
+// beat, so a worker has one full period to report in.
+func NewHeartbeat() *Heartbeat {
+	h := &Heartbeat{}
+	h.Beat()
+	return h
+}
+
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Check fails when the last beat is older than maxAge.
+func (h *Heartbeat) Check(maxAge time.Duration) Check {
+	return func(context.Context) error {
+		age := time.Since(time.Unix(0, h.last.Load()))
+		if age > maxAge {
+			return errors.New("no heartbeat for " + age.Truncate(time.Second).String())
+		}
+		return nil
+	}
+}
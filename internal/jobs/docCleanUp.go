@@ -3,53 +3,64 @@ package jobs
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/metrics"
 	"realTimeEditor/internal/model"
 	"realTimeEditor/internal/repositories"
+	"realTimeEditor/internal/tracing"
 	"sync"
-	"time"
 
 	"github.com/robfig/cron/v3"
 )
 
 type DocumentCleanup struct {
 	DocumentMedia repositories.DocumentMediaRepository
+	Config        config.JobsConfig
 	cron          *cron.Cron
 }
 
-func NewReceiptCleanup(documentMedia repositories.DocumentMediaRepository) *DocumentCleanup {
+func NewReceiptCleanup(documentMedia repositories.DocumentMediaRepository, cfg config.JobsConfig) *DocumentCleanup {
 	return &DocumentCleanup{
 		DocumentMedia: documentMedia,
+		Config:        cfg,
 		cron:          cron.New(cron.WithSeconds()),
 	}
 }
 
+// Start runs the schedule until ctx is cancelled, then returns once a run
+// in progress has finished.
 func (r *DocumentCleanup) Start(ctx context.Context) {
-	_, err := r.cron.AddFunc("0 */20 * * * *", func() {
+	_, err := r.cron.AddFunc(r.Config.DocumentCleanupSchedule, func() {
 		r.CleanupBatch(ctx)
 	})
 	if err != nil {
-		log.Printf("Failed to schedule receipt cleanup: %v", err)
+		slog.Error("Failed to schedule receipt cleanup", "error", err)
 		return
 	}
 
 	r.cron.Start()
-	go func() {
-		<-ctx.Done()
-		log.Println("Stopping receipt cleanup scheduler...")
-		r.cron.Stop()
-	}()
+	<-ctx.Done()
+	slog.Info("Stopping receipt cleanup scheduler")
+	<-r.cron.Stop().Done()
 }
 
 func (r *DocumentCleanup) CleanupBatch(ctx context.Context) {
-	documentMedia, err := r.DocumentMedia.GetExpiredReceipts(20 * time.Minute)
+	spanCtx, span := tracing.Start(ctx, "jobs.receipt_cleanup")
+	defer span.End()
+	// Deletes already started are allowed to finish on shutdown.
+	deleteCtx := context.WithoutCancel(spanCtx)
+
+	documentMedia, err := r.DocumentMedia.WithContext(spanCtx).GetExpiredReceipts(r.Config.ReceiptTTL)
 	if err != nil {
-		log.Printf("Error fetching receipts: %v", err)
+		slog.Error("Error fetching receipts", "error", err)
+		metrics.CleanupErrors.Inc()
 		return
 	}
+	metrics.CleanupBatchSize.Observe(float64(len(documentMedia)))
 
 	if len(documentMedia) == 0 {
-		log.Printf("Nothing to cleanup, exiting...")
+		slog.Debug("Nothing to clean up")
 		return
 	}
 	var (
@@ -63,7 +74,7 @@ func (r *DocumentCleanup) CleanupBatch(ctx context.Context) {
 	for _, documentMedium := range documentMedia {
 		select {
 		case <-ctx.Done():
-			log.Println("Aborting cleanup batch due to shutdown")
+			slog.Info("Aborting cleanup batch due to shutdown")
 			return
 		default:
 		}
@@ -74,13 +85,13 @@ func (r *DocumentCleanup) CleanupBatch(ctx context.Context) {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			if _, err := repositories.CloudinaryDelete(medium.PublicID, repositories.RawResource); err != nil {
+			if _, err := repositories.CloudinaryDelete(deleteCtx, medium.PublicID, repositories.RawResource); err != nil {
 				mu.Lock()
 				deleteErr = append(deleteErr, fmt.Sprintf("Failed to delete %s: %v", medium.PublicID, err))
 				mu.Unlock()
 			}
 
-			if err := r.DocumentMedia.Delete(medium.ID); err != nil {
+			if err := r.DocumentMedia.WithContext(deleteCtx).Delete(medium.ID); err != nil {
 				mu.Lock()
 				deleteErr = append(deleteErr, fmt.Sprintf("Failed to delete %s: %v", medium.PublicID, err))
 				mu.Unlock()
@@ -90,7 +101,8 @@ func (r *DocumentCleanup) CleanupBatch(ctx context.Context) {
 	}
 
 	wg.Wait()
+	metrics.CleanupErrors.Add(float64(len(deleteErr)))
 	if len(deleteErr) > 0 {
-		log.Printf("Cleanup completed with %d errors", len(deleteErr))
+		slog.Error("Cleanup completed with errors", "count", len(deleteErr), "errors", deleteErr)
 	}
 }
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/repositories"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// InviteExpiry marks pending invites whose link has run out as expired, so
+// owners see them as such and can resend them.
+type InviteExpiry struct {
+	Invites *repositories.InviteRepository
+	Config  config.JobsConfig
+	cron    *cron.Cron
+}
+
+func NewInviteExpiry(invites *repositories.InviteRepository, cfg config.JobsConfig) *InviteExpiry {
+	return &InviteExpiry{
+		Invites: invites,
+		Config:  cfg,
+		cron:    cron.New(cron.WithSeconds()),
+	}
+}
+
+// Start runs the schedule until ctx is cancelled, then returns once a run
+// in progress has finished.
+func (i *InviteExpiry) Start(ctx context.Context) {
+	_, err := i.cron.AddFunc(i.Config.InviteExpirySchedule, i.Expire)
+	if err != nil {
+		slog.Error("Failed to schedule invite expiry", "error", err)
+		return
+	}
+
+	i.cron.Start()
+	<-ctx.Done()
+	slog.Info("Stopping invite expiry scheduler")
+	<-i.cron.Stop().Done()
+}
+
+func (i *InviteExpiry) Expire() {
+	expired, err := i.Invites.ExpirePending(time.Now().UTC())
+	if err != nil {
+		slog.Error("Error expiring invites", "error", err)
+		return
+	}
+	if expired > 0 {
+		slog.Info("Expired pending invites", "count", expired)
+	}
+}
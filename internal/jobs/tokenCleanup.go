@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/repositories"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// TokenCleanup deletes refresh tokens once they have expired; until then used
+// and revoked tokens are kept so reuse can be detected. It also drops
+// abandoned OIDC sign-in attempts, expired or used password resets, expired
+// email verification links, login failure counters that have gone quiet and
+// expired rate limit counters.
+type TokenCleanup struct {
+	RefreshTokens      *repositories.RefreshTokenRepository
+	OIDCAuthRequests   *repositories.OIDCAuthRequestRepository
+	ForgotPasswords    *repositories.ForgotPasswordRepository
+	EmailVerifications *repositories.EmailVerificationRepository
+	LoginThrottles     *repositories.LoginThrottleRepository
+	RateLimits         *repositories.RateLimitRepository
+	Config             config.JobsConfig
+	cron               *cron.Cron
+}
+
+func NewTokenCleanup(
+	refreshTokens *repositories.RefreshTokenRepository,
+	oidcAuthRequests *repositories.OIDCAuthRequestRepository,
+	forgotPasswords *repositories.ForgotPasswordRepository,
+	emailVerifications *repositories.EmailVerificationRepository,
+	loginThrottles *repositories.LoginThrottleRepository,
+	rateLimits *repositories.RateLimitRepository,
+	cfg config.JobsConfig,
+) *TokenCleanup {
+	return &TokenCleanup{
+		RefreshTokens:      refreshTokens,
+		OIDCAuthRequests:   oidcAuthRequests,
+		ForgotPasswords:    forgotPasswords,
+		EmailVerifications: emailVerifications,
+		LoginThrottles:     loginThrottles,
+		RateLimits:         rateLimits,
+		Config:             cfg,
+		cron:               cron.New(cron.WithSeconds()),
+	}
+}
+
+// Start runs the schedule until ctx is cancelled, then returns once a run
+// in progress has finished.
+func (t *TokenCleanup) Start(ctx context.Context) {
+	_, err := t.cron.AddFunc(t.Config.TokenCleanupSchedule, t.Cleanup)
+	if err != nil {
+		slog.Error("Failed to schedule token cleanup", "error", err)
+		return
+	}
+
+	t.cron.Start()
+	<-ctx.Done()
+	slog.Info("Stopping token cleanup scheduler")
+	<-t.cron.Stop().Done()
+}
+
+func (t *TokenCleanup) Cleanup() {
+	now := time.Now().UTC()
+
+	deleted, err := t.RefreshTokens.DeleteExpired(now)
+	if err != nil {
+		slog.Error("Error cleaning up refresh tokens", "error", err)
+	} else if deleted > 0 {
+		slog.Info("Deleted expired refresh tokens", "count", deleted)
+	}
+
+	deleted, err = t.OIDCAuthRequests.DeleteExpired(now)
+	if err != nil {
+		slog.Error("Error cleaning up OIDC auth requests", "error", err)
+	} else if deleted > 0 {
+		slog.Info("Deleted expired OIDC auth requests", "count", deleted)
+	}
+
+	deleted, err = t.ForgotPasswords.DeleteStale(now)
+	if err != nil {
+		slog.Error("Error cleaning up password resets", "error", err)
+	} else if deleted > 0 {
+		slog.Info("Deleted stale password resets", "count", deleted)
+	}
+
+	deleted, err = t.EmailVerifications.DeleteExpired(now)
+	if err != nil {
+		slog.Error("Error cleaning up email verifications", "error", err)
+	} else if deleted > 0 {
+		slog.Info("Deleted expired email verifications", "count", deleted)
+	}
+
+	// Failures older than a day no longer affect any delay or lockout.
+	deleted, err = t.LoginThrottles.DeleteStale(now.Add(-24 * time.Hour))
+	if err != nil {
+		slog.Error("Error cleaning up login throttles", "error", err)
+	} else if deleted > 0 {
+		slog.Info("Deleted stale login throttles", "count", deleted)
+	}
+
+	deleted, err = t.RateLimits.DeleteExpired(now)
+	if err != nil {
+		slog.Error("Error cleaning up rate limit counters", "error", err)
+	} else if deleted > 0 {
+		slog.Info("Deleted expired rate limit counters", "count", deleted)
+	}
+}
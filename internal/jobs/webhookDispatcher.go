@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"realTimeEditor/config"
+	"realTimeEditor/internal/health"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/internal/repositories"
+	"realTimeEditor/internal/tracing"
+	"realTimeEditor/pkg/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	webhookMaxAttempts = 8
+	webhookBaseBackoff = 30 * time.Second
+	webhookBatchSize   = 20
+	webhookTimeout     = 10 * time.Second
+)
+
+type WebhookDispatcher struct {
+	Deliveries *repositories.WebhookDeliveryRepository
+	Config     config.JobsConfig
+	client     *http.Client
+	pending    <-chan struct{}
+	heartbeat  *health.Heartbeat
+}
+
+func NewWebhookDispatcher(deliveries *repositories.WebhookDeliveryRepository, pending <-chan struct{}, cfg config.JobsConfig) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Deliveries: deliveries,
+		Config:     cfg,
+		client:     newWebhookClient(),
+		pending:    pending,
+		heartbeat:  health.NewHeartbeat(),
+	}
+}
+
+// newWebhookClient returns a client that only connects to public addresses
+// and does not follow redirects, so webhooks cannot be pointed at internal
+// services.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   webhookTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   utils.PublicDialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: tracing.Transport(transport),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// HealthCheck fails when the dispatcher has not finished a cycle for longer
+// than a poll interval and a full batch of slow deliveries would take.
+func (w *WebhookDispatcher) HealthCheck() health.Check {
+	return w.heartbeat.Check(2*w.Config.WebhookPollInterval + webhookBatchSize*webhookTimeout)
+}
+
+// Start polls for due deliveries until ctx is cancelled, finishing the batch
+// in progress. New deliveries wake it early through the publisher's pending
+// channel.
+func (w *WebhookDispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.Config.WebhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.DispatchBatch(ctx)
+		w.heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping webhook dispatcher")
+			return
+		case <-ticker.C:
+		case <-w.pending:
+		}
+	}
+}
+
+func (w *WebhookDispatcher) DispatchBatch(ctx context.Context) {
+	deliveries, err := w.Deliveries.ClaimDue(webhookBatchSize, webhookTimeout*2)
+	if err != nil {
+		slog.Error("Error fetching webhook deliveries", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 5)
+
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(delivery model.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			// A claimed delivery is sent even during shutdown; the client
+			// timeout bounds it.
+			w.deliver(context.WithoutCancel(ctx), &delivery)
+		}(delivery)
+	}
+
+	wg.Wait()
+}
+
+func (w *WebhookDispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	statusCode, err := w.send(ctx, delivery)
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		now := time.Now().UTC()
+		delivery.Status = model.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = failureReason(err)
+	default:
+		delivery.LastError = failureReason(err)
+		delivery.NextAttemptAt = time.Now().UTC().Add(backoff(delivery.Attempts))
+	}
+
+	if err != nil {
+		slog.Warn("Webhook delivery failed", "deliveryId", delivery.ID, "attempt", delivery.Attempts, "error", err)
+	}
+
+	if err := w.Deliveries.Update(delivery); err != nil {
+		slog.Error("Error recording webhook delivery", "deliveryId", delivery.ID, "error", err)
+	}
+}
+
+func (w *WebhookDispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	timestamp := time.Now().UTC().Unix()
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FileEditor-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", string(delivery.Event))
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", utils.SignWebhookPayload(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%w %d", errUnexpectedStatus, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+var errUnexpectedStatus = errors.New("unexpected status")
+
+// failureReason is the error recorded on a delivery, which webhook owners
+// can read. Transport errors are reduced to a generic reason so they do not
+// reveal anything about the network the dispatcher runs in.
+func failureReason(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errUnexpectedStatus):
+		return err.Error()
+	case errors.Is(err, utils.ErrNonPublicAddress):
+		return "destination address is not allowed"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "request failed"
+	}
+}
+
+// backoff doubles from webhookBaseBackoff: 30s, 1m, 2m, 4m ... capped at 6h.
+func backoff(attempt int) time.Duration {
+	delay := time.Duration(math.Pow(2, float64(attempt-1))) * webhookBaseBackoff
+	return min(delay, 6*time.Hour)
+}
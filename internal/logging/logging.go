@@ -0,0 +1,195 @@
+// Package logging sets up the structured logger and attaches request and
+// connection fields to it.
+//
+// Attributes whose key names a credential or document content (password,
+// token, secret, content, ...) are always written as "[redacted]", so they
+// are safe to pass even by mistake.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	socketio "github.com/googollee/go-socket.io"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RequestIDHeader carries the request ID in and out; a valid incoming
+	// value is kept so a request can be followed across services.
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "requestId"
+)
+
+type contextKey struct{}
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// sensitiveSuffixes are matched case-insensitively against the end of a
+// key, so accessToken and clientSecret are caught too. Codes are matched
+// exactly to leave statusCode alone.
+var (
+	sensitiveSuffixes = []string{"password", "token", "secret", "authorization", "cookie", "content", "salt"}
+	sensitiveKeys     = map[string]bool{"code": true, "resetcode": true, "recoverycode": true}
+)
+
+func redacted(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, s := range sensitiveSuffixes {
+		if strings.HasSuffix(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindGroup && redacted(a.Key) {
+		return slog.String(a.Key, "[redacted]")
+	}
+	return a
+}
+
+// NewLogger returns a logger writing format ("json" or "text") to w at
+// level ("debug", "info", "warn" or "error").
+func NewLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+// Setup makes a logger for format and level the default, including for the
+// standard log package.
+func Setup(format, level string) error {
+	logger, err := NewLogger(os.Stderr, format, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// RequestID returns the request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger with the request ID and trace ID
+// in ctx, if any.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With(requestIDKey, id)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		logger = logger.With("traceId", span.TraceID().String())
+	}
+	return logger
+}
+
+// From returns the default logger with the request ID and, once the request
+// is authenticated, the user ID.
+func From(c *gin.Context) *slog.Logger {
+	logger := FromContext(c.Request.Context())
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(model.User); ok {
+			logger = logger.With("userId", u.ID)
+		}
+	}
+	return logger
+}
+
+// Socket returns the default logger with the connection ID and, once the
+// connection is authenticated, the user ID.
+func Socket(s socketio.Conn) *slog.Logger {
+	logger := slog.Default().With("connId", s.ID())
+	if ctx, ok := s.Context().(map[string]string); ok && ctx["userId"] != "" {
+		logger = logger.With("userId", ctx["userId"])
+	}
+	return logger
+}
+
+// Middleware assigns every request an ID, taken from the X-Request-ID header
+// when valid, echoes it in the response and logs the request once it has
+// been handled.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, id))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		} else if status >= 400 {
+			level = slog.LevelWarn
+		}
+		// The route pattern rather than the path, which can hold tokens
+		// such as invite links.
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		From(c).Log(c.Request.Context(), level, "Request handled",
+			"method", c.Request.Method,
+			"route", route,
+			"status", status,
+			"duration", time.Since(start),
+			"ip", c.ClientIP(),
+		)
+	}
+}
+
+// Recovery logs a panicking handler, with its stack, under the request's
+// fields and records a 500 for middlewares.ErrorHandler to write.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				From(c).Error("Handler panicked", "panic", recovered, "stack", string(debug.Stack()))
+				c.Error(apperror.New(http.StatusInternalServerError, apperror.CodeInternal, "Internal server error"))
+				c.Abort()
+			}
+		}()
+		c.Next()
+	}
+}
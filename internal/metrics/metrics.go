@@ -0,0 +1,153 @@
+// Package metrics defines the Prometheus metrics served on /metrics. They
+// are registered with the default registry, which also exports Go runtime
+// and process metrics.
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	socketio "github.com/googollee/go-socket.io"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "realtime_editor"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// SocketEdits counts edit events by outcome: applied, rate_limited,
+	// rejected (bad input or no access) or failed.
+	SocketEdits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "socket_edit_events_total",
+		Help:      "Socket edit events by result.",
+	}, []string{"result"})
+
+	// BroadcastFanout is the number of connections each document update
+	// was broadcast to.
+	BroadcastFanout = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "socket_broadcast_fanout",
+		Help:      "Connections reached by each document update broadcast.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250},
+	})
+
+	PDFRenderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pdf_render_duration_seconds",
+		Help:      "Time to render a document PDF, by result.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"result"})
+
+	// EmailsSent counts sends by template and result: sent, failed or
+	// disabled when SMTP is not configured.
+	EmailsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_sent_total",
+		Help:      "Emails by template and result.",
+	}, []string{"template", "result"})
+
+	CleanupBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "document_cleanup_batch_size",
+		Help:      "Expired exports found by each document cleanup run.",
+		Buckets:   []float64{0, 1, 5, 10, 25, 50, 100, 250, 500},
+	})
+
+	CleanupErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "document_cleanup_errors_total",
+		Help:      "Exports the document cleanup job failed to delete.",
+	})
+)
+
+// Result is "ok" or "error" for the result label of an operation.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Since observes the seconds elapsed since start with the result of err.
+func Since(h *prometheus.HistogramVec, start time.Time, err error) {
+	h.WithLabelValues(Result(err)).Observe(time.Since(start).Seconds())
+}
+
+// GinMiddleware records every request under its route pattern, e.g.
+// /document/:id, so IDs do not create new series. Requests that match no
+// route are recorded as "unmatched".
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics, requiring token as a bearer token when it is
+// not empty.
+func Handler(token string) http.Handler {
+	h := promhttp.Handler()
+	if token == "" {
+		return h
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// RegisterSocketServer exports the connection and room counts of server,
+// read at scrape time.
+func RegisterSocketServer(server *socketio.Server, socketNamespace string) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "socket_connections",
+		Help:      "Connected sockets.",
+	}, func() float64 {
+		return float64(server.Count())
+	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "socket_rooms",
+		Help:      "Socket rooms with at least one connection.",
+	}, func() float64 {
+		return float64(len(server.Rooms(socketNamespace)))
+	})
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
@@ -1,32 +1,44 @@
 package middlewares
 
 import (
-	"net/http"
+	"fmt"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/logging"
 	"realTimeEditor/internal/model"
 	"realTimeEditor/internal/repositories"
 	"realTimeEditor/pkg/jwt"
+	"realTimeEditor/pkg/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthMiddleware struct {
-	UserRepository *repositories.UserRepository
+	UserRepository                *repositories.UserRepository
+	PersonalAccessTokenRepository *repositories.PersonalAccessTokenRepository
 }
 
-func (a *AuthMiddleware) UserAuth(sessionService *jwt.Session) gin.HandlerFunc {
+// UserAuth accepts a session access token, or a personal access token when
+// the route lists the scopes it needs. Routes without scopes are closed to
+// personal access tokens.
+func (a *AuthMiddleware) UserAuth(sessionService *jwt.Session, scopes ...model.TokenScope) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+			c.Error(apperror.Unauthorized("Missing access token"))
 			c.Abort()
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		email, err := sessionService.VerifyAccessToken(tokenString)
+		if strings.HasPrefix(tokenString, model.PersonalAccessTokenPrefix) {
+			a.personalAccessTokenAuth(c, tokenString, scopes)
+			return
+		}
+
+		email, issuedAt, err := sessionService.VerifyAccessToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Error(apperror.Unauthorized("Invalid or expired access token"))
 			c.Abort()
 			return
 		}
@@ -35,7 +47,13 @@ func (a *AuthMiddleware) UserAuth(sessionService *jwt.Session) gin.HandlerFunc {
 
 		err = a.UserRepository.GetByEmail(&user, email)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Error(apperror.Unauthorized("User not found"))
+			c.Abort()
+			return
+		}
+
+		if user.AccessTokenRevoked(issuedAt) {
+			c.Error(apperror.Unauthorized("Session revoked"))
 			c.Abort()
 			return
 		}
@@ -44,3 +62,83 @@ func (a *AuthMiddleware) UserAuth(sessionService *jwt.Session) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func (a *AuthMiddleware) personalAccessTokenAuth(c *gin.Context, tokenString string, scopes []model.TokenScope) {
+	if len(scopes) == 0 {
+		c.Error(apperror.Forbidden("Personal access tokens cannot be used for this endpoint"))
+		c.Abort()
+		return
+	}
+
+	var token model.PersonalAccessToken
+	if err := a.PersonalAccessTokenRepository.GetByHash(utils.HashToken(tokenString), &token); err != nil {
+		c.Error(apperror.Unauthorized("Invalid access token"))
+		c.Abort()
+		return
+	}
+
+	if !token.Active() {
+		c.Error(apperror.Unauthorized("Token expired or revoked"))
+		c.Abort()
+		return
+	}
+
+	for _, scope := range scopes {
+		if !token.HasScope(scope) {
+			c.Error(apperror.Forbidden(fmt.Sprintf("Token is missing the %s scope", scope)))
+			c.Abort()
+			return
+		}
+	}
+
+	if err := a.PersonalAccessTokenRepository.TouchLastUsed(token.ID, c.ClientIP()); err != nil {
+		logging.From(c).Error("Error updating token last use", "error", err)
+	}
+
+	c.Set("user", token.User)
+	c.Set("personalAccessTokenId", token.ID)
+	c.Next()
+}
+
+// RequireVerifiedEmail must run after UserAuth; it rejects users who have not
+// verified their email yet.
+func (a *AuthMiddleware) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.Error(apperror.Unauthorized("Invalid session"))
+			c.Abort()
+			return
+		}
+
+		userDetails, ok := user.(model.User)
+		if !ok || !userDetails.EmailVerified() {
+			c.Error(apperror.Forbidden("Verify your email address to create or share documents"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminAuth must run after UserAuth; it rejects users without the admin flag.
+func (a *AuthMiddleware) AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.Error(apperror.Unauthorized("Invalid session"))
+			c.Abort()
+			return
+		}
+
+		userDetails, ok := user.(model.User)
+		if !ok || !userDetails.IsAdmin {
+			c.Error(apperror.Forbidden("Admin access required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
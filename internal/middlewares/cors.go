@@ -1,6 +1,8 @@
 package middlewares
 
 import (
+	"realTimeEditor/internal/logging"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -9,7 +11,8 @@ func CORSMiddleware() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", logging.RequestIDHeader},
+		ExposeHeaders:    []string{logging.RequestIDHeader},
 		AllowCredentials: true,
 		AllowWebSockets:  true,
 	})
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LegacyRoutesDeprecatedAt is when the unversioned routes were deprecated in
+// favour of /api/v1.
+var LegacyRoutesDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks responses with the Deprecation header (RFC 9745) and a
+// link to the API documentation, which lists each route's replacement.
+func Deprecated(since time.Time) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", `</swagger>; rel="deprecation"; type="text/html"`)
+		c.Next()
+	}
+}
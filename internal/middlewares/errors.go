@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 error responses.
+const ProblemContentType = "application/problem+json"
+
+// ErrorHandler writes the last error a handler recorded with c.Error as a
+// problem+json response, unless the handler already responded. Errors that
+// are not an *apperror.Error are answered with a generic 500 and logged with
+// their cause.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		appErr := apperror.From(err)
+		if appErr.Status >= 500 && appErr.Err != nil {
+			logging.From(c).Error("Request failed", "error", err)
+		}
+
+		problem := appErr.Problem()
+		if id := logging.RequestID(c.Request.Context()); id != "" {
+			problem["requestId"] = id
+		}
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(appErr.Status, problem)
+	}
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"context"
+	"realTimeEditor/internal/repositories"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"github.com/ulule/limiter/v3/drivers/store/common"
+)
+
+// PostgresStore is a limiter.Store that keeps counters in the database so
+// every replica enforces the same limits.
+type PostgresStore struct {
+	RateLimitRepository *repositories.RateLimitRepository
+}
+
+func NewPostgresStore(rateLimitRepository *repositories.RateLimitRepository) limiter.Store {
+	return &PostgresStore{
+		RateLimitRepository: rateLimitRepository,
+	}
+}
+
+func (p *PostgresStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return p.Increment(ctx, key, 1, rate)
+}
+
+func (p *PostgresStore) Increment(_ context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	counter, err := p.RateLimitRepository.Increment(key, count, rate.Period)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+	return common.GetContextFromState(time.Now(), rate, counter.ExpiresAt, counter.Count), nil
+}
+
+func (p *PostgresStore) Peek(_ context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	counter, err := p.RateLimitRepository.Get(key, rate.Period)
+	if err != nil {
+		return limiter.Context{}, err
+	}
+	return common.GetContextFromState(time.Now(), rate, counter.ExpiresAt, counter.Count), nil
+}
+
+func (p *PostgresStore) Reset(_ context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	if err := p.RateLimitRepository.Reset(key); err != nil {
+		return limiter.Context{}, err
+	}
+	return common.GetContextFromState(time.Now(), rate, time.Now().Add(rate.Period), 0), nil
+}
@@ -1,14 +1,94 @@
 package middlewares
 
 import (
+	"fmt"
+	"math"
+	"realTimeEditor/internal/apperror"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/model"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/ulule/limiter/v3"
-	ginLimiter "github.com/ulule/limiter/v3/drivers/middleware/gin"
-	memstore "github.com/ulule/limiter/v3/drivers/store/memory"
 )
 
-func RateLimiterMiddleware() gin.HandlerFunc {
-	rate, _ := limiter.NewRateFromFormatted("50-M")
-	store := memstore.NewStore()
-	return ginLimiter.NewMiddleware(limiter.New(store, rate))
+// RateLimiter enforces named rate limit policies. Requests are counted per
+// user once a route's auth middleware has run and per client IP otherwise,
+// so each route group applies its policy after authentication.
+type RateLimiter struct {
+	store    limiter.Store
+	policies map[string]limiter.Rate
+}
+
+// NewRateLimiter parses the policies, given as "<limit>-<S|M|H|D>" rates by
+// name.
+func NewRateLimiter(store limiter.Store, policies map[string]string) (*RateLimiter, error) {
+	rates := make(map[string]limiter.Rate, len(policies))
+	for name, formatted := range policies {
+		rate, err := limiter.NewRateFromFormatted(formatted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit for %s: %w", name, err)
+		}
+		rates[name] = rate
+	}
+	return &RateLimiter{
+		store:    store,
+		policies: rates,
+	}, nil
+}
+
+// Limit applies the named policy. It sets the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers for
+// whichever applied policy has the fewest requests left, and answers 429
+// with Retry-After once the limit is reached.
+func (r *RateLimiter) Limit(policy string) gin.HandlerFunc {
+	rate, ok := r.policies[policy]
+	if !ok {
+		panic(fmt.Sprintf("unknown rate limit policy %q", policy))
+	}
+
+	return func(c *gin.Context) {
+		state, err := r.store.Get(c, policy+":"+rateLimitKey(c), rate)
+		if err != nil {
+			// A store outage should not take the API down with it.
+			logging.From(c).Error("Error checking rate limit", "policy", policy, "error", err)
+			c.Next()
+			return
+		}
+
+		reset := max(int64(math.Ceil(time.Until(time.Unix(state.Reset, 0)).Seconds())), 0)
+		if current := c.Writer.Header().Get("RateLimit-Remaining"); current == "" || state.Remaining <= parseHeaderInt(current) {
+			c.Header("RateLimit-Limit", strconv.FormatInt(state.Limit, 10))
+			c.Header("RateLimit-Remaining", strconv.FormatInt(state.Remaining, 10))
+			c.Header("RateLimit-Reset", strconv.FormatInt(reset, 10))
+			c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rate.Limit, int64(rate.Period.Seconds())))
+		}
+
+		if state.Reached {
+			c.Header("Retry-After", strconv.FormatInt(reset, 10))
+			c.Error(apperror.TooManyRequests("Too many requests; try again later"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func rateLimitKey(c *gin.Context) string {
+	if user, exists := c.Get("user"); exists {
+		if userDetails, ok := user.(model.User); ok {
+			return "user:" + userDetails.ID.String()
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func parseHeaderInt(value string) int64 {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return math.MaxInt64
+	}
+	return n
 }
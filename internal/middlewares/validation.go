@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"fmt"
+	"realTimeEditor/internal/apperror"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateRequest checks the path, query and body parameters of a request
+// against the operation of route and answers 400 when they do not match.
+// Authentication is left to the auth middleware, which runs first.
+func ValidateRequest(route *routers.Route) gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		// Uploads are checked by their handlers; validating them here would
+		// read the whole file into memory first.
+		ExcludeRequestBody: isUpload(route.Operation),
+	}
+	options.WithCustomSchemaErrorFunc(schemaErrorMessage)
+
+	return func(c *gin.Context) {
+		pathParams := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+
+		err := openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		})
+		if err != nil {
+			c.Error(apperror.BadRequest(err.Error()))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func isUpload(operation *openapi3.Operation) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return false
+	}
+	return operation.RequestBody.Value.Content.Get("multipart/form-data") != nil
+}
+
+// schemaErrorMessage leaves the schema and value out of the message, which
+// by default dumps both.
+func schemaErrorMessage(err *openapi3.SchemaError) string {
+	reason := err.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("doesn't match schema %q", err.SchemaField)
+	}
+	if pointer := err.JSONPointer(); len(pointer) > 0 {
+		return fmt.Sprintf("/%s: %s", strings.Join(pointer, "/"), reason)
+	}
+	return reason
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var ErrAuditLogImmutable = errors.New("audit log entries are append-only")
+
+// AuditLog is an append-only record of a security relevant action. Hooks
+// refuse updates and deletes through GORM; the database trigger in
+// db/migrations enforces the same for raw SQL.
+type AuditLog struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	ActorID    *uuid.UUID     `gorm:"type:uuid;index;default:null" json:"actorId"`
+	ActorEmail string         `gorm:"type:varchar(255)" json:"actorEmail,omitempty"`
+	Action     AuditAction    `gorm:"type:varchar(64);not null;index" json:"action"`
+	TargetType string         `gorm:"type:varchar(64)" json:"targetType"`
+	TargetID   string         `gorm:"type:varchar(255);index" json:"targetId"`
+	DocumentID *uuid.UUID     `gorm:"type:uuid;index;default:null" json:"documentId"`
+	Before     datatypes.JSON `gorm:"type:jsonb" json:"before,omitempty"`
+	After      datatypes.JSON `gorm:"type:jsonb" json:"after,omitempty"`
+	IPAddress  string         `gorm:"type:varchar(64)" json:"ipAddress"`
+	UserAgent  string         `gorm:"type:text" json:"userAgent"`
+	CreatedAt  time.Time      `gorm:"type:timestamp;index" json:"createdAt"`
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	a.ID = uuid.New()
+	a.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EmailVerification is a verification link sent to a user. Only the hash of
+// the link's token is stored.
+type EmailVerification struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"userId"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"type:timestamp;not null" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"type:timestamp;default:null" json:"usedAt"`
+	CreatedAt time.Time  `gorm:"type:timestamp;index" json:"createdAt"`
+}
+
+func (e *EmailVerification) BeforeCreate(tx *gorm.DB) error {
+	e.ID = uuid.New()
+	e.CreatedAt = time.Now().UTC()
+	return nil
+}
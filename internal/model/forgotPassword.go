@@ -7,12 +7,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// ForgotPassword is a single password reset attempt. The emailed code and,
+// once the code is verified, the reset token are stored only as hashes.
 type ForgotPassword struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Email     string    `gorm:"type:varchar(255)" json:"email"`
-	ResetCode string    `gorm:"type:varchar(255)" json:"resetCode"`
-	CreatedAt time.Time `gorm:"type:timestamp" json:"createdAt"`
-	UpdatedAt time.Time `gorm:"type:timestamp" json:"updatedAt"`
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;index" json:"userId"`
+	Email          string     `gorm:"type:varchar(255)" json:"email"`
+	CodeHash       string     `gorm:"type:varchar(64)" json:"-"`
+	ResetTokenHash *string    `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt      time.Time  `gorm:"type:timestamp" json:"expiresAt"`
+	VerifiedAt     *time.Time `gorm:"type:timestamp;default:null" json:"verifiedAt"`
+	UsedAt         *time.Time `gorm:"type:timestamp;default:null" json:"usedAt"`
+	CreatedAt      time.Time  `gorm:"type:timestamp" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"type:timestamp" json:"updatedAt"`
 }
 
 func (forgotPassword *ForgotPassword) BeforeCreate(tx *gorm.DB) error {
@@ -13,11 +13,15 @@ type Invite struct {
 	Email          *string      `gorm:"type:varchar(255);index;not null" json:"email"`
 	DocumentId     uuid.UUID    `gorm:"type:uuid;not null;index" json:"documentId"`
 	Role           Role         `gorm:"type:varchar(20);not null" json:"role"`
-	Token          string       `gorm:"type:varchar(64);not null;uniqueIndex" json:"token"`
+	Token          string       `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
 	Status         InviteStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
 	InviterId      uuid.UUID    `gorm:"type:uuid;index;default:null" json:"inviterId"`
-	CreatedAt      time.Time    `gorm:"type:timestamp" json:"createdAt"`
-	UpdatedAt      time.Time    `gorm:"type:timestamp" json:"updatedAt"`
+	ExpiresAt      *time.Time   `gorm:"type:timestamp;default:null" json:"expiresAt"`
+	// AccountCompletedAt is set when a user created by accepting this invite
+	// sets up their account, so the completion link works only once.
+	AccountCompletedAt *time.Time `gorm:"type:timestamp;default:null" json:"-"`
+	CreatedAt          time.Time  `gorm:"type:timestamp" json:"createdAt"`
+	UpdatedAt          time.Time  `gorm:"type:timestamp" json:"updatedAt"`
 }
 
 func (i *Invite) BeforeCreate(tx *gorm.DB) error {
@@ -26,3 +30,8 @@ func (i *Invite) BeforeCreate(tx *gorm.DB) error {
 	i.UpdatedAt = time.Now().UTC()
 	return nil
 }
+
+// Open reports whether the invite can still be accepted.
+func (i *Invite) Open() bool {
+	return i.Status == InviteStatus(Pending) && (i.ExpiresAt == nil || i.ExpiresAt.After(time.Now().UTC()))
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type Notification struct {
+	ID         uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID         `gorm:"type:uuid;not null;index" json:"userId"`
+	ActorID    *uuid.UUID        `gorm:"type:uuid;default:null" json:"actorId"`
+	DocumentID *uuid.UUID        `gorm:"type:uuid;index;default:null" json:"documentId"`
+	Event      NotificationEvent `gorm:"type:varchar(50);not null" json:"event"`
+	Title      string            `gorm:"type:varchar(255)" json:"title"`
+	Body       string            `gorm:"type:text" json:"body"`
+	Link       string            `gorm:"type:text" json:"link,omitempty"`
+	Data       datatypes.JSON    `gorm:"type:jsonb" json:"data,omitempty"`
+	ReadAt     *time.Time        `gorm:"type:timestamp;index" json:"readAt"`
+	CreatedAt  time.Time         `gorm:"type:timestamp;index" json:"createdAt"`
+	UpdatedAt  time.Time         `gorm:"type:timestamp" json:"updatedAt"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	n.ID = uuid.New()
+	n.CreatedAt = time.Now().UTC()
+	n.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// NotificationPreference records how a user wants to hear about one event type.
+// Missing rows fall back to DefaultNotificationPreference.
+type NotificationPreference struct {
+	ID        uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_user_event" json:"userId"`
+	Event     NotificationEvent `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_event" json:"event"`
+	InApp     bool              `gorm:"type:boolean;not null;default:true" json:"inApp"`
+	Email     bool              `gorm:"type:boolean;not null;default:false" json:"email"`
+	CreatedAt time.Time         `gorm:"type:timestamp" json:"createdAt"`
+	UpdatedAt time.Time         `gorm:"type:timestamp" json:"updatedAt"`
+}
+
+func (p *NotificationPreference) BeforeCreate(tx *gorm.DB) error {
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now().UTC()
+	p.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func DefaultNotificationPreference(userID uuid.UUID, event NotificationEvent) NotificationPreference {
+	return NotificationPreference{
+		UserID: userID,
+		Event:  event,
+		InApp:  true,
+		Email:  event == NotificationInviteReceived,
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token so leaked
+// tokens can be recognised, e.g. by secret scanners.
+const PersonalAccessTokenPrefix = "rte_pat_"
+
+// PersonalAccessToken is a long-lived, scoped credential for scripts. Only the
+// token's hash is stored; Prefix is kept so users can tell tokens apart.
+type PersonalAccessToken struct {
+	ID         uuid.UUID                       `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID                       `gorm:"type:uuid;not null;index" json:"userId"`
+	User       User                            `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Name       string                          `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string                          `gorm:"type:varchar(32);not null" json:"prefix"`
+	TokenHash  string                          `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes     datatypes.JSONSlice[TokenScope] `gorm:"type:jsonb" json:"scopes"`
+	ExpiresAt  *time.Time                      `gorm:"type:timestamp;default:null" json:"expiresAt"`
+	LastUsedAt *time.Time                      `gorm:"type:timestamp;default:null" json:"lastUsedAt"`
+	LastUsedIP string                          `gorm:"type:varchar(64)" json:"lastUsedIp"`
+	RevokedAt  *time.Time                      `gorm:"type:timestamp;default:null" json:"revokedAt"`
+	CreatedAt  time.Time                       `gorm:"type:timestamp" json:"createdAt"`
+}
+
+func (p *PersonalAccessToken) BeforeCreate(tx *gorm.DB) error {
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+func (p *PersonalAccessToken) Active() bool {
+	return p.RevokedAt == nil && (p.ExpiresAt == nil || p.ExpiresAt.After(time.Now().UTC()))
+}
+
+func (p *PersonalAccessToken) HasScope(scope TokenScope) bool {
+	return slices.Contains(p.Scopes, scope)
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// RateLimitCounter is the number of requests counted against a rate limit
+// key in the window ending at ExpiresAt.
+type RateLimitCounter struct {
+	Key       string    `gorm:"type:varchar(255);primaryKey" json:"key"`
+	Count     int64     `gorm:"not null;default:0" json:"count"`
+	ExpiresAt time.Time `gorm:"type:timestamp;not null;index" json:"expiresAt"`
+}
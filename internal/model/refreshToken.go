@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken is one issued refresh token. Its ID is the token's jti, and
+// every token rotated from the same login shares a FamilyID so that reuse of
+// an old token can revoke the whole chain.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	FamilyID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"familyId"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"userId"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	ExpiresAt time.Time  `gorm:"type:timestamp;not null;index" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"type:timestamp;default:null" json:"usedAt"`
+	RevokedAt *time.Time `gorm:"type:timestamp;default:null" json:"revokedAt"`
+	IPAddress string     `gorm:"type:varchar(64)" json:"ipAddress"`
+	UserAgent string     `gorm:"type:text" json:"userAgent"`
+	CreatedAt time.Time  `gorm:"type:timestamp" json:"createdAt"`
+}
+
+func (r *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	r.CreatedAt = time.Now().UTC()
+	return nil
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TwoFactorAuth holds a user's TOTP secret. It exists but is not Enabled
+// between enrollment and the first confirmed code.
+type TwoFactorAuth struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"userId"`
+	User           User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Secret         string     `gorm:"type:varchar(64);not null" json:"-"`
+	Enabled        bool       `gorm:"type:boolean;default:false" json:"enabled"`
+	ConfirmedAt    *time.Time `gorm:"type:timestamp;default:null" json:"confirmedAt"`
+	LastUsedStep   int64      `gorm:"type:bigint;default:0" json:"-"`
+	FailedAttempts int        `gorm:"type:int;default:0" json:"-"`
+	LockedUntil    *time.Time `gorm:"type:timestamp;default:null" json:"-"`
+	CreatedAt      time.Time  `gorm:"type:timestamp" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"type:timestamp" json:"updatedAt"`
+}
+
+func (t *TwoFactorAuth) BeforeCreate(tx *gorm.DB) error {
+	t.ID = uuid.New()
+	t.CreatedAt = time.Now().UTC()
+	t.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (t *TwoFactorAuth) BeforeUpdate(tx *gorm.DB) error {
+	t.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// RecoveryCode is a single-use 2FA fallback; only its hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"userId"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	CodeHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	UsedAt    *time.Time `gorm:"type:timestamp;default:null" json:"usedAt"`
+	CreatedAt time.Time  `gorm:"type:timestamp" json:"createdAt"`
+}
+
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	r.CreatedAt = time.Now().UTC()
+	return nil
+}
@@ -11,7 +11,111 @@ const (
 type InviteStatus string
 
 const (
-	Pending  Role = "pending"
-	Accepted Role = "accepted"
-	Declined Role = "declined"
+	Pending   Role = "pending"
+	Accepted  Role = "accepted"
+	Declined  Role = "declined"
+	Cancelled Role = "cancelled"
+	Expired   Role = "expired"
 )
+
+type NotificationEvent string
+
+const (
+	NotificationInviteReceived       NotificationEvent = "invite.received"
+	NotificationAccessModified       NotificationEvent = "access.modified"
+	NotificationAccessRevoked        NotificationEvent = "access.revoked"
+	NotificationOwnershipTransferred NotificationEvent = "ownership.transferred"
+	NotificationCommentCreated       NotificationEvent = "comment.created"
+	NotificationCommentMention       NotificationEvent = "comment.mention"
+)
+
+var NotificationEvents = []NotificationEvent{
+	NotificationInviteReceived,
+	NotificationAccessModified,
+	NotificationAccessRevoked,
+	NotificationOwnershipTransferred,
+	NotificationCommentCreated,
+	NotificationCommentMention,
+}
+
+type WebhookEvent string
+
+const (
+	WebhookDocumentCreated      WebhookEvent = "document.created"
+	WebhookDocumentUpdated      WebhookEvent = "document.updated"
+	WebhookDocumentDeleted      WebhookEvent = "document.deleted"
+	WebhookAccessGranted        WebhookEvent = "access.granted"
+	WebhookAccessRevoked        WebhookEvent = "access.revoked"
+	WebhookInviteAccepted       WebhookEvent = "invite.accepted"
+	WebhookOwnershipTransferred WebhookEvent = "ownership.transferred"
+	WebhookPing                 WebhookEvent = "ping"
+)
+
+var WebhookEvents = []WebhookEvent{
+	WebhookDocumentCreated,
+	WebhookDocumentUpdated,
+	WebhookDocumentDeleted,
+	WebhookAccessGranted,
+	WebhookAccessRevoked,
+	WebhookInviteAccepted,
+	WebhookOwnershipTransferred,
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+type AuditAction string
+
+const (
+	AuditAccessRevoked          AuditAction = "access.revoked"
+	AuditAccessModified         AuditAction = "access.modified"
+	AuditOwnershipTransferred   AuditAction = "ownership.transferred"
+	AuditVisibilityToggled      AuditAction = "document.visibility_toggled"
+	AuditDocumentDeleted        AuditAction = "document.deleted"
+	AuditInviteCreated          AuditAction = "invite.created"
+	AuditInviteAccepted         AuditAction = "invite.accepted"
+	AuditInviteResent           AuditAction = "invite.resent"
+	AuditInviteCancelled        AuditAction = "invite.cancelled"
+	AuditInviteDeclined         AuditAction = "invite.declined"
+	AuditLoginSucceeded         AuditAction = "user.login_succeeded"
+	AuditLoginFailed            AuditAction = "user.login_failed"
+	AuditPasswordResetRequested AuditAction = "user.password_reset_requested"
+	AuditPasswordResetVerified  AuditAction = "user.password_reset_verified"
+	AuditPasswordReset          AuditAction = "user.password_reset"
+	AuditLogoutAll              AuditAction = "user.logout_all"
+	AuditRefreshTokenReused     AuditAction = "user.refresh_token_reused"
+	AuditTwoFactorEnabled       AuditAction = "user.2fa_enabled"
+	AuditTwoFactorDisabled      AuditAction = "user.2fa_disabled"
+	AuditRecoveryCodesRenewed   AuditAction = "user.2fa_recovery_codes_regenerated"
+	AuditIdentityLinked         AuditAction = "user.identity_linked"
+	AuditIdentityUnlinked       AuditAction = "user.identity_unlinked"
+	AuditTokenCreated           AuditAction = "token.created"
+	AuditTokenRevoked           AuditAction = "token.revoked"
+	AuditEmailVerified          AuditAction = "user.email_verified"
+	AuditAccountLockedOut       AuditAction = "user.locked_out"
+	AuditAccountUnlocked        AuditAction = "user.unlocked"
+	AuditIPLockedOut            AuditAction = "auth.ip_locked_out"
+	AuditAdminGranted           AuditAction = "user.admin_granted"
+	AuditAdminRevoked           AuditAction = "user.admin_revoked"
+)
+
+// TokenScope limits what a personal access token may do. Sessions from a
+// login are not scoped.
+type TokenScope string
+
+const (
+	ScopeDocumentsRead  TokenScope = "documents:read"
+	ScopeDocumentsWrite TokenScope = "documents:write"
+	ScopeSharingManage  TokenScope = "sharing:manage"
+)
+
+var TokenScopes = []TokenScope{
+	ScopeDocumentsRead,
+	ScopeDocumentsWrite,
+	ScopeSharingManage,
+}
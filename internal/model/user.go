@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,8 +15,14 @@ type User struct {
 	Email        string    `gorm:"type:varchar(255);uniqueIndex" json:"email"`
 	Password     *string   `gorm:"type:varchar(255)" json:"password"`
 	ProfilePhoto *Media    `gorm:"type:jsonb" json:"profilePhoto"`
-	CreatedAt    time.Time `gorm:"type:timestamp" json:"createdAt"`
-	UpdatedAt    time.Time `gorm:"type:timestamp" json:"updatedAt"`
+	Locale       string    `gorm:"type:varchar(10);default:'en'" json:"locale"`
+	IsAdmin      bool      `gorm:"type:boolean;default:false" json:"-"`
+	// EmailVerifiedAt is set once the user has proven they own Email.
+	EmailVerifiedAt *time.Time `gorm:"type:timestamp;default:null" json:"emailVerifiedAt"`
+	// TokensRevokedAt rejects access tokens issued before it; set by logout-all.
+	TokensRevokedAt *time.Time `gorm:"type:timestamp;default:null" json:"-"`
+	CreatedAt       time.Time  `gorm:"type:timestamp" json:"createdAt"`
+	UpdatedAt       time.Time  `gorm:"type:timestamp" json:"updatedAt"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -24,3 +31,36 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	u.UpdatedAt = time.Now().UTC()
 	return nil
 }
+
+// FullName is the user's first and last name, whichever are set, or their
+// email when neither is.
+func (u *User) FullName() string {
+	var parts []string
+	if u.FirstName != nil && *u.FirstName != "" {
+		parts = append(parts, *u.FirstName)
+	}
+	if u.LastName != nil && *u.LastName != "" {
+		parts = append(parts, *u.LastName)
+	}
+	if len(parts) == 0 {
+		return u.Email
+	}
+	return strings.Join(parts, " ")
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// TokensRevokedAfter returns the TokensRevokedAt that rejects every access
+// token issued up to at. Access tokens carry iat in whole seconds, so it
+// rounds up: a token issued earlier in the same second must not survive.
+func TokensRevokedAfter(at time.Time) time.Time {
+	return at.UTC().Truncate(time.Second).Add(time.Second)
+}
+
+// AccessTokenRevoked reports whether an access token issued at issuedAt was
+// issued before the user's sessions were revoked.
+func (u *User) AccessTokenRevoked(issuedAt time.Time) bool {
+	return u.TokensRevokedAt != nil && issuedAt.Before(*u.TokensRevokedAt)
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a user to an account at an external OIDC provider,
+// identified by the provider's stable subject claim.
+type UserIdentity struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_user_identity_user_provider" json:"userId"`
+	User        User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Provider    string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_user_identity_user_provider;uniqueIndex:idx_user_identity_provider_subject" json:"provider"`
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identity_provider_subject" json:"-"`
+	Email       string     `gorm:"type:varchar(255)" json:"email"`
+	LastLoginAt *time.Time `gorm:"type:timestamp;default:null" json:"lastLoginAt"`
+	CreatedAt   time.Time  `gorm:"type:timestamp" json:"createdAt"`
+}
+
+func (u *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	u.ID = uuid.New()
+	u.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// OIDCAuthRequest is the server side state of an authorization code flow in
+// progress. It is consumed by the callback and only lives a few minutes.
+type OIDCAuthRequest struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	StateHash    string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Provider     string     `gorm:"type:varchar(64);not null" json:"provider"`
+	Nonce        string     `gorm:"type:varchar(128);not null" json:"-"`
+	CodeVerifier string     `gorm:"type:varchar(128);not null" json:"-"`
+	LinkUserID   *uuid.UUID `gorm:"type:uuid;default:null" json:"linkUserId"`
+	ExpiresAt    time.Time  `gorm:"type:timestamp;not null;index" json:"expiresAt"`
+	CreatedAt    time.Time  `gorm:"type:timestamp" json:"createdAt"`
+}
+
+func (o *OIDCAuthRequest) BeforeCreate(tx *gorm.DB) error {
+	o.ID = uuid.New()
+	o.CreatedAt = time.Now().UTC()
+	return nil
+}
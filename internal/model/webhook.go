@@ -0,0 +1,63 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Webhook is a subscription owned by a user. With a DocumentID it only fires
+// for that document; without one it fires for every document the user owns.
+type Webhook struct {
+	ID         uuid.UUID                         `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID                         `gorm:"type:uuid;not null;index" json:"userId"`
+	DocumentID *uuid.UUID                        `gorm:"type:uuid;index;default:null" json:"documentId"`
+	URL        string                            `gorm:"type:text;not null" json:"url"`
+	Secret     string                            `gorm:"type:varchar(128);not null" json:"-"`
+	Events     datatypes.JSONSlice[WebhookEvent] `gorm:"type:jsonb" json:"events"`
+	Active     bool                              `gorm:"type:boolean;not null;default:true" json:"active"`
+	CreatedAt  time.Time                         `gorm:"type:timestamp" json:"createdAt"`
+	UpdatedAt  time.Time                         `gorm:"type:timestamp" json:"updatedAt"`
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	w.ID = uuid.New()
+	w.CreatedAt = time.Now().UTC()
+	w.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (w *Webhook) Subscribes(event WebhookEvent) bool {
+	return event == WebhookPing || slices.Contains(w.Events, event)
+}
+
+// WebhookDelivery is both the outbox row the worker consumes and the delivery log.
+type WebhookDelivery struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	WebhookID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"webhookId"`
+	Event          WebhookEvent   `gorm:"type:varchar(50);not null" json:"event"`
+	Payload        datatypes.JSON `gorm:"type:jsonb" json:"payload"`
+	Status         DeliveryStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts       int            `gorm:"type:int;not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time      `gorm:"type:timestamp;index" json:"nextAttemptAt"`
+	LastStatusCode int            `gorm:"type:int" json:"lastStatusCode,omitempty"`
+	LastError      string         `gorm:"type:text" json:"lastError,omitempty"`
+	DeliveredAt    *time.Time     `gorm:"type:timestamp" json:"deliveredAt"`
+	CreatedAt      time.Time      `gorm:"type:timestamp" json:"createdAt"`
+	UpdatedAt      time.Time      `gorm:"type:timestamp" json:"updatedAt"`
+
+	Webhook Webhook `gorm:"foreignKey:WebhookID" json:"-"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	d.ID = uuid.New()
+	d.CreatedAt = time.Now().UTC()
+	d.UpdatedAt = time.Now().UTC()
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = d.CreatedAt
+	}
+	return nil
+}
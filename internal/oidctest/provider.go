@@ -0,0 +1,226 @@
+// Package oidctest runs a minimal OpenID Connect provider for tests. It
+// serves discovery, a JWKS and a token endpoint that checks PKCE; the
+// authorization step is skipped, tests call Authorize with the parameters
+// of the URL they would have sent the browser to.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const keyID = "oidctest"
+
+// Claims are the ID token claims the provider issues for a code.
+type Claims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Locale        string `json:"locale,omitempty"`
+	// Nonce overrides the nonce of the authorization request when set.
+	Nonce string `json:"-"`
+}
+
+type grant struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	claims        Claims
+}
+
+// Provider is a running stub provider; URL is its issuer.
+type Provider struct {
+	URL      string
+	ClientID string
+
+	t      testing.TB
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+// NewProvider starts a provider for clientID, stopped when the test ends.
+func NewProvider(t testing.TB, clientID string) *Provider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	p := &Provider{
+		ClientID: clientID,
+		t:        t,
+		key:      key,
+		grants:   map[string]grant{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("POST /token", p.token)
+	p.server = httptest.NewServer(mux)
+	p.URL = p.server.URL
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// Authorize plays the user approving the request at authURL and returns the
+// code the provider would redirect back with. The ID token for it carries
+// claims.
+func (p *Provider) Authorize(authURL string, claims Claims) (state, code string) {
+	p.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("parsing auth URL: %v", err)
+	}
+	query := u.Query()
+	if got := query.Get("code_challenge_method"); got != "S256" {
+		p.t.Fatalf("code_challenge_method = %q, want S256", got)
+	}
+	for _, param := range []string{"state", "nonce", "code_challenge"} {
+		if query.Get(param) == "" {
+			p.t.Fatalf("auth URL has no %s", param)
+		}
+	}
+
+	code = rand.Text()
+	p.mu.Lock()
+	p.grants[code] = grant{
+		clientID:      query.Get("client_id"),
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+		claims:        claims,
+	}
+	p.mu.Unlock()
+	return query.Get("state"), code
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	g, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	clientID, _, hasBasic := r.BasicAuth()
+	if !hasBasic {
+		clientID = r.PostForm.Get("client_id")
+	}
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code", !ok:
+		tokenError(w, "invalid_grant")
+		return
+	case clientID != g.clientID || r.PostForm.Get("redirect_uri") != g.redirectURI:
+		tokenError(w, "invalid_client")
+		return
+	case challenge(r.PostForm.Get("code_verifier")) != g.codeChallenge:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	nonce := g.nonce
+	if g.claims.Nonce != "" {
+		nonce = g.claims.Nonce
+	}
+	idToken, err := p.sign(g.claims, nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) sign(claims Claims, nonce string) (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(struct {
+		Claims
+		Issuer   string `json:"iss"`
+		Audience string `json:"aud"`
+		Nonce    string `json:"nonce"`
+		IssuedAt int64  `json:"iat"`
+		Expiry   int64  `json:"exp"`
+	}{claims, p.URL, p.ClientID, nonce, now.Unix(), now.Add(5 * time.Minute).Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing ID token: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func challenge(verifier string) string {
+	digest := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository deliberately has no update or delete methods.
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{
+		db: db,
+	}
+}
+
+func (a *AuditLogRepository) Create(entry *model.AuditLog) error {
+	return a.db.Create(entry).Error
+}
+
+func (a *AuditLogRepository) GetDocumentLogs(documentId uuid.UUID, limit, offset int) ([]model.AuditLog, error) {
+	var entries []model.AuditLog
+	err := a.db.Where("document_id = ?", documentId).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching audit logs: %w", err)
+	}
+	return entries, nil
+}
+
+// StreamRange calls fn with batches of entries created in [from, to), oldest
+// first, so exports do not hold the whole log in memory.
+func (a *AuditLogRepository) StreamRange(from, to time.Time, fn func([]model.AuditLog) error) error {
+	const batchSize = 500
+
+	query := a.db.Where("created_at >= ? AND created_at < ?", from, to)
+	var last *model.AuditLog
+	for {
+		var batch []model.AuditLog
+		tx := query.Session(&gorm.Session{})
+		if last != nil {
+			tx = tx.Where("(created_at, id) > (?, ?)", last.CreatedAt, last.ID)
+		}
+		if err := tx.Order("created_at, id").Limit(batchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("error fetching audit logs: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		last = &batch[len(batch)-1]
+	}
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"realTimeEditor/internal/model"
@@ -21,6 +22,12 @@ func NewDocumentRepository(db *gorm.DB) *DocumentRepository {
 	}
 }
 
+// WithContext returns a copy of the repository whose queries run under ctx,
+// so they are traced as part of the request.
+func (d *DocumentRepository) WithContext(ctx context.Context) *DocumentRepository {
+	return &DocumentRepository{db: d.db.WithContext(ctx)}
+}
+
 func (d *DocumentRepository) Create(document *model.Document) error {
 	return d.db.Create(document).Error
 }
@@ -37,6 +44,19 @@ func (d *DocumentRepository) GetUserDocuments(userId uuid.UUID) ([]model.Documen
 	return documents, nil
 }
 
+// List returns documents newest first, only those of ownerId when it is set.
+func (d *DocumentRepository) List(ownerId *uuid.UUID, limit int) ([]model.Document, error) {
+	query := d.db.Order("created_at DESC").Limit(limit)
+	if ownerId != nil {
+		query = query.Where("user_id = ?", *ownerId)
+	}
+	var documents []model.Document
+	if err := query.Find(&documents).Error; err != nil {
+		return nil, fmt.Errorf("error listing documents: %w", err)
+	}
+	return documents, nil
+}
+
 func (d *DocumentRepository) ToggleVisibility(id uuid.UUID) error {
 	var existingDoc model.Document
 
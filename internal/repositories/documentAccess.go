@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -21,10 +22,20 @@ func NewDocumentAccessRepository(db *gorm.DB) *DocumentAccessRepository {
 	}
 }
 
+// WithContext returns a copy of the repository whose queries run under ctx,
+// so they are traced as part of the request.
+func (d *DocumentAccessRepository) WithContext(ctx context.Context) *DocumentAccessRepository {
+	return &DocumentAccessRepository{db: d.db.WithContext(ctx)}
+}
+
 func (d *DocumentAccessRepository) Create(documentAccess *model.DocumentAccess) error {
 	return d.db.Create(documentAccess).Error
 }
 
+func (d *DocumentAccessRepository) CreateWithTransaction(tx *gorm.DB, documentAccess *model.DocumentAccess) error {
+	return tx.Create(documentAccess).Error
+}
+
 func (d *DocumentAccessRepository) GetDocumentAccesses(documentId uuid.UUID) ([]model.DocumentAccess, error) {
 	var documentAccesses []model.DocumentAccess
 
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"realTimeEditor/internal/model"
 	"time"
@@ -19,6 +20,12 @@ func NewDocumentMediaRepository(db *gorm.DB) *DocumentMediaRepository {
 	}
 }
 
+// WithContext returns a copy of the repository whose queries run under ctx,
+// so they are traced as part of the request.
+func (r *DocumentMediaRepository) WithContext(ctx context.Context) *DocumentMediaRepository {
+	return &DocumentMediaRepository{db: r.db.WithContext(ctx)}
+}
+
 func (r *DocumentMediaRepository) Create(media *model.DocumentMedia) error {
 	return r.db.Create(media).Error
 }
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"realTimeEditor/internal/model"
 	"time"
@@ -19,10 +20,20 @@ func NewDocumentMetaDataRepository(db *gorm.DB) *DocumentMetaDataRepository {
 	}
 }
 
+// WithContext returns a copy of the repository whose queries run under ctx,
+// so they are traced as part of the request.
+func (d *DocumentMetaDataRepository) WithContext(ctx context.Context) *DocumentMetaDataRepository {
+	return &DocumentMetaDataRepository{db: d.db.WithContext(ctx)}
+}
+
 func (d *DocumentMetaDataRepository) Create(metaData *model.DocumentMetadata) error {
 	return d.db.Create(metaData).Error
 }
 
+func (d *DocumentMetaDataRepository) CreateWithTransaction(tx *gorm.DB, metaData *model.DocumentMetadata) error {
+	return tx.Create(metaData).Error
+}
+
 func (d *DocumentMetaDataRepository) GetAll() ([]model.DocumentMetadata, error) {
 	var metaData []model.DocumentMetadata
 	if err := d.db.Find(&metaData).Error; err != nil {
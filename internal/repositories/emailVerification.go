@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{
+		db: db,
+	}
+}
+
+func (e *EmailVerificationRepository) Create(verification *model.EmailVerification) error {
+	return e.db.Create(verification).Error
+}
+
+// GetSentSince returns the user's verifications created after since, newest
+// first, for rate limiting resends.
+func (e *EmailVerificationRepository) GetSentSince(userId uuid.UUID, since time.Time) ([]model.EmailVerification, error) {
+	var verifications []model.EmailVerification
+	err := e.db.Where("user_id = ? AND created_at > ?", userId, since).
+		Order("created_at DESC").
+		Find(&verifications).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching email verifications: %w", err)
+	}
+	return verifications, nil
+}
+
+// Consume marks the unexpired verification for tokenHash as used and returns
+// it, so a link works only once.
+func (e *EmailVerificationRepository) Consume(tokenHash string, verification *model.EmailVerification) error {
+	now := time.Now().UTC()
+	result := e.db.Model(verification).Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return fmt.Errorf("error consuming email verification: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes verifications that expired before cutoff.
+func (e *EmailVerificationRepository) DeleteExpired(cutoff time.Time) (int64, error) {
+	result := e.db.Where("expires_at < ?", cutoff).Delete(&model.EmailVerification{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error deleting expired email verifications: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// translateUniqueViolation maps a Postgres unique violation to
+// gorm.ErrDuplicatedKey, which the DB config does not do globally.
+func translateUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return gorm.ErrDuplicatedKey
+	}
+	return err
+}
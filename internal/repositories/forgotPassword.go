@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ForgotPasswordRepository struct {
@@ -23,31 +24,104 @@ func (f *ForgotPasswordRepository) Create(forgotPassword *model.ForgotPassword)
 	return f.db.Create(forgotPassword).Error
 }
 
-func (f *ForgotPasswordRepository) GetAll() ([]model.ForgotPassword, error) {
-	var forgotPasswords []model.ForgotPassword
-	if err := f.db.Find(&forgotPasswords).Error; err != nil {
-		return nil, fmt.Errorf("error fetching forgotPasswords: %s", err)
+// Reissue invalidates every outstanding reset for the user and stores
+// forgotPassword in its place, so only the latest code can be used.
+func (f *ForgotPasswordRepository) Reissue(forgotPassword *model.ForgotPassword) error {
+	return f.db.Transaction(func(tx *gorm.DB) error {
+		if err := invalidateResets(tx, forgotPassword.UserID); err != nil {
+			return err
+		}
+		return tx.Create(forgotPassword).Error
+	})
+}
+
+// GetPendingByEmail returns the latest unexpired reset for email whose code
+// has not been verified yet.
+func (f *ForgotPasswordRepository) GetPendingByEmail(email string, forgotPassword *model.ForgotPassword) error {
+	return f.db.
+		Where("email = ? AND used_at IS NULL AND verified_at IS NULL AND expires_at > ?", email, time.Now().UTC()).
+		Order("created_at DESC").
+		First(forgotPassword).Error
+}
+
+// RecordFailedAttempt counts a wrong code and invalidates the reset once
+// maxAttempts is reached. It returns the number of attempts so far.
+func (f *ForgotPasswordRepository) RecordFailedAttempt(id uuid.UUID, maxAttempts int) (int, error) {
+	var forgotPassword model.ForgotPassword
+	err := f.db.Model(&forgotPassword).Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"used_at":    gorm.Expr("CASE WHEN attempts + 1 >= ? THEN ? ELSE used_at END", maxAttempts, time.Now().UTC()),
+			"updated_at": time.Now().UTC(),
+		}).Error
+	if err != nil {
+		return 0, fmt.Errorf("error recording reset attempt: %w", err)
 	}
-	return forgotPasswords, nil
+	return forgotPassword.Attempts, nil
 }
 
-func (f *ForgotPasswordRepository) GetOne(id uuid.UUID, forgotPassword *model.ForgotPassword) error {
-	return f.db.Where("id = ?", id).First(forgotPassword).Error
+// MarkVerified stores the hash of the reset token issued for a verified code
+// and moves the expiry to expiresAt. It fails with gorm.ErrRecordNotFound if
+// the code was verified or invalidated concurrently.
+func (f *ForgotPasswordRepository) MarkVerified(id uuid.UUID, resetTokenHash string, expiresAt time.Time) error {
+	now := time.Now().UTC()
+	result := f.db.Model(&model.ForgotPassword{}).
+		Where("id = ? AND verified_at IS NULL AND used_at IS NULL AND expires_at > ?", id, now).
+		Updates(map[string]interface{}{
+			"reset_token_hash": resetTokenHash,
+			"verified_at":      now,
+			"expires_at":       expiresAt,
+			"updated_at":       now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("error verifying reset code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-func (f *ForgotPasswordRepository) GetOneByCode(resetCode string, forgotPassword *model.ForgotPassword) error {
-	return f.db.Where("reset_code = ?", resetCode).First(forgotPassword).Error
+// ConsumeResetToken marks the reset for resetTokenHash as used and returns it,
+// so a reset token works only once.
+func (f *ForgotPasswordRepository) ConsumeResetToken(resetTokenHash string, forgotPassword *model.ForgotPassword) error {
+	now := time.Now().UTC()
+	result := f.db.Model(forgotPassword).Clauses(clause.Returning{}).
+		Where("reset_token_hash = ? AND used_at IS NULL AND expires_at > ?", resetTokenHash, now).
+		Updates(map[string]interface{}{"used_at": now, "updated_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("error consuming reset token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-func (f *ForgotPasswordRepository) Update(forgotPassword *model.ForgotPassword, id uuid.UUID) error {
-	if err := f.db.Where("id = ?", id).Error; err != nil {
-		return err
+// InvalidateForUser marks every outstanding reset of the user as used.
+func (f *ForgotPasswordRepository) InvalidateForUser(userId uuid.UUID) error {
+	return invalidateResets(f.db, userId)
+}
+
+// DeleteStale removes resets that expired before cutoff or have been used.
+func (f *ForgotPasswordRepository) DeleteStale(cutoff time.Time) (int64, error) {
+	result := f.db.
+		Where("expires_at < ? OR expires_at IS NULL OR used_at IS NOT NULL", cutoff).
+		Delete(&model.ForgotPassword{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error deleting stale password resets: %w", result.Error)
 	}
-	forgotPassword.UpdatedAt = time.Now().UTC().UTC()
-	return f.db.Model(&model.ForgotPassword{}).
-		Where("id = ?", id).Updates(forgotPassword).Error
+	return result.RowsAffected, nil
 }
 
-func (f *ForgotPasswordRepository) Delete(forgotPassword *model.ForgotPassword, id uuid.UUID) error {
-	return f.db.Delete(forgotPassword, "id = ?", id).Error
+func invalidateResets(db *gorm.DB, userId uuid.UUID) error {
+	now := time.Now().UTC()
+	err := db.Model(&model.ForgotPassword{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Updates(map[string]interface{}{"used_at": now, "updated_at": now}).Error
+	if err != nil {
+		return fmt.Errorf("error invalidating password resets: %w", err)
+	}
+	return nil
 }
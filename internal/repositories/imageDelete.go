@@ -1,18 +1,24 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"realTimeEditor/config"
+	"realTimeEditor/internal/tracing"
 
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func CloudinaryDelete(public_id string, resourceType ResourceType) (*uploader.DestroyResult, error) {
-	cld, ctx, err := config.CloudinaryCredentials()
+func CloudinaryDelete(ctx context.Context, public_id string, resourceType ResourceType) (result *uploader.DestroyResult, err error) {
+	cld, err := config.CloudinaryCredentials()
 	if err != nil {
 		return nil, fmt.Errorf("delete failed: %w", err)
 	}
 
+	ctx, span := tracing.Start(ctx, "cloudinary.delete", attribute.String("cloudinary.resource_type", string(resourceType)))
+	defer func() { tracing.End(span, err) }()
+
 	deleteResult, err := cld.Upload.Destroy(ctx, uploader.DestroyParams{
 		PublicID:     public_id,
 		ResourceType: string(resourceType),
@@ -1,26 +1,32 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"realTimeEditor/config"
+	"realTimeEditor/internal/tracing"
 	"strings"
 	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func CloudinaryUploader(base64Data string, resourceType ResourceType) (UploadedMedia, error) {
+func CloudinaryUploader(ctx context.Context, base64Data string, resourceType ResourceType) (media UploadedMedia, err error) {
 	if !strings.HasPrefix(base64Data, "data:image/") {
 		return UploadedMedia{}, fmt.Errorf("invalid base64 image format")
 	}
 
-	cld, ctx, err := config.CloudinaryCredentials()
+	cld, err := config.CloudinaryCredentials()
 	if err != nil {
 		return UploadedMedia{}, fmt.Errorf("cloudinary initialization failed: %w", err)
 	}
 
+	ctx, span := tracing.Start(ctx, "cloudinary.upload", attribute.String("cloudinary.resource_type", string(resourceType)))
+	defer func() { tracing.End(span, err) }()
+
 	uploadResult, err := cld.Upload.Upload(ctx, base64Data, uploader.UploadParams{
 		AllowedFormats: []string{"png", "jpg", "jpeg", "gif", "webp"},
 		ResourceType:   string(resourceType),
@@ -44,12 +50,15 @@ func PtrBool(b bool) *bool {
 	return &b
 }
 
-func CloudinaryUploaderStream(stream io.Reader, fileName string, resourceType ResourceType) (UploadedMedia, error) {
-	cld, ctx, err := config.CloudinaryCredentials()
+func CloudinaryUploaderStream(ctx context.Context, stream io.Reader, fileName string, resourceType ResourceType) (media UploadedMedia, err error) {
+	cld, err := config.CloudinaryCredentials()
 	if err != nil {
 		return UploadedMedia{}, fmt.Errorf("cloudinary initialization failed: %w", err)
 	}
 
+	ctx, span := tracing.Start(ctx, "cloudinary.upload", attribute.String("cloudinary.resource_type", string(resourceType)))
+	defer func() { tracing.End(span, err) }()
+
 	// Generate UUID for the public ID
 	timestamp := time.Now().UTC().UTC().Unix() // Unix timestamp in seconds
 	uuidStr := strings.ReplaceAll(uuid.New().String(), "-", "")
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"realTimeEditor/internal/model"
 	"time"
@@ -19,6 +20,12 @@ func NewInviteRepository(db *gorm.DB) *InviteRepository {
 	}
 }
 
+// WithContext returns a copy of the repository whose queries run under ctx,
+// so they are traced as part of the request.
+func (i *InviteRepository) WithContext(ctx context.Context) *InviteRepository {
+	return &InviteRepository{db: i.db.WithContext(ctx)}
+}
+
 func (i *InviteRepository) Create(invite *model.Invite) error {
 	return i.db.Create(invite).Error
 }
@@ -55,3 +62,127 @@ func (i *InviteRepository) GetOneByEmailAndDocId(invite *model.Invite, email str
 func (i *InviteRepository) Delete(invite *model.Invite, id uuid.UUID) error {
 	return i.db.Delete(invite, "id = ?", id).Error
 }
+
+func (i *InviteRepository) HasPendingInvite(email string) (bool, error) {
+	var count int64
+	err := i.db.Model(&model.Invite{}).
+		Where("LOWER(email) = LOWER(?) AND status = ? AND (expires_at IS NULL OR expires_at > ?)", email, model.Pending, time.Now().UTC()).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error checking invites: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Reissue gives the invite a new token and expiry and reopens it, so links
+// sent earlier stop working.
+func (i *InviteRepository) Reissue(id uuid.UUID, token string, expiresAt time.Time) error {
+	return i.db.Model(&model.Invite{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"token":      token,
+			"expires_at": expiresAt,
+			"status":     model.Pending,
+			"updated_at": time.Now().UTC(),
+		}).Error
+}
+
+// CompleteAccount records that the user created by accepting the invite has
+// set up their account. It fails with gorm.ErrRecordNotFound if that already
+// happened, so an account completion link works only once.
+func (i *InviteRepository) CompleteAccount(id, userId uuid.UUID) error {
+	now := time.Now().UTC()
+	result := i.db.Model(&model.Invite{}).
+		Where("id = ? AND collaborator_id = ? AND status = ? AND account_completed_at IS NULL", id, userId, model.Accepted).
+		Updates(map[string]interface{}{"account_completed_at": now, "updated_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("error completing account: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetDocumentInvites returns the document's invites with the given status,
+// newest first.
+func (i *InviteRepository) GetDocumentInvites(documentId uuid.UUID, status model.Role) ([]model.Invite, error) {
+	var invites []model.Invite
+	err := i.db.Where("document_id = ? AND status = ?", documentId, status).
+		Order("created_at DESC").
+		Find(&invites).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching document invites: %w", err)
+	}
+	return invites, nil
+}
+
+// GetOpenForEmail returns the unexpired pending invites sent to email.
+func (i *InviteRepository) GetOpenForEmail(email string) ([]model.Invite, error) {
+	var invites []model.Invite
+	err := i.db.
+		Where("LOWER(email) = LOWER(?) AND status = ? AND (expires_at IS NULL OR expires_at > ?)", email, model.Pending, time.Now().UTC()).
+		Order("created_at DESC").
+		Find(&invites).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching invites: %w", err)
+	}
+	return invites, nil
+}
+
+// DeletePending removes pending invites of email to the document, before a
+// new invite replaces them.
+func (i *InviteRepository) DeletePending(email string, documentId uuid.UUID) error {
+	err := i.db.Where("LOWER(email) = LOWER(?) AND document_id = ? AND status = ?", email, documentId, model.Pending).
+		Delete(&model.Invite{}).Error
+	if err != nil {
+		return fmt.Errorf("error deleting pending invites: %w", err)
+	}
+	return nil
+}
+
+// SetStatus moves a pending invite to status. It fails with
+// gorm.ErrRecordNotFound if the invite was no longer pending.
+func (i *InviteRepository) SetStatus(id uuid.UUID, status model.Role) error {
+	result := i.db.Model(&model.Invite{}).Where("id = ? AND status = ?", id, model.Pending).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now().UTC()})
+	if result.Error != nil {
+		return fmt.Errorf("error updating invite: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptWithTransaction marks an open invite accepted. It fails with
+// gorm.ErrRecordNotFound if the invite is no longer pending or has expired,
+// so only one of several concurrent accepts goes through.
+func (i *InviteRepository) AcceptWithTransaction(tx *gorm.DB, id uuid.UUID) error {
+	now := time.Now().UTC()
+	result := tx.Model(&model.Invite{}).
+		Where("id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)", id, model.Pending, now).
+		Updates(map[string]interface{}{"status": model.Accepted, "updated_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("error accepting invite: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (i *InviteRepository) SetCollaboratorWithTransaction(tx *gorm.DB, id, collaboratorId uuid.UUID) error {
+	return tx.Model(&model.Invite{}).Where("id = ?", id).
+		Update("collaborator_id", collaboratorId).Error
+}
+
+// ExpirePending marks pending invites that expired before cutoff as expired.
+func (i *InviteRepository) ExpirePending(cutoff time.Time) (int64, error) {
+	result := i.db.Model(&model.Invite{}).
+		Where("status = ? AND expires_at < ?", model.Pending, cutoff).
+		Updates(map[string]interface{}{"status": model.Expired, "updated_at": time.Now().UTC()})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error expiring invites: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) *LoginThrottleRepository {
+	return &LoginThrottleRepository{
+		db: db,
+	}
+}
+
+func (l *LoginThrottleRepository) Get(kind model.ThrottleKind, key string, throttle *model.LoginThrottle) error {
+	return l.db.Where("kind = ? AND key = ?", kind, key).First(throttle).Error
+}
+
+// RecordFailure adds a failure to the counter for key and returns it. The
+// count starts again from one when the last failure was before windowStart
+// or an earlier lockout has run out.
+func (l *LoginThrottleRepository) RecordFailure(kind model.ThrottleKind, key string, windowStart time.Time, throttle *model.LoginThrottle) error {
+	now := time.Now().UTC()
+	restart := gorm.Expr(
+		"login_throttles.last_failure_at < ? OR (login_throttles.locked_until IS NOT NULL AND login_throttles.locked_until <= ?)",
+		windowStart, now,
+	)
+
+	err := l.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "kind"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"failures":          gorm.Expr("CASE WHEN ? THEN 1 ELSE login_throttles.failures + 1 END", restart),
+			"locked_until":      gorm.Expr("CASE WHEN ? THEN NULL ELSE login_throttles.locked_until END", restart),
+			"unlock_token_hash": gorm.Expr("CASE WHEN ? THEN NULL ELSE login_throttles.unlock_token_hash END", restart),
+			"last_failure_at":   now,
+			"updated_at":        now,
+		}),
+	}).Create(&model.LoginThrottle{
+		Kind:          kind,
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: now,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("error recording login failure: %w", err)
+	}
+
+	return l.Get(kind, key, throttle)
+}
+
+// Lock locks the counter until the given time unless it is already locked.
+// It reports whether this call applied the lock, so only one of several
+// concurrent failures sends the unlock email.
+func (l *LoginThrottleRepository) Lock(throttle *model.LoginThrottle, until time.Time, unlockTokenHash *string) (bool, error) {
+	result := l.db.Model(&model.LoginThrottle{}).
+		Where("id = ? AND (locked_until IS NULL OR locked_until <= ?)", throttle.ID, time.Now().UTC()).
+		Updates(map[string]any{
+			"locked_until":      until,
+			"unlock_token_hash": unlockTokenHash,
+			"updated_at":        time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("error locking login throttle: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	throttle.LockedUntil = &until
+	throttle.UnlockTokenHash = unlockTokenHash
+	return true, nil
+}
+
+// Unlock lifts the active lockout matching the unlock token hash and returns
+// the counter it belonged to. The token works only once.
+func (l *LoginThrottleRepository) Unlock(unlockTokenHash string, throttle *model.LoginThrottle) error {
+	now := time.Now().UTC()
+	result := l.db.Model(throttle).Clauses(clause.Returning{}).
+		Where("unlock_token_hash = ? AND locked_until > ?", unlockTokenHash, now).
+		Updates(map[string]any{
+			"failures":          0,
+			"locked_until":      nil,
+			"unlock_token_hash": nil,
+			"updated_at":        now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("error unlocking login throttle: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Reset forgets the failures recorded for key.
+func (l *LoginThrottleRepository) Reset(kind model.ThrottleKind, key string) error {
+	err := l.db.Where("kind = ? AND key = ?", kind, key).Delete(&model.LoginThrottle{}).Error
+	if err != nil {
+		return fmt.Errorf("error resetting login throttle: %w", err)
+	}
+	return nil
+}
+
+// DeleteStale removes counters with no failure since cutoff and no active
+// lockout.
+func (l *LoginThrottleRepository) DeleteStale(cutoff time.Time) (int64, error) {
+	result := l.db.
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", cutoff, time.Now().UTC()).
+		Delete(&model.LoginThrottle{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error deleting stale login throttles: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
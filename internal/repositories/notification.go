@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+func (n *NotificationRepository) Create(notification *model.Notification) error {
+	return n.db.Create(notification).Error
+}
+
+func (n *NotificationRepository) GetUserNotifications(userId uuid.UUID, unreadOnly bool, limit, offset int) ([]model.Notification, error) {
+	var notifications []model.Notification
+	query := n.db.Where("user_id = ?", userId)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("error fetching notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+func (n *NotificationRepository) CountUnread(userId uuid.UUID) (int64, error) {
+	var count int64
+	err := n.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userId).
+		Count(&count).Error
+	return count, err
+}
+
+func (n *NotificationRepository) MarkRead(id, userId uuid.UUID) error {
+	result := n.db.Model(&model.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userId).
+		Updates(map[string]any{"read_at": time.Now().UTC(), "updated_at": time.Now().UTC()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var existing model.Notification
+		return n.db.Where("id = ? AND user_id = ?", id, userId).First(&existing).Error
+	}
+	return nil
+}
+
+func (n *NotificationRepository) MarkAllRead(userId uuid.UUID) (int64, error) {
+	result := n.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userId).
+		Updates(map[string]any{"read_at": time.Now().UTC(), "updated_at": time.Now().UTC()})
+	return result.RowsAffected, result.Error
+}
+
+type NotificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{
+		db: db,
+	}
+}
+
+// GetUserPreferences returns one preference per known event, filling gaps with defaults.
+func (p *NotificationPreferenceRepository) GetUserPreferences(userId uuid.UUID) ([]model.NotificationPreference, error) {
+	var stored []model.NotificationPreference
+	if err := p.db.Where("user_id = ?", userId).Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("error fetching notification preferences: %w", err)
+	}
+
+	byEvent := make(map[model.NotificationEvent]model.NotificationPreference, len(stored))
+	for _, pref := range stored {
+		byEvent[pref.Event] = pref
+	}
+
+	preferences := make([]model.NotificationPreference, 0, len(model.NotificationEvents))
+	for _, event := range model.NotificationEvents {
+		if pref, ok := byEvent[event]; ok {
+			preferences = append(preferences, pref)
+			continue
+		}
+		preferences = append(preferences, model.DefaultNotificationPreference(userId, event))
+	}
+	return preferences, nil
+}
+
+func (p *NotificationPreferenceRepository) GetOne(userId uuid.UUID, event model.NotificationEvent) (model.NotificationPreference, error) {
+	var pref model.NotificationPreference
+	err := p.db.Where("user_id = ? AND event = ?", userId, event).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.DefaultNotificationPreference(userId, event), nil
+	}
+	return pref, err
+}
+
+func (p *NotificationPreferenceRepository) Upsert(pref *model.NotificationPreference) error {
+	pref.UpdatedAt = time.Now().UTC()
+	return p.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}},
+		DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "updated_at"}),
+	}).Create(pref).Error
+}
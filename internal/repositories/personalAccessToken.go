@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lastUsedGranularity limits last-used tracking to one write per token per
+// minute, however busy a script is.
+const lastUsedGranularity = time.Minute
+
+type PersonalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{
+		db: db,
+	}
+}
+
+func (p *PersonalAccessTokenRepository) Create(token *model.PersonalAccessToken) error {
+	return p.db.Create(token).Error
+}
+
+func (p *PersonalAccessTokenRepository) GetByHash(tokenHash string, token *model.PersonalAccessToken) error {
+	return p.db.Preload("User").Where("token_hash = ?", tokenHash).First(token).Error
+}
+
+func (p *PersonalAccessTokenRepository) GetUserTokens(userId uuid.UUID) ([]model.PersonalAccessToken, error) {
+	var tokens []model.PersonalAccessToken
+	err := p.db.Where("user_id = ?", userId).Order("created_at DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching personal access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (p *PersonalAccessTokenRepository) GetOneForUser(id, userId uuid.UUID, token *model.PersonalAccessToken) error {
+	return p.db.Where("id = ? AND user_id = ?", id, userId).First(token).Error
+}
+
+func (p *PersonalAccessTokenRepository) Revoke(id uuid.UUID) error {
+	return p.db.Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// RevokeAllForUser revokes every token of the user that is still active.
+func (p *PersonalAccessTokenRepository) RevokeAllForUser(userId uuid.UUID) error {
+	return p.db.Model(&model.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+func (p *PersonalAccessTokenRepository) TouchLastUsed(id uuid.UUID, ip string) error {
+	now := time.Now().UTC()
+	return p.db.Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-lastUsedGranularity)).
+		UpdateColumns(map[string]any{"last_used_at": now, "last_used_ip": ip}).Error
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RateLimitRepository struct {
+	db *gorm.DB
+}
+
+func NewRateLimitRepository(db *gorm.DB) *RateLimitRepository {
+	return &RateLimitRepository{
+		db: db,
+	}
+}
+
+// Increment adds count to key in a single statement, so replicas sharing the
+// table never lose an update. A counter whose window has ended starts a new
+// window of length period.
+func (r *RateLimitRepository) Increment(key string, count int64, period time.Duration) (*model.RateLimitCounter, error) {
+	now := time.Now().UTC()
+	var counter model.RateLimitCounter
+	err := r.db.Raw(`
+		INSERT INTO rate_limit_counters (key, count, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limit_counters.expires_at <= ? THEN EXCLUDED.count
+				ELSE rate_limit_counters.count + EXCLUDED.count END,
+			expires_at = CASE WHEN rate_limit_counters.expires_at <= ? THEN EXCLUDED.expires_at
+				ELSE rate_limit_counters.expires_at END
+		RETURNING key, count, expires_at`,
+		key, count, now.Add(period), now, now,
+	).Scan(&counter).Error
+	if err != nil {
+		return nil, fmt.Errorf("error incrementing rate limit counter: %w", err)
+	}
+	return &counter, nil
+}
+
+// Get returns the counter for key, or an empty counter for a new window if
+// there is none or it has expired.
+func (r *RateLimitRepository) Get(key string, period time.Duration) (*model.RateLimitCounter, error) {
+	now := time.Now().UTC()
+	var counter model.RateLimitCounter
+	err := r.db.Where("key = ? AND expires_at > ?", key, now).First(&counter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.RateLimitCounter{Key: key, ExpiresAt: now.Add(period)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching rate limit counter: %w", err)
+	}
+	return &counter, nil
+}
+
+func (r *RateLimitRepository) Reset(key string) error {
+	if err := r.db.Where("key = ?", key).Delete(&model.RateLimitCounter{}).Error; err != nil {
+		return fmt.Errorf("error resetting rate limit counter: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes counters whose window ended before cutoff.
+func (r *RateLimitRepository) DeleteExpired(cutoff time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", cutoff).Delete(&model.RateLimitCounter{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error deleting expired rate limit counters: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		db: db,
+	}
+}
+
+func (r *RefreshTokenRepository) Create(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *RefreshTokenRepository) GetOne(id uuid.UUID, token *model.RefreshToken) error {
+	return r.db.Preload("User").Where("id = ?", id).First(token).Error
+}
+
+// MarkUsed atomically consumes a token. It returns false when the token was
+// already used, revoked or expired, so two concurrent refreshes cannot both
+// succeed.
+func (r *RefreshTokenRepository) MarkUsed(id uuid.UUID) (bool, error) {
+	now := time.Now().UTC()
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return false, fmt.Errorf("error consuming refresh token: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyId uuid.UUID) error {
+	err := r.db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now().UTC()).Error
+	if err != nil {
+		return fmt.Errorf("error revoking refresh token family: %w", err)
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(userId uuid.UUID) error {
+	err := r.db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now().UTC()).Error
+	if err != nil {
+		return fmt.Errorf("error revoking refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes tokens that expired before cutoff. Used and revoked
+// tokens are kept until then so reuse can still be detected.
+func (r *RefreshTokenRepository) DeleteExpired(cutoff time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", cutoff).Delete(&model.RefreshToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error deleting expired refresh tokens: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
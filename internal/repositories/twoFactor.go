@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		db: db,
+	}
+}
+
+func (t *TwoFactorRepository) GetByUser(userId uuid.UUID, twoFactor *model.TwoFactorAuth) error {
+	return t.db.Where("user_id = ?", userId).First(twoFactor).Error
+}
+
+// StartEnrollment stores a new pending secret for the user, replacing any
+// earlier unconfirmed one.
+func (t *TwoFactorRepository) StartEnrollment(userId uuid.UUID, secret string) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND enabled = ?", userId, false).Delete(&model.TwoFactorAuth{}).Error; err != nil {
+			return fmt.Errorf("error clearing pending enrollment: %w", err)
+		}
+		if err := tx.Create(&model.TwoFactorAuth{UserID: userId, Secret: secret}).Error; err != nil {
+			return fmt.Errorf("error creating enrollment: %w", err)
+		}
+		return nil
+	})
+}
+
+// Enable turns on 2FA and replaces the user's recovery codes in one go.
+func (t *TwoFactorRepository) Enable(userId uuid.UUID, step int64, codeHashes []string) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		err := tx.Model(&model.TwoFactorAuth{}).Where("user_id = ?", userId).Updates(map[string]any{
+			"enabled":         true,
+			"confirmed_at":    now,
+			"last_used_step":  step,
+			"failed_attempts": 0,
+			"locked_until":    nil,
+			"updated_at":      now,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("error enabling two-factor auth: %w", err)
+		}
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+}
+
+func (t *TwoFactorRepository) Disable(userId uuid.UUID) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("error deleting recovery codes: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&model.TwoFactorAuth{}).Error; err != nil {
+			return fmt.Errorf("error disabling two-factor auth: %w", err)
+		}
+		return nil
+	})
+}
+
+// UseStep records step as used. It returns false if that step or a later one
+// was already used, so a code cannot be replayed.
+func (t *TwoFactorRepository) UseStep(userId uuid.UUID, step int64) (bool, error) {
+	result := t.db.Model(&model.TwoFactorAuth{}).
+		Where("user_id = ? AND last_used_step < ?", userId, step).
+		Updates(map[string]any{"last_used_step": step, "failed_attempts": 0, "locked_until": nil})
+	if result.Error != nil {
+		return false, fmt.Errorf("error recording two-factor step: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (t *TwoFactorRepository) ResetFailures(userId uuid.UUID) error {
+	return t.db.Model(&model.TwoFactorAuth{}).Where("user_id = ?", userId).
+		Updates(map[string]any{"failed_attempts": 0, "locked_until": nil}).Error
+}
+
+// RecordFailure counts a failed code and locks the user out for lockFor once
+// maxAttempts is reached. It returns the lock expiry, if any.
+func (t *TwoFactorRepository) RecordFailure(userId uuid.UUID, maxAttempts int, lockFor time.Duration) (*time.Time, error) {
+	var twoFactor model.TwoFactorAuth
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.TwoFactorAuth{}).Where("user_id = ?", userId).
+			UpdateColumn("failed_attempts", gorm.Expr("failed_attempts + 1")).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).First(&twoFactor).Error; err != nil {
+			return err
+		}
+		if twoFactor.FailedAttempts < maxAttempts {
+			return nil
+		}
+		lockedUntil := time.Now().UTC().Add(lockFor)
+		twoFactor.LockedUntil = &lockedUntil
+		return tx.Model(&model.TwoFactorAuth{}).Where("user_id = ?", userId).
+			UpdateColumns(map[string]any{"failed_attempts": 0, "locked_until": lockedUntil}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error recording two-factor failure: %w", err)
+	}
+	return twoFactor.LockedUntil, nil
+}
+
+func (t *TwoFactorRepository) ReplaceRecoveryCodes(userId uuid.UUID, codeHashes []string) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+}
+
+// ConsumeRecoveryCode marks an unused code as used and reports whether one
+// matched.
+func (t *TwoFactorRepository) ConsumeRecoveryCode(userId uuid.UUID, codeHash string) (bool, error) {
+	result := t.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return false, fmt.Errorf("error consuming recovery code: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (t *TwoFactorRepository) CountUnusedRecoveryCodes(userId uuid.UUID) (int64, error) {
+	var count int64
+	err := t.db.Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userId).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("error counting recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userId uuid.UUID, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+	codes := make([]model.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = model.RecoveryCode{UserID: userId, CodeHash: hash}
+	}
+	if err := tx.Create(&codes).Error; err != nil {
+		return fmt.Errorf("error storing recovery codes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(db *gorm.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{
+		db: db,
+	}
+}
+
+func (u *UserIdentityRepository) Create(identity *model.UserIdentity) error {
+	return translateUniqueViolation(u.db.Create(identity).Error)
+}
+
+func (u *UserIdentityRepository) GetByProviderSubject(provider, subject string, identity *model.UserIdentity) error {
+	return u.db.Preload("User").Where("provider = ? AND subject = ?", provider, subject).First(identity).Error
+}
+
+func (u *UserIdentityRepository) GetUserIdentities(userId uuid.UUID) ([]model.UserIdentity, error) {
+	var identities []model.UserIdentity
+	if err := u.db.Where("user_id = ?", userId).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, fmt.Errorf("error fetching identities: %w", err)
+	}
+	return identities, nil
+}
+
+func (u *UserIdentityRepository) GetOneForUser(id, userId uuid.UUID, identity *model.UserIdentity) error {
+	return u.db.Where("id = ? AND user_id = ?", id, userId).First(identity).Error
+}
+
+func (u *UserIdentityRepository) CountForUser(userId uuid.UUID) (int64, error) {
+	var count int64
+	if err := u.db.Model(&model.UserIdentity{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error counting identities: %w", err)
+	}
+	return count, nil
+}
+
+func (u *UserIdentityRepository) TouchLogin(id uuid.UUID) error {
+	return u.db.Model(&model.UserIdentity{}).Where("id = ?", id).UpdateColumn("last_login_at", time.Now().UTC()).Error
+}
+
+func (u *UserIdentityRepository) Delete(id uuid.UUID) error {
+	return u.db.Where("id = ?", id).Delete(&model.UserIdentity{}).Error
+}
+
+type OIDCAuthRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCAuthRequestRepository(db *gorm.DB) *OIDCAuthRequestRepository {
+	return &OIDCAuthRequestRepository{
+		db: db,
+	}
+}
+
+func (o *OIDCAuthRequestRepository) Create(request *model.OIDCAuthRequest) error {
+	return o.db.Create(request).Error
+}
+
+// Consume deletes and returns the unexpired request for stateHash, so a
+// callback can only be completed once.
+func (o *OIDCAuthRequestRepository) Consume(stateHash string, request *model.OIDCAuthRequest) error {
+	result := o.db.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, time.Now().UTC()).
+		Delete(request)
+	if result.Error != nil {
+		return fmt.Errorf("error consuming OIDC auth request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (o *OIDCAuthRequestRepository) DeleteExpired(cutoff time.Time) (int64, error) {
+	result := o.db.Where("expires_at < ?", cutoff).Delete(&model.OIDCAuthRequest{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error deleting expired OIDC auth requests: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -1,7 +1,11 @@
 package repositories
 
 import (
+	"context"
+	"fmt"
 	"realTimeEditor/internal/model"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -17,6 +21,12 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	}
 }
 
+// WithContext returns a copy of the repository whose queries run under ctx,
+// so they are traced as part of the request.
+func (u *UserRepository) WithContext(ctx context.Context) *UserRepository {
+	return &UserRepository{db: u.db.WithContext(ctx)}
+}
+
 func (u *UserRepository) Create(user *model.User) (*model.User, error) {
 	err := u.db.Create(user).Error
 	if err != nil {
@@ -25,10 +35,16 @@ func (u *UserRepository) Create(user *model.User) (*model.User, error) {
 	return user, nil
 }
 
+func (u *UserRepository) CreateWithTransaction(tx *gorm.DB, user *model.User) error {
+	return tx.Create(user).Error
+}
+
 func (u *UserRepository) Update(user *model.User, id uuid.UUID) error {
-	if err := u.db.Where("id = ?", id).First(user).Error; err != nil {
+	var existing model.User
+	if err := u.db.Where("id = ?", id).First(&existing).Error; err != nil {
 		return err
 	}
+	user.ID = id
 	return u.db.Save(user).Error
 }
 
@@ -39,3 +55,62 @@ func (u *UserRepository) GetById(user *model.User, id uuid.UUID) error {
 func (u *UserRepository) GetByEmail(user *model.User, email string) error {
 	return u.db.Where("email = ?", email).First(&user).Error
 }
+
+// List returns users newest first, only those whose email or name contains
+// search when it is set.
+func (u *UserRepository) List(search string, limit int) ([]model.User, error) {
+	query := u.db.Order("created_at DESC").Limit(limit)
+	if search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where(
+			"LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?",
+			pattern, pattern, pattern,
+		)
+	}
+	var users []model.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("error listing users: %w", err)
+	}
+	return users, nil
+}
+
+// RevokeTokens invalidates every access token issued to the user before at.
+func (u *UserRepository) RevokeTokens(id uuid.UUID, at time.Time) error {
+	return u.db.Model(&model.User{}).Where("id = ?", id).UpdateColumn("tokens_revoked_at", at).Error
+}
+
+// ClaimEmail marks an unverified account as verified by whoever just proved
+// they own its email, e.g. by accepting an invite. The account may have been
+// registered by someone else, so its password is cleared and its access
+// tokens revoked. It reports whether the account was unverified.
+func (u *UserRepository) ClaimEmail(id uuid.UUID, at time.Time) (bool, error) {
+	result := u.db.Model(&model.User{}).Where("id = ? AND email_verified_at IS NULL", id).
+		UpdateColumns(map[string]any{
+			"email_verified_at": at,
+			"password":          nil,
+			"tokens_revoked_at": model.TokensRevokedAfter(at),
+			"updated_at":        at,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("error claiming account: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkEmailVerified records that the user has proven they own their email.
+func (u *UserRepository) MarkEmailVerified(id uuid.UUID, at time.Time) error {
+	return u.db.Model(&model.User{}).Where("id = ? AND email_verified_at IS NULL", id).
+		UpdateColumns(map[string]any{"email_verified_at": at, "updated_at": at}).Error
+}
+
+// SetAdmin grants or revokes the user's access to the admin routes.
+func (u *UserRepository) SetAdmin(id uuid.UUID, admin bool) error {
+	return u.db.Model(&model.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]any{"is_admin": admin, "updated_at": time.Now().UTC()}).Error
+}
+
+// UpdatePassword replaces only the password hash, e.g. when rehashing on login.
+func (u *UserRepository) UpdatePassword(id uuid.UUID, hashedPassword string) error {
+	return u.db.Model(&model.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]any{"password": hashedPassword, "updated_at": time.Now().UTC()}).Error
+}
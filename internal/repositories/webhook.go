@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"fmt"
+	"realTimeEditor/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (w *WebhookRepository) Create(webhook *model.Webhook) error {
+	return w.db.Create(webhook).Error
+}
+
+func (w *WebhookRepository) GetUserWebhooks(userId uuid.UUID) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := w.db.Where("user_id = ?", userId).Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("error fetching webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (w *WebhookRepository) GetOneForUser(id, userId uuid.UUID, webhook *model.Webhook) error {
+	return w.db.Where("id = ? AND user_id = ?", id, userId).First(webhook).Error
+}
+
+// GetSubscribers returns active webhooks scoped to the document or, when
+// unscoped, owned by the document's owner.
+func (w *WebhookRepository) GetSubscribers(documentId, ownerId uuid.UUID) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	err := w.db.
+		Where("active = ?", true).
+		Where("document_id = ? OR (document_id IS NULL AND user_id = ?)", documentId, ownerId).
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook subscribers: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (w *WebhookRepository) Update(webhook *model.Webhook, id uuid.UUID) error {
+	webhook.UpdatedAt = time.Now().UTC()
+	return w.db.Model(&model.Webhook{}).
+		Where("id = ?", id).
+		Select("url", "events", "active", "updated_at").
+		Updates(webhook).Error
+}
+
+func (w *WebhookRepository) Delete(id uuid.UUID) error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Webhook{}, "id = ?", id).Error
+	})
+}
+
+type WebhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		db: db,
+	}
+}
+
+func (w *WebhookDeliveryRepository) Create(delivery *model.WebhookDelivery) error {
+	return w.db.Create(delivery).Error
+}
+
+func (w *WebhookDeliveryRepository) GetByWebhookID(webhookId uuid.UUID, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := w.db.Where("webhook_id = ?", webhookId).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimDue locks up to limit pending deliveries that are due and pushes their
+// next attempt back by lease so other replicas skip them while in flight.
+func (w *WebhookDeliveryRepository) ClaimDue(limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Preload("Webhook").
+			Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i, d := range deliveries {
+			ids[i] = d.ID
+		}
+		return tx.Model(&model.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (w *WebhookDeliveryRepository) Update(delivery *model.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now().UTC()
+	return w.db.Model(&model.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Select("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at", "updated_at").
+		Updates(delivery).Error
+}
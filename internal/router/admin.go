@@ -0,0 +1,20 @@
+package router
+
+import (
+	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/pkg/constants"
+	"realTimeEditor/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRouter(g *gin.RouterGroup, a *controllers.AdminController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	adminGroup := g.Group("/admin")
+	adminGroup.Use(m.UserAuth(s), m.AdminAuth(), l.Limit(constants.RateLimitAdmin))
+	{
+		adminGroup.GET("/email-templates/locales", a.ListEmailLocales)
+		adminGroup.GET("/email-templates/:name/preview", a.PreviewEmailTemplate)
+		adminGroup.GET("/audit-log/export", a.ExportAuditLog)
+	}
+}
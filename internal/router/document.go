@@ -3,32 +3,57 @@ package router
 import (
 	"realTimeEditor/internal/controllers"
 	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/pkg/constants"
 	"realTimeEditor/pkg/jwt"
 
 	"github.com/gin-gonic/gin"
 )
 
-func DocumentRouter(g *gin.Engine, d *controllers.DocumentController, m *middlewares.AuthMiddleware, s *jwt.Session) {
-	documentGroup := g.Group("/document")
-	documentGroup.Use(m.UserAuth(s))
+// Document routes are grouped by the personal access token scope they need.
+func DocumentRouter(g *gin.RouterGroup, d *controllers.DocumentController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	readGroup := g.Group("/document")
+	readGroup.Use(m.UserAuth(s, model.ScopeDocumentsRead), l.Limit(constants.RateLimitAPI))
 	{
-		documentGroup.POST("/create", d.Create)
-		documentGroup.GET("/user-created-docs", d.GetUserCreatedDocuments)
-		documentGroup.GET("/get-one", d.GetSingleDocument)
-		documentGroup.DELETE("/revoke-access/:documentAccessId", d.RevokeAccess)
-		documentGroup.DELETE("/delete/:id", d.DeleteDocument)
-		documentGroup.PATCH("/modify-access/:documentAccessId/:newRole", d.ModifyAccess)
-		documentGroup.GET("/all", d.FetchAllDocuments)
-		documentGroup.GET("/collaborators/:id", d.FetchCollaborators)
-		documentGroup.PATCH("/transfer-ownership/:documentId/:recipientId", d.TransferOwnership)
-		documentGroup.POST("/invite-collaborator", d.InviteCollaborator)
-		documentGroup.GET("/generate-pdf", d.GenerateDocPDF)
-		documentGroup.GET("/toggle-visibility/:id", d.ToggleVisibility)
+		readGroup.GET("/user-created-docs", d.GetUserCreatedDocuments)
+		readGroup.GET("/get-one", d.GetSingleDocument)
+		readGroup.GET("/all", d.FetchAllDocuments)
+		readGroup.GET("/collaborators/:id", d.FetchCollaborators)
+		readGroup.GET("/generate-pdf", l.Limit(constants.RateLimitExpensive), d.GenerateDocPDF)
+		readGroup.GET("/audit-log/:id", d.GetAuditLog)
+	}
+
+	writeGroup := g.Group("/document")
+	writeGroup.Use(m.UserAuth(s, model.ScopeDocumentsWrite), l.Limit(constants.RateLimitAPI))
+	{
+		writeGroup.POST("/create", m.RequireVerifiedEmail(), d.Create)
+		writeGroup.DELETE("/delete/:id", d.DeleteDocument)
+		writeGroup.GET("/toggle-visibility/:id", m.RequireVerifiedEmail(), d.ToggleVisibility)
+	}
+
+	sharingGroup := g.Group("/document")
+	sharingGroup.Use(m.UserAuth(s, model.ScopeSharingManage), l.Limit(constants.RateLimitAPI), m.RequireVerifiedEmail())
+	{
+		sharingGroup.DELETE("/revoke-access/:accessId", d.RevokeAccess)
+		sharingGroup.PATCH("/modify-access/:accessId/:role", d.ModifyAccess)
+		sharingGroup.PATCH("/transfer-ownership/:id/:recipientId", d.TransferOwnership)
+		sharingGroup.POST("/invite-collaborator", d.InviteCollaborator)
+		sharingGroup.GET("/pending-invites/:id", d.ListDocumentInvites)
+		sharingGroup.POST("/invites/:inviteId/resend", d.ResendInvite)
+		sharingGroup.DELETE("/invites/:inviteId", d.CancelInvite)
 	}
 
 	docGroup := g.Group("/invite")
+	docGroup.Use(l.Limit(constants.RateLimitPublic))
 	{
 		docGroup.GET("/verify", d.VerifyInviteToken)
 		docGroup.POST("/accept/:token", d.AcceptInvitation)
+		docGroup.POST("/decline/:token", d.DeclineInvitation)
+	}
+
+	myInvitesGroup := g.Group("/invite")
+	myInvitesGroup.Use(m.UserAuth(s, model.ScopeDocumentsRead), l.Limit(constants.RateLimitAPI), m.RequireVerifiedEmail())
+	{
+		myInvitesGroup.GET("/mine", d.ListMyInvites)
 	}
 }
@@ -3,18 +3,25 @@ package router
 import (
 	"realTimeEditor/internal/controllers"
 	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/pkg/constants"
 	"realTimeEditor/pkg/jwt"
 
 	"github.com/gin-gonic/gin"
 )
 
-func DocumentMetadataRouter(g *gin.Engine, d *controllers.DocumentMetadataController, m *middlewares.AuthMiddleware, s *jwt.Session) {
-	documentMetadataGroup := g.Group("/document-metadata")
-	documentMetadataGroup.Use(m.UserAuth(s))
+func DocumentMetadataRouter(g *gin.RouterGroup, d *controllers.DocumentMetadataController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	readGroup := g.Group("/document-metadata")
+	readGroup.Use(m.UserAuth(s, model.ScopeDocumentsRead), l.Limit(constants.RateLimitAPI))
 	{
-		documentMetadataGroup.POST("/create", d.Create)
-		documentMetadataGroup.GET("/get-one/:documentId", d.GetDocumentMetadata)
-		documentMetadataGroup.PATCH("/update", d.Update)
-		documentMetadataGroup.DELETE("/delete", d.Delete)
+		readGroup.GET("/get-one/:id", d.GetDocumentMetadata)
+	}
+
+	writeGroup := g.Group("/document-metadata")
+	writeGroup.Use(m.UserAuth(s, model.ScopeDocumentsWrite), l.Limit(constants.RateLimitAPI))
+	{
+		writeGroup.POST("/create", d.Create)
+		writeGroup.PATCH("/update", d.Update)
+		writeGroup.DELETE("/delete", d.Delete)
 	}
 }
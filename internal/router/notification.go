@@ -0,0 +1,23 @@
+package router
+
+import (
+	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/pkg/constants"
+	"realTimeEditor/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NotificationRouter(g *gin.RouterGroup, n *controllers.NotificationController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	notificationGroup := g.Group("/notifications")
+	notificationGroup.Use(m.UserAuth(s), l.Limit(constants.RateLimitAPI))
+	{
+		notificationGroup.GET("", n.List)
+		notificationGroup.GET("/unread-count", n.UnreadCount)
+		notificationGroup.PATCH("/read-all", n.MarkAllRead)
+		notificationGroup.PATCH("/:id/read", n.MarkRead)
+		notificationGroup.GET("/preferences", n.GetPreferences)
+		notificationGroup.PUT("/preferences", n.UpdatePreferences)
+	}
+}
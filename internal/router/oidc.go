@@ -0,0 +1,28 @@
+package router
+
+import (
+	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/pkg/constants"
+	"realTimeEditor/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func OIDCRouter(g *gin.RouterGroup, o *controllers.OIDCController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	oidcGroup := g.Group("/auth/oidc")
+	oidcGroup.Use(l.Limit(constants.RateLimitAuth))
+	{
+		oidcGroup.GET("/providers", o.Providers)
+		oidcGroup.GET("/:provider/login", o.Login)
+		oidcGroup.GET("/:provider/callback", o.Callback)
+	}
+
+	identityGroup := g.Group("/member/identities")
+	identityGroup.Use(m.UserAuth(s), l.Limit(constants.RateLimitAPI))
+	{
+		identityGroup.GET("", o.ListIdentities)
+		identityGroup.POST("/:provider", o.Link)
+		identityGroup.DELETE("/:id", o.Unlink)
+	}
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/pkg/constants"
+	"realTimeEditor/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PersonalAccessTokenRouter(g *gin.RouterGroup, p *controllers.PersonalAccessTokenController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	tokenGroup := g.Group("/member/tokens")
+	tokenGroup.Use(m.UserAuth(s), l.Limit(constants.RateLimitAPI))
+	{
+		tokenGroup.POST("", p.Create)
+		tokenGroup.GET("", p.List)
+		tokenGroup.DELETE("/:id", p.Revoke)
+	}
+}
@@ -9,15 +9,32 @@ import (
 )
 
 type RouterContainer struct {
-	UserController             *controllers.UserController
-	DocumentController         *controllers.DocumentController
-	DocumentMetadataController *controllers.DocumentMetadataController
-	AuthMiddleware             *middlewares.AuthMiddleware
-	Session                    *jwt.Session
+	UserController                *controllers.UserController
+	DocumentController            *controllers.DocumentController
+	DocumentMetadataController    *controllers.DocumentMetadataController
+	AdminController               *controllers.AdminController
+	NotificationController        *controllers.NotificationController
+	WebhookController             *controllers.WebhookController
+	TwoFactorController           *controllers.TwoFactorController
+	OIDCController                *controllers.OIDCController
+	PersonalAccessTokenController *controllers.PersonalAccessTokenController
+	AuthMiddleware                *middlewares.AuthMiddleware
+	RateLimiter                   *middlewares.RateLimiter
+	Session                       *jwt.Session
 }
 
+// Register serves the unversioned routes the API was first served on. They
+// are deprecated aliases of the /api/v1 routes registered by RegisterV1.
 func (rc *RouterContainer) Register(r *gin.Engine) {
-	UserRouter(r, rc.UserController, rc.AuthMiddleware, rc.Session)
-	DocumentRouter(r, rc.DocumentController, rc.AuthMiddleware, rc.Session)
-	DocumentMetadataRouter(r, rc.DocumentMetadataController, rc.AuthMiddleware, rc.Session)
+	legacy := r.Group("/", middlewares.Deprecated(middlewares.LegacyRoutesDeprecatedAt))
+
+	UserRouter(legacy, rc.UserController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	DocumentRouter(legacy, rc.DocumentController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	DocumentMetadataRouter(legacy, rc.DocumentMetadataController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	AdminRouter(legacy, rc.AdminController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	NotificationRouter(legacy, rc.NotificationController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	WebhookRouter(legacy, rc.WebhookController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	TwoFactorRouter(legacy, rc.TwoFactorController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	OIDCRouter(legacy, rc.OIDCController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
+	PersonalAccessTokenRouter(legacy, rc.PersonalAccessTokenController, rc.AuthMiddleware, rc.Session, rc.RateLimiter)
 }
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/pkg/constants"
+	"realTimeEditor/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TwoFactorRouter(g *gin.RouterGroup, t *controllers.TwoFactorController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	twoFactorGroup := g.Group("/member/2fa")
+	twoFactorGroup.Use(m.UserAuth(s), l.Limit(constants.RateLimitAPI))
+	{
+		twoFactorGroup.GET("", t.Status)
+		twoFactorGroup.POST("/enroll", t.Enroll)
+		twoFactorGroup.POST("/confirm", t.Confirm)
+		twoFactorGroup.POST("/disable", t.Disable)
+		twoFactorGroup.POST("/recovery-codes", t.RegenerateRecoveryCodes)
+	}
+}
@@ -3,31 +3,40 @@ package router
 import (
 	"realTimeEditor/internal/controllers"
 	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/pkg/constants"
 	"realTimeEditor/pkg/jwt"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRouter(g *gin.Engine, u *controllers.UserController, m *middlewares.AuthMiddleware, s *jwt.Session) {
+func UserRouter(g *gin.RouterGroup, u *controllers.UserController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
 	authGroup := g.Group("/auth")
+	authGroup.Use(l.Limit(constants.RateLimitAuth))
 	{
 		authGroup.POST("/register", u.Create)
 		authGroup.POST("/login", u.Login)
+		authGroup.POST("/login/mfa", u.LoginMFA)
 		authGroup.POST("/forgot-password", u.ForgotPassword)
 		authGroup.POST("/verify-reset-code", u.VerifyResetCode)
+		authGroup.POST("/reset-password", u.ResetPassword)
+		authGroup.POST("/verify-email", u.VerifyEmail)
+		authGroup.POST("/unlock", u.UnlockAccount)
 		authGroup.POST("/access-token", u.GenerateAccessToken)
+		authGroup.POST("/logout", u.Logout)
 		authGroup.POST("/complete-account", u.CompleteAccount)
 	}
 
 	authGroup.Use(m.UserAuth(s))
 	{
-		authGroup.POST("/reset-password", u.ResetPassword)
+		authGroup.POST("/logout-all", u.LogoutAll)
+		authGroup.POST("/resend-verification", u.ResendVerification)
 	}
 
 	userGroup := g.Group("/member")
-	userGroup.Use(m.UserAuth(s))
+	userGroup.Use(m.UserAuth(s), l.Limit(constants.RateLimitAPI))
 	{
 		userGroup.GET("/profile", u.Profile)
-		userGroup.POST("/profile-upload", u.UploadProfilePicture)
+		userGroup.POST("/profile-upload", l.Limit(constants.RateLimitExpensive), u.UploadProfilePicture)
+		userGroup.PATCH("/locale", u.UpdateLocale)
 	}
 }
@@ -0,0 +1,328 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/internal/model"
+	"realTimeEditor/pkg/constants"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+// APIPrefix is the path the versioned API is served under.
+const APIPrefix = "/api/v1"
+
+// Operation extensions of the API spec that declare middleware.
+const (
+	extTokenScopes   = "x-token-scopes"
+	extAdmin         = "x-admin"
+	extRateLimit     = "x-rate-limit"
+	extVerifiedEmail = "x-verified-email"
+)
+
+var (
+	pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+	rateLimitPolicies = []string{
+		constants.RateLimitAuth,
+		constants.RateLimitPublic,
+		constants.RateLimitAPI,
+		constants.RateLimitExpensive,
+		constants.RateLimitAdmin,
+	}
+)
+
+// v1Route is an operation of the API spec with its handler and the
+// middleware its extensions ask for.
+type v1Route struct {
+	// path is the spec path in gin syntax, without APIPrefix.
+	path          string
+	route         *routers.Route
+	handler       gin.HandlerFunc
+	auth          bool
+	scopes        []model.TokenScope
+	admin         bool
+	limits        []string
+	verifiedEmail bool
+}
+
+// operations maps each operationId of the API spec to its handler. The
+// controllers may be nil when only the mapping is needed.
+func (rc *RouterContainer) operations() map[string]gin.HandlerFunc {
+	u := rc.UserController
+	d := rc.DocumentController
+	dm := rc.DocumentMetadataController
+	a := rc.AdminController
+	n := rc.NotificationController
+	w := rc.WebhookController
+	t := rc.TwoFactorController
+	o := rc.OIDCController
+	p := rc.PersonalAccessTokenController
+
+	return map[string]gin.HandlerFunc{
+		"createDocument":            d.Create,
+		"listDocuments":             d.FetchAllDocuments,
+		"listOwnedDocuments":        d.GetUserCreatedDocuments,
+		"getDocument":               d.GetSingleDocument,
+		"deleteDocument":            d.DeleteDocument,
+		"toggleDocumentVisibility":  d.ToggleVisibility,
+		"listCollaborators":         d.FetchCollaborators,
+		"getDocumentAuditLog":       d.GetAuditLog,
+		"generateDocumentPdf":       d.GenerateDocPDF,
+		"transferDocumentOwnership": d.TransferOwnership,
+		"modifyDocumentAccess":      d.ModifyAccess,
+		"revokeDocumentAccess":      d.RevokeAccess,
+		"inviteCollaborators":       d.InviteCollaborator,
+		"listDocumentInvites":       d.ListDocumentInvites,
+		"resendInvite":              d.ResendInvite,
+		"cancelInvite":              d.CancelInvite,
+		"verifyInvite":              d.VerifyInviteToken,
+		"acceptInvite":              d.AcceptInvitation,
+		"declineInvite":             d.DeclineInvitation,
+		"listMyInvites":             d.ListMyInvites,
+
+		"getDocumentMetadata":    dm.GetDocumentMetadata,
+		"createDocumentMetadata": dm.Create,
+		"updateDocumentMetadata": dm.Update,
+		"deleteDocumentMetadata": dm.Delete,
+
+		"register":           u.Create,
+		"login":              u.Login,
+		"loginMfa":           u.LoginMFA,
+		"forgotPassword":     u.ForgotPassword,
+		"verifyResetCode":    u.VerifyResetCode,
+		"resetPassword":      u.ResetPassword,
+		"verifyEmail":        u.VerifyEmail,
+		"unlockAccount":      u.UnlockAccount,
+		"refreshAccessToken": u.GenerateAccessToken,
+		"logout":             u.Logout,
+		"completeAccount":    u.CompleteAccount,
+		"logoutAll":          u.LogoutAll,
+		"resendVerification": u.ResendVerification,
+		"getProfile":         u.Profile,
+		"uploadProfilePhoto": u.UploadProfilePicture,
+		"updateLocale":       u.UpdateLocale,
+
+		"listOidcProviders": o.Providers,
+		"oidcLogin":         o.Login,
+		"oidcCallback":      o.Callback,
+		"linkIdentity":      o.Link,
+		"listIdentities":    o.ListIdentities,
+		"unlinkIdentity":    o.Unlink,
+
+		"getTwoFactorStatus":      t.Status,
+		"enrollTwoFactor":         t.Enroll,
+		"confirmTwoFactor":        t.Confirm,
+		"disableTwoFactor":        t.Disable,
+		"regenerateRecoveryCodes": t.RegenerateRecoveryCodes,
+
+		"createPersonalAccessToken": p.Create,
+		"listPersonalAccessTokens":  p.List,
+		"revokePersonalAccessToken": p.Revoke,
+
+		"listNotifications":             n.List,
+		"getUnreadNotificationCount":    n.UnreadCount,
+		"markNotificationRead":          n.MarkRead,
+		"markAllNotificationsRead":      n.MarkAllRead,
+		"getNotificationPreferences":    n.GetPreferences,
+		"updateNotificationPreferences": n.UpdatePreferences,
+
+		"createWebhook":         w.Create,
+		"listWebhooks":          w.List,
+		"getWebhook":            w.GetOne,
+		"updateWebhook":         w.Update,
+		"deleteWebhook":         w.Delete,
+		"listWebhookDeliveries": w.Deliveries,
+		"pingWebhook":           w.Ping,
+
+		"listEmailLocales":     a.ListEmailLocales,
+		"previewEmailTemplate": a.PreviewEmailTemplate,
+		"exportAuditLog":       a.ExportAuditLog,
+	}
+}
+
+// v1Routes resolves every operation of spec. It fails when an operation has
+// no handler, a handler has no operation or an extension is invalid, so the
+// spec and the routes cannot drift apart.
+func (rc *RouterContainer) v1Routes(spec *openapi3.T) ([]v1Route, error) {
+	handlers := rc.operations()
+	seen := make(map[string]bool, len(handlers))
+	var routes []v1Route
+	var errs []error
+
+	for _, path := range spec.Paths.InMatchingOrder() {
+		pathItem := spec.Paths.Value(path)
+		operations := pathItem.Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		slices.Sort(methods)
+
+		for _, method := range methods {
+			operation := operations[method]
+			seen[operation.OperationID] = true
+			route, err := newV1Route(spec, path, pathItem, method, operation, handlers)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", method, path, err))
+				continue
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	for operationId := range handlers {
+		if !seen[operationId] {
+			errs = append(errs, fmt.Errorf("operation %q has a handler but is not in the API spec", operationId))
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+	return routes, errors.Join(errs...)
+}
+
+func newV1Route(spec *openapi3.T, path string, pathItem *openapi3.PathItem, method string, operation *openapi3.Operation, handlers map[string]gin.HandlerFunc) (v1Route, error) {
+	handler, ok := handlers[operation.OperationID]
+	if !ok {
+		return v1Route{}, fmt.Errorf("no handler for operation %q", operation.OperationID)
+	}
+
+	route := v1Route{
+		path:    pathParam.ReplaceAllString(path, ":$1"),
+		handler: handler,
+		auth:    operation.Security != nil && len(*operation.Security) > 0,
+		route: &routers.Route{
+			Spec:      spec,
+			Path:      path,
+			PathItem:  pathItem,
+			Method:    method,
+			Operation: operation,
+		},
+	}
+
+	scopes, err := stringsExtension(operation, extTokenScopes)
+	if err != nil {
+		return v1Route{}, err
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(model.TokenScopes, model.TokenScope(scope)) {
+			return v1Route{}, fmt.Errorf("unknown token scope %q", scope)
+		}
+		route.scopes = append(route.scopes, model.TokenScope(scope))
+	}
+
+	if route.admin, err = boolExtension(operation, extAdmin); err != nil {
+		return v1Route{}, err
+	}
+	if route.verifiedEmail, err = boolExtension(operation, extVerifiedEmail); err != nil {
+		return v1Route{}, err
+	}
+	if !route.auth && (len(route.scopes) > 0 || route.admin || route.verifiedEmail) {
+		return v1Route{}, fmt.Errorf("%s, %s and %s need BearerAuth security", extTokenScopes, extAdmin, extVerifiedEmail)
+	}
+
+	if route.limits, err = stringsExtension(operation, extRateLimit); err != nil {
+		return v1Route{}, err
+	}
+	if len(route.limits) == 0 {
+		return v1Route{}, fmt.Errorf("%s is required", extRateLimit)
+	}
+	for _, policy := range route.limits {
+		if !slices.Contains(rateLimitPolicies, policy) {
+			return v1Route{}, fmt.Errorf("unknown rate limit policy %q", policy)
+		}
+	}
+	return route, nil
+}
+
+// RegisterV1 serves the operations of spec under APIPrefix. Each runs
+// authentication, the admin check, its rate limits, the verified email check
+// and request validation, in that order, before its handler.
+func (rc *RouterContainer) RegisterV1(r *gin.Engine, spec *openapi3.T) error {
+	routes, err := rc.v1Routes(spec)
+	if err != nil {
+		return err
+	}
+
+	api := r.Group(APIPrefix)
+	for _, route := range routes {
+		var chain []gin.HandlerFunc
+		if route.auth {
+			chain = append(chain, rc.AuthMiddleware.UserAuth(rc.Session, route.scopes...))
+		}
+		if route.admin {
+			chain = append(chain, rc.AuthMiddleware.AdminAuth())
+		}
+		for _, policy := range route.limits {
+			chain = append(chain, rc.RateLimiter.Limit(policy))
+		}
+		if route.verifiedEmail {
+			chain = append(chain, rc.AuthMiddleware.RequireVerifiedEmail())
+		}
+		chain = append(chain, middlewares.ValidateRequest(route.route), route.handler)
+		api.Handle(route.route.Method, route.path, chain...)
+	}
+	return nil
+}
+
+// CheckSpec reports drift between the API spec and the handlers: operations
+// without a handler, handlers missing from the spec, invalid extensions and
+// paths gin cannot serve side by side.
+func CheckSpec(spec *openapi3.T) (err error) {
+	var rc RouterContainer
+	routes, err := rc.v1Routes(spec)
+	if err != nil {
+		return err
+	}
+
+	// gin panics when it registers a route that conflicts with another.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("conflicting routes: %v", r)
+		}
+	}()
+	engine := gin.New()
+	for _, route := range routes {
+		engine.Handle(route.route.Method, APIPrefix+route.path, route.handler)
+	}
+	return nil
+}
+
+func stringsExtension(operation *openapi3.Operation, name string) ([]string, error) {
+	raw, ok := operation.Extensions[name]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list", name)
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of strings", name)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func boolExtension(operation *openapi3.Operation, name string) (bool, error) {
+	raw, ok := operation.Extensions[name]
+	if !ok {
+		return false, nil
+	}
+	value, ok := raw.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", name)
+	}
+	return value, nil
+}
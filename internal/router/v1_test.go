@@ -0,0 +1,127 @@
+package router
+
+import (
+	"os"
+	"path/filepath"
+	"realTimeEditor/internal/apispec"
+	"realTimeEditor/internal/middlewares"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	spec, err := apispec.Load(filepath.Join("..", "..", apispec.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	if err := CheckSpec(spec); err != nil {
+		t.Fatalf("API spec and routes disagree:\n%s", err)
+	}
+
+	policies := make(map[string]string, len(rateLimitPolicies))
+	for _, policy := range rateLimitPolicies {
+		policies[policy] = "1000-S"
+	}
+	rateLimiter, err := middlewares.NewRateLimiter(memory.NewStore(), policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := &RouterContainer{
+		AuthMiddleware: &middlewares.AuthMiddleware{},
+		RateLimiter:    rateLimiter,
+	}
+
+	engine := gin.New()
+	if err := rc.RegisterV1(engine, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for path, pathItem := range spec.Paths.Map() {
+		for method := range pathItem.Operations() {
+			want = append(want, method+" "+APIPrefix+pathParam.ReplaceAllString(path, ":$1"))
+		}
+	}
+	var got []string
+	for _, route := range engine.Routes() {
+		got = append(got, route.Method+" "+route.Path)
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+
+	for _, route := range want {
+		if !slices.Contains(got, route) {
+			t.Errorf("%s is in the API spec but not served", route)
+		}
+	}
+	for _, route := range got {
+		if !slices.Contains(want, route) {
+			t.Errorf("%s is served but not in the API spec", route)
+		}
+	}
+}
+
+func TestCheckSpecReportsDrift(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*openapi3.T)
+		want   string
+	}{
+		{
+			name: "operation without handler",
+			change: func(spec *openapi3.T) {
+				spec.Paths.Value("/documents").Get.OperationID = "listEverything"
+			},
+			want: `no handler for operation "listEverything"`,
+		},
+		{
+			name: "handler missing from spec",
+			change: func(spec *openapi3.T) {
+				spec.Paths.Value("/documents").Get = nil
+			},
+			want: `operation "listDocuments" has a handler but is not in the API spec`,
+		},
+		{
+			name: "unknown rate limit policy",
+			change: func(spec *openapi3.T) {
+				spec.Paths.Value("/documents").Get.Extensions[extRateLimit] = []any{"unlimited"}
+			},
+			want: `unknown rate limit policy "unlimited"`,
+		},
+		{
+			name: "conflicting paths",
+			change: func(spec *openapi3.T) {
+				operation := spec.Paths.Value("/documents/{id}").Get
+				spec.Paths.Set("/documents/{documentId}/copy", &openapi3.PathItem{Post: operation})
+			},
+			want: "conflicting routes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := loadSpec(t)
+			tt.change(spec)
+			err := CheckSpec(spec)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"realTimeEditor/internal/controllers"
+	"realTimeEditor/internal/middlewares"
+	"realTimeEditor/pkg/constants"
+	"realTimeEditor/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WebhookRouter(g *gin.RouterGroup, w *controllers.WebhookController, m *middlewares.AuthMiddleware, s *jwt.Session, l *middlewares.RateLimiter) {
+	webhookGroup := g.Group("/webhooks")
+	webhookGroup.Use(m.UserAuth(s), l.Limit(constants.RateLimitAPI))
+	{
+		webhookGroup.POST("", w.Create)
+		webhookGroup.GET("", w.List)
+		webhookGroup.GET("/:id", w.GetOne)
+		webhookGroup.PATCH("/:id", w.Update)
+		webhookGroup.DELETE("/:id", w.Delete)
+		webhookGroup.GET("/:id/deliveries", w.Deliveries)
+		webhookGroup.POST("/:id/ping", w.Ping)
+	}
+}
@@ -1,17 +1,27 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"realTimeEditor/internal/handlers"
+	"realTimeEditor/internal/logging"
+	"realTimeEditor/internal/metrics"
 	"realTimeEditor/internal/model"
 	"realTimeEditor/internal/repositories"
+	"realTimeEditor/internal/tracing"
 	"realTimeEditor/pkg/jwt"
+	"realTimeEditor/pkg/utils"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	socketio "github.com/googollee/go-socket.io"
+	"github.com/ulule/limiter/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SocketHandler struct {
@@ -19,32 +29,99 @@ type SocketHandler struct {
 	DocumentAccessRepository *repositories.DocumentAccessRepository
 	SessionService           *jwt.Session
 	UserRepository           *repositories.UserRepository
+	WebhookPublisher         *handlers.WebhookPublisher
+	EditLimiter              *limiter.Limiter
 	Initialized              bool
+
+	// mu guards closing, so no edit starts once Shutdown waits on edits.
+	mu      sync.Mutex
+	closing bool
+	edits   sync.WaitGroup
 }
 
+// shutdownEvent tells clients to reconnect, which lands them on another
+// instance behind the load balancer.
+const shutdownEvent = "server_shutdown"
+
 func NewSocketHandler(
 	documentRepo *repositories.DocumentRepository,
 	documentAccessRepo *repositories.DocumentAccessRepository,
 	session *jwt.Session,
 	userRepo *repositories.UserRepository,
+	webhookPublisher *handlers.WebhookPublisher,
+	editLimiter *limiter.Limiter,
 ) *SocketHandler {
 	return &SocketHandler{
 		DocumentRepository:       documentRepo,
 		DocumentAccessRepository: documentAccessRepo,
 		SessionService:           session,
 		UserRepository:           userRepo,
+		WebhookPublisher:         webhookPublisher,
+		EditLimiter:              editLimiter,
 		Initialized:              true,
 	}
 }
 
+// startSpan begins the span of a socket event. Events are traced on their
+// own rather than under the handshake, which can be hours old.
+func startSpan(s socketio.Conn, event string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("socket.conn_id", s.ID()))
+	return tracing.Start(context.Background(), "socket."+event, attrs...)
+}
+
+// recordEdit counts an edit event by result and marks its span with it.
+func recordEdit(span trace.Span, result string) {
+	metrics.SocketEdits.WithLabelValues(result).Inc()
+	span.SetAttributes(attribute.String("socket.edit.result", result))
+	if result == "failed" {
+		span.SetStatus(codes.Error, "edit failed")
+	}
+}
+
+// beginEdit registers an edit in progress, or returns false once shutdown
+// has started.
+func (sh *SocketHandler) beginEdit() bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.closing {
+		return false
+	}
+	sh.edits.Add(1)
+	return true
+}
+
+// Shutdown stops accepting connections and edits, tells connected clients to
+// reconnect elsewhere and waits for edits that are being saved.
+func (sh *SocketHandler) Shutdown(ctx context.Context, server *socketio.Server) error {
+	sh.mu.Lock()
+	sh.closing = true
+	sh.mu.Unlock()
+
+	server.BroadcastToNamespace(handlers.SocketNamespace, shutdownEvent, gin.H{"reconnect": true})
+	return utils.WaitContext(ctx, &sh.edits)
+}
+
+func (sh *SocketHandler) isClosing() bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.closing
+}
+
 func (sh *SocketHandler) RegisterEvents(server *socketio.Server) {
 	if !sh.Initialized {
 		panic("SocketHandler not initialized")
 	}
-	server.OnConnect("/ws", func(s socketio.Conn) error {
+	server.OnConnect("/ws", func(s socketio.Conn) (err error) {
 		if s == nil {
 			return errors.New("nil connection")
 		}
+		ctx, span := startSpan(s, "connect")
+		defer func() { tracing.End(span, err) }()
+
+		if sh.isClosing() {
+			s.Emit(shutdownEvent, gin.H{"reconnect": true})
+			return errors.New("server is shutting down")
+		}
 
 		u := s.URL()
 		token := u.Query().Get("token")
@@ -79,17 +156,23 @@ func (sh *SocketHandler) RegisterEvents(server *socketio.Server) {
 			return errors.New("authentication required")
 		}
 
-		email, err := sh.SessionService.VerifyAccessToken(token)
+		email, issuedAt, err := sh.SessionService.VerifyAccessToken(token)
 		if err != nil {
 			s.Emit("error", "Invalid or expired session")
-			log.Printf("Token validation failed: %v", err)
+			logging.Socket(s).Warn("Token validation failed", "error", err)
 			return errors.New("authentication failed")
 		}
 
 		var user model.User
-		if err := sh.UserRepository.GetByEmail(&user, email); err != nil {
+		if err := sh.UserRepository.WithContext(ctx).GetByEmail(&user, email); err != nil {
+			s.Emit("error", "Invalid or expired session")
+			logging.Socket(s).Warn("Token validation failed", "error", err)
+			return errors.New("authentication failed")
+		}
+
+		if user.AccessTokenRevoked(issuedAt) {
 			s.Emit("error", "Invalid or expired session")
-			log.Printf("Token validation failed: %v", err)
+			logging.Socket(s).Warn("Token validation failed: session revoked", "userId", user.ID)
 			return errors.New("authentication failed")
 		}
 
@@ -97,9 +180,9 @@ func (sh *SocketHandler) RegisterEvents(server *socketio.Server) {
 			"userId": user.ID.String(),
 			"email":  user.Email,
 		})
+		s.Join(handlers.UserRoom(user.ID.String()))
 
-		log.Println("Connected:", s.ID())
-		log.Println("Authenticated connection:", s.ID())
+		logging.Socket(s).Info("Authenticated connection")
 		s.Emit("connected", map[string]string{
 			"message": "Connection established",
 			"userId":  user.ID.String(),
@@ -108,51 +191,110 @@ func (sh *SocketHandler) RegisterEvents(server *socketio.Server) {
 	})
 
 	server.OnEvent("/ws", "join", func(s socketio.Conn, docId string) {
-		log.Printf("User %s joined document room %s", s.ID(), docId)
-		s.Join(docId)
-		s.Emit("joined", gin.H{"room": docId})
+		ctx, span := startSpan(s, "join", attribute.String("document.id", docId))
+		defer span.End()
+
+		// Rooms other than documents, such as the per-user notification
+		// rooms, are joined by the server only.
+		docUUID, err := uuid.Parse(docId)
+		if err != nil {
+			s.Emit("error", "Invalid document ID")
+			return
+		}
+
+		userUUID, err := uuid.Parse(s.Context().(map[string]string)["userId"])
+		if err != nil {
+			s.Emit("error", "Internal server error")
+			logging.Socket(s).Error("Invalid user ID in socket context", "error", err)
+			return
+		}
+
+		hasAccess, err := sh.DocumentAccessRepository.WithContext(ctx).HasReadAccess(userUUID, docUUID)
+		if err != nil {
+			span.SetStatus(codes.Error, "access check failed")
+			s.Emit("error", "Error validating document access")
+			logging.Socket(s).Error("Access validation failed", "error", err)
+			return
+		}
+		if !hasAccess {
+			s.Emit("error", "You do not have access to this document")
+			return
+		}
+
+		room := docUUID.String()
+		logging.Socket(s).Debug("Joined document room", "documentId", room)
+		s.Join(room)
+		s.Emit("joined", gin.H{"room": room})
 	})
 
 	server.OnEvent("/ws", "leave", func(s socketio.Conn, docId string) {
-		log.Printf("User %s left document room %s", s.ID(), docId)
+		_, span := startSpan(s, "leave", attribute.String("document.id", docId))
+		defer span.End()
+		logging.Socket(s).Debug("Left document room", "documentId", docId)
 		s.Leave(docId)
 		s.Emit("left", gin.H{"room": docId})
 	})
 
 	server.OnEvent("/ws", "edit", func(s socketio.Conn, data map[string]interface{}) {
-		ctx := s.Context().(map[string]string)
-		userId := ctx["userId"]
-		log.Printf("ctx: %s", ctx)
-		log.Printf("data: %s", data)
+		userId := s.Context().(map[string]string)["userId"]
+		ctx, span := startSpan(s, "edit")
+		defer span.End()
+
+		// The client resends the edit after reconnecting elsewhere.
+		if !sh.beginEdit() {
+			recordEdit(span, "rejected")
+			s.Emit(shutdownEvent, gin.H{"reconnect": true})
+			return
+		}
+		defer sh.edits.Done()
+
+		// Edits are limited per connection; a connection lives on a single
+		// instance, so the limiter does not need shared storage.
+		limit, err := sh.EditLimiter.Get(ctx, s.ID())
+		if err != nil {
+			logging.Socket(s).Error("Error checking edit rate limit", "error", err)
+		} else if limit.Reached {
+			recordEdit(span, "rate_limited")
+			s.Emit("rate_limited", gin.H{
+				"event":   "edit",
+				"resetAt": limit.Reset,
+			})
+			return
+		}
 
 		docId, ok := data["id"].(string)
 		if !ok || docId == "" {
+			recordEdit(span, "rejected")
 			s.Emit("error", "Invalid document ID")
 			return
 		}
 
 		userUUID, err := uuid.Parse(userId)
 		if err != nil {
+			recordEdit(span, "rejected")
 			s.Emit("error", "Internal server error")
-			log.Printf("Error: %v", err)
+			logging.Socket(s).Error("Invalid user ID in socket context", "error", err)
 			return
 		}
 
 		docUUID, err := uuid.Parse(docId)
 		if err != nil {
+			recordEdit(span, "rejected")
 			s.Emit("error", "Internal server error")
-			log.Printf("Error: %v", err)
+			logging.Socket(s).Warn("Invalid document ID", "error", err)
 			return
 		}
 
-		hasAccess, err := sh.DocumentAccessRepository.HasEditAccess(userUUID, docUUID)
+		hasAccess, err := sh.DocumentAccessRepository.WithContext(ctx).HasEditAccess(userUUID, docUUID)
 		if err != nil {
+			recordEdit(span, "failed")
 			s.Emit("error", "Error validating editor access")
-			log.Printf("Access validation failed: %v", err)
+			logging.Socket(s).Error("Access validation failed", "error", err)
 			return
 		}
 
 		if !hasAccess {
+			recordEdit(span, "rejected")
 			s.Emit("error", "You do not have access to edit this document")
 			return
 		}
@@ -163,31 +305,35 @@ func (sh *SocketHandler) RegisterEvents(server *socketio.Server) {
 		// 	return
 		// }
 
-		// log.Printf("User %s editing document %s", userId, docId)
-
 		var document model.Document
 		d, err := json.Marshal(data)
 		if err != nil {
-			log.Println("Failed to marshal message:", err)
+			logging.Socket(s).Warn("Failed to marshal message", "error", err)
+			recordEdit(span, "rejected")
 			s.Emit("error", "Invalid message format")
 			return
 		}
 		if err := json.Unmarshal(d, &document); err != nil {
-			log.Println("Invalid post:", err)
+			logging.Socket(s).Warn("Invalid edit message", "error", err)
+			recordEdit(span, "rejected")
 			return
 		}
 
-		if err := sh.DocumentRepository.Update(&document, docUUID); err != nil {
-			log.Println("Failed to update document:", err)
+		if err := sh.DocumentRepository.WithContext(ctx).Update(&document, docUUID); err != nil {
+			logging.Socket(s).Error("Failed to update document", "documentId", docId, "error", err)
+			recordEdit(span, "failed")
 			s.Emit("error", "Failed to update document")
 			return
 		}
-		log.Print(document)
 
-		server.BroadcastToRoom("/ws", docId, "document_updated", gin.H{
+		server.BroadcastToRoom("/ws", docUUID.String(), "document_updated", gin.H{
 			"editorId": userId,
 			"document": document,
 		})
+		recordEdit(span, "applied")
+		metrics.BroadcastFanout.Observe(float64(server.RoomLen("/ws", docUUID.String())))
+
+		sh.WebhookPublisher.Publish(ctx, model.WebhookDocumentUpdated, &document, &userUUID, gin.H{"title": document.Title})
 	})
 
 }
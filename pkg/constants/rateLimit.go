@@ -0,0 +1,14 @@
+package constants
+
+// Rate limit policies applied to route groups. Each is a rate in the
+// "<limit>-<S|M|H|D>" format and can be overridden with RATE_LIMIT_<NAME>,
+// e.g. RATE_LIMIT_API=600-M.
+const (
+	RateLimitGlobal    = "global"
+	RateLimitAuth      = "auth"
+	RateLimitPublic    = "public"
+	RateLimitAPI       = "api"
+	RateLimitExpensive = "expensive"
+	RateLimitAdmin     = "admin"
+	RateLimitSocket    = "socket_edit"
+)
@@ -3,7 +3,6 @@ package jwt
 import (
 	"errors"
 	"fmt"
-	"realTimeEditor/pkg/constants"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -13,22 +12,37 @@ type Session struct {
 	JWTSecret string
 }
 
-func NewSession() (*Session, error) {
-	env, err := constants.LoadEnv()
-	if err != nil {
-		return nil, err
+func NewSession(jwtSecret string) (*Session, error) {
+	if jwtSecret == "" {
+		return nil, errors.New("JWT secret is empty")
 	}
 	return &Session{
-		JWTSecret: env.JWT_SECRET,
+		JWTSecret: jwtSecret,
 	}, nil
 }
 
 var ErrTokenExpired = errors.New("access token expired")
 
+const (
+	AccessTokenTTL  = time.Hour * 24
+	RefreshTokenTTL = time.Hour * 24 * 30
+	// MFAChallengeTTL bounds the time between the password and 2FA steps.
+	MFAChallengeTTL = time.Minute * 5
+	// AccountCompletionTTL bounds how long an invited user has to set up
+	// the account created when they accepted the invite.
+	AccountCompletionTTL = time.Hour * 48
+)
+
+// RefreshClaims identifies a stored refresh token; ID is its jti.
+type RefreshClaims struct {
+	Email string
+	ID    string
+}
+
 func (s *Session) GenerateAccessToken(email string) (string, error) {
 	claims := jwt.MapClaims{
 		"email":      email,
-		"exp":        time.Now().UTC().UTC().Add(time.Hour * 24).Unix(),
+		"exp":        time.Now().UTC().UTC().Add(AccessTokenTTL).Unix(),
 		"token_type": "access",
 		"iat":        time.Now().UTC().UTC().Unix(),
 		"iss":        "nobelium24",
@@ -42,10 +56,13 @@ func (s *Session) GenerateAccessToken(email string) (string, error) {
 	return tokenString, nil
 }
 
-func (s *Session) GenerateRefreshToken(email string) (string, error) {
+// GenerateRefreshToken signs a refresh token for the stored token tokenId,
+// which becomes the jti claim.
+func (s *Session) GenerateRefreshToken(email, tokenId string, expiresAt time.Time) (string, error) {
 	claims := jwt.MapClaims{
 		"email":      email,
-		"exp":        time.Now().UTC().UTC().Add(time.Hour * 24 * 30).Unix(),
+		"jti":        tokenId,
+		"exp":        expiresAt.Unix(),
 		"token_type": "refresh",
 		"iat":        time.Now().UTC().UTC().Unix(),
 		"iss":        "nobelium24",
@@ -59,7 +76,9 @@ func (s *Session) GenerateRefreshToken(email string) (string, error) {
 	return tokenString, nil
 }
 
-func (s *Session) VerifyAccessToken(tokenString string) (string, error) {
+// VerifyAccessToken returns the token's email and the time it was issued, so
+// callers can reject tokens issued before a user's sessions were revoked.
+func (s *Session) VerifyAccessToken(tokenString string) (string, time.Time, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -70,21 +89,33 @@ func (s *Session) VerifyAccessToken(tokenString string) (string, error) {
 	if err != nil {
 		ve, ok := err.(*jwt.ValidationError)
 		if ok && ve.Errors&jwt.ValidationErrorExpired != 0 {
-			return "", ErrTokenExpired
+			return "", time.Time{}, ErrTokenExpired
 		}
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if tokenType, ok := claims["token_type"].(string); !ok || tokenType != "access" {
+			return "", time.Time{}, fmt.Errorf("invalid token type: expected access token")
+		}
+
 		if iss, ok := claims["iss"].(string); !ok || iss != "nobelium24" {
-			return "", fmt.Errorf("invalid issuer")
+			return "", time.Time{}, fmt.Errorf("invalid issuer")
 		}
 
-		email := claims["email"].(string)
-		return email, nil
+		email, ok := claims["email"].(string)
+		if !ok {
+			return "", time.Time{}, fmt.Errorf("invalid token claims: email not found")
+		}
+
+		iat, ok := claims["iat"].(float64)
+		if !ok {
+			return "", time.Time{}, fmt.Errorf("invalid token claims: iat not found")
+		}
+		return email, time.Unix(int64(iat), 0).UTC(), nil
 	}
 
-	return "", fmt.Errorf("invalid token")
+	return "", time.Time{}, fmt.Errorf("invalid token")
 }
 
 func (s *Session) VerifyExpiredToken(tokenString string) (bool, error) {
@@ -114,7 +145,9 @@ func (s *Session) VerifyExpiredToken(tokenString string) (bool, error) {
 	return false, fmt.Errorf("invalid token claims")
 }
 
-func (s *Session) VerifyRefreshToken(tokenString string) (string, error) {
+// VerifyRefreshToken checks the signature and claims of a refresh token. It
+// does not check whether the stored token is still usable.
+func (s *Session) VerifyRefreshToken(tokenString string) (*RefreshClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -122,12 +155,64 @@ func (s *Session) VerifyRefreshToken(tokenString string) (string, error) {
 		return []byte(s.JWTSecret), nil
 	})
 	if err != nil {
-		return "", fmt.Errorf("error parsing token: %s", err)
+		return nil, fmt.Errorf("error parsing token: %s", err)
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		if tokenType, ok := claims["token_type"].(string); !ok || tokenType != "refresh" {
-			return "", fmt.Errorf("invalid token type: expected refresh token")
+			return nil, fmt.Errorf("invalid token type: expected refresh token")
+		}
+
+		if iss, ok := claims["iss"].(string); !ok || iss != "nobelium24" {
+			return nil, fmt.Errorf("invalid issuer")
+		}
+
+		email, ok := claims["email"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid token claims: email not found")
+		}
+		jti, ok := claims["jti"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid token claims: jti not found")
+		}
+		return &RefreshClaims{Email: email, ID: jti}, nil
+	}
+	return nil, fmt.Errorf("invalid token")
+}
+
+// GenerateMFAChallengeToken is returned by login instead of a token pair when
+// the user has 2FA enabled. It only proves the password step was passed.
+func (s *Session) GenerateMFAChallengeToken(email string) (string, error) {
+	claims := jwt.MapClaims{
+		"email":      email,
+		"exp":        time.Now().UTC().Add(MFAChallengeTTL).Unix(),
+		"token_type": "mfa_challenge",
+		"iat":        time.Now().UTC().Unix(),
+		"iss":        "nobelium24",
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(s.JWTSecret))
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+func (s *Session) VerifyMFAChallengeToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.JWTSecret), nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error parsing token: %s", err)
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if tokenType, ok := claims["token_type"].(string); !ok || tokenType != "mfa_challenge" {
+			return "", fmt.Errorf("invalid token type: expected MFA challenge token")
 		}
 
 		if iss, ok := claims["iss"].(string); !ok || iss != "nobelium24" {
@@ -138,11 +223,67 @@ func (s *Session) VerifyRefreshToken(tokenString string) (string, error) {
 		if !ok {
 			return "", fmt.Errorf("invalid token claims: email not found")
 		}
-		accessToken, err := s.GenerateAccessToken(email)
-		if err != nil {
-			return "", err
-		}
-		return accessToken, nil
+		return email, nil
 	}
 	return "", fmt.Errorf("invalid token")
 }
+
+// AccountCompletionClaims identify the invited user finishing their account
+// and the invite that created it.
+type AccountCompletionClaims struct {
+	UserID   string
+	InviteID string
+}
+
+// GenerateAccountCompletionToken is emailed to a user created by accepting an
+// invite. The invite records its use, so the token works only once.
+func (s *Session) GenerateAccountCompletionToken(userId, inviteId string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":        userId,
+		"invite":     inviteId,
+		"exp":        time.Now().UTC().Add(AccountCompletionTTL).Unix(),
+		"token_type": "account_completion",
+		"iat":        time.Now().UTC().Unix(),
+		"iss":        "nobelium24",
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(s.JWTSecret))
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+func (s *Session) VerifyAccountCompletionToken(tokenString string) (*AccountCompletionClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %s", err)
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if tokenType, ok := claims["token_type"].(string); !ok || tokenType != "account_completion" {
+			return nil, fmt.Errorf("invalid token type: expected account completion token")
+		}
+
+		if iss, ok := claims["iss"].(string); !ok || iss != "nobelium24" {
+			return nil, fmt.Errorf("invalid issuer")
+		}
+
+		userId, ok := claims["sub"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid token claims: sub not found")
+		}
+		inviteId, ok := claims["invite"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid token claims: invite not found")
+		}
+		return &AccountCompletionClaims{UserID: userId, InviteID: inviteId}, nil
+	}
+	return nil, fmt.Errorf("invalid token")
+}
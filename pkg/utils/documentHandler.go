@@ -2,18 +2,29 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"realTimeEditor/internal/metrics"
 	"realTimeEditor/internal/model"
 	"realTimeEditor/internal/repositories"
+	"realTimeEditor/internal/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // DocumentHandler generates a PDF from a Document (with optional formatting metadata) and uploads it to Cloudinary.
 func DocumentHandler(
+	ctx context.Context,
 	document *model.Document,
 	metadata *model.DocumentMetadata,
 ) (*repositories.UploadedMedia, error) {
 	pdfService := NewPDFService("assets/")
+	start := time.Now()
+	_, span := tracing.Start(ctx, "pdf.render", attribute.String("document.id", document.ID.String()))
 	byteSlice, err := pdfService.GenerateDocumentPDF(document, metadata)
+	tracing.End(span, err)
+	metrics.Since(metrics.PDFRenderDuration, start, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate PDF: %w", err)
 	}
@@ -21,7 +32,7 @@ func DocumentHandler(
 	reader := bytes.NewReader(byteSlice)
 	fileName := fmt.Sprintf("document_%s.pdf", document.ID.String())
 
-	result, err := repositories.CloudinaryUploaderStream(reader, fileName, repositories.RawResource)
+	result, err := repositories.CloudinaryUploaderStream(ctx, reader, fileName, repositories.RawResource)
 	if err != nil {
 		return nil, fmt.Errorf("cloudinary upload failed: %w", err)
 	}
@@ -1,35 +1,157 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"realTimeEditor/pkg/constants"
+	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
+// Argon2Params are the argon2id cost parameters encoded into every hash.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params are used for new hashes. Raising them makes existing
+// hashes report NeedsRehash, so users are upgraded on their next login.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// legacyArgon2Params are the fixed parameters of hashes created with the
+// global SALT, before hashes carried their own salt and parameters.
+var legacyArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  1,
+	Parallelism: 4,
+	KeyLength:   32,
+}
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
 type PasswordHasher struct {
-	Salt []byte
+	// LegacySalt verifies hashes created before per-user salts.
+	LegacySalt []byte
+	Params     Argon2Params
 }
 
-func NewPasswordHasher() (*PasswordHasher, error) {
-	env, err := constants.LoadEnv()
-	if err != nil {
-		return nil, fmt.Errorf("invalid salt provided")
+func NewPasswordHasher(legacySalt string) *PasswordHasher {
+	return &PasswordHasher{
+		LegacySalt: []byte(legacySalt),
+		Params:     DefaultArgon2Params,
 	}
-	return &PasswordHasher{Salt: []byte(env.SALT)}, nil
 }
 
+// HashPassword returns a PHC encoded argon2id hash with a random salt:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
 func (p *PasswordHasher) HashPassword(password string) (string, error) {
-	hash := argon2.IDKey([]byte(password), p.Salt, 1, 64*1024, 4, 32)
-	encodedHash := base64.RawStdEncoding.EncodeToString(hash)
-	return encodedHash, nil
+	salt := make([]byte, p.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error generating salt: %w", err)
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, p.Params.Iterations, p.Params.Memory, p.Params.Parallelism, p.Params.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Params.Memory,
+		p.Params.Iterations,
+		p.Params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
 }
 
+// VerifyPassword checks password against a PHC encoded hash, or against a
+// legacy global-salt hash when hashedPassword is not PHC encoded.
 func (p *PasswordHasher) VerifyPassword(hashedPassword, password string) (bool, error) {
+	if !isPHC(hashedPassword) {
+		// Without the legacy salt these hashes cannot be checked.
+		if len(p.LegacySalt) == 0 {
+			return false, ErrInvalidHash
+		}
+		expected, err := base64.RawStdEncoding.DecodeString(hashedPassword)
+		if err != nil {
+			return false, ErrInvalidHash
+		}
+		params := legacyArgon2Params
+		hash := argon2.IDKey([]byte(password), p.LegacySalt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(hash, expected) == 1, nil
+	}
+
+	params, salt, expected, err := decodePHC(hashedPassword)
+	if err != nil {
+		return false, err
+	}
+	hash := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(hash, expected) == 1, nil
+}
+
+// NeedsRehash reports whether hashedPassword is a legacy hash or was created
+// with parameters other than the current ones.
+func (p *PasswordHasher) NeedsRehash(hashedPassword string) bool {
+	if !isPHC(hashedPassword) {
+		return true
+	}
+	params, salt, hash, err := decodePHC(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return params.Memory != p.Params.Memory ||
+		params.Iterations != p.Params.Iterations ||
+		params.Parallelism != p.Params.Parallelism ||
+		uint32(len(salt)) != p.Params.SaltLength ||
+		uint32(len(hash)) != p.Params.KeyLength
+}
+
+func isPHC(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$argon2id$")
+}
+
+func decodePHC(encoded string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
 
-	salt := []byte(p.Salt)
-	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
-	encodedHash := base64.RawStdEncoding.EncodeToString(hash)
-	return encodedHash == hashedPassword, nil
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(hash))
+	return params, salt, hash, nil
 }
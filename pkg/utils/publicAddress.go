@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrNonPublicAddress is returned when a connection would reach a loopback,
+// private, link-local or otherwise non-public address.
+var ErrNonPublicAddress = errors.New("destination address is not public")
+
+// IsPublicAddress reports whether addr is routable on the public internet.
+// Loopback, RFC 1918 and unique local, link-local (which includes cloud
+// metadata endpoints such as 169.254.169.254), multicast and unspecified
+// addresses are not.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicDialControl is a net.Dialer Control function that refuses to connect
+// to non-public addresses. It runs on the resolved address of every
+// connection, so DNS rebinding cannot get past it.
+func PublicDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, addr)
+	}
+	return nil
+}
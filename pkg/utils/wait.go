@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// WaitContext waits for wg like wg.Wait, but gives up with ctx's error when
+// ctx is done first.
+func WaitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
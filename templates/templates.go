@@ -0,0 +1,15 @@
+// Package templates embeds the email templates so the binary does not depend
+// on the templates directory being present at runtime.
+//
+// layout.html holds the shared shell. Each locale directory provides
+// common.html (lang and footer blocks) plus one file per email defining the
+// subject, title and content blocks.
+package templates
+
+import "embed"
+
+//go:embed layout.html */*.html
+var FS embed.FS
+
+// DefaultLocale is used when a recipient has no locale or an unsupported one.
+const DefaultLocale = "en"